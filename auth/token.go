@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/configuration"
 )
 
 // TokenResponse represents the auth token response
@@ -21,7 +23,7 @@ type TokenProvider struct {
 	mu            sync.RWMutex
 	baseURL       string
 	clientID      string
-	apiKey        string
+	apiKey        configuration.KeySource
 	httpClient    *http.Client
 	currentToken  string
 	expiresAt     time.Time
@@ -30,6 +32,13 @@ type TokenProvider struct {
 
 // NewTokenProvider creates a new token provider
 func NewTokenProvider(baseURL, clientID, apiKey string, httpClient *http.Client) *TokenProvider {
+	return NewTokenProviderFromSource(baseURL, clientID, configuration.StaticKey(apiKey), httpClient)
+}
+
+// NewTokenProviderFromSource creates a token provider that fetches the API key
+// from apiKey on every token refresh, so rotated keys are picked up without
+// restarting.
+func NewTokenProviderFromSource(baseURL, clientID string, apiKey configuration.KeySource, httpClient *http.Client) *TokenProvider {
 	if httpClient == nil {
 		httpClient = &http.Client{Timeout: 10 * time.Second}
 	}
@@ -65,6 +74,11 @@ func (p *TokenProvider) GetToken() (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	apiKey, err := p.apiKey.FetchKey(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch API key: %w", err)
+	}
+
 	url := p.baseURL + "/v1/connect/token"
 	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
 	if err != nil {
@@ -73,7 +87,7 @@ func (p *TokenProvider) GetToken() (string, error) {
 
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("x-client-id", p.clientID)
-	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("x-api-key", apiKey)
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
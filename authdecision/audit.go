@@ -0,0 +1,261 @@
+package authdecision
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/internal/ctxutil"
+)
+
+// AuditRecord describes one authorization decision.
+type AuditRecord struct {
+	Time          time.Time `json:"time"`
+	TransactionID string    `json:"transaction_id"`
+	// Transaction is the decrypted request with card-number-like digit runs
+	// masked. UQPAY does not send the PAN, so this only guards against PANs
+	// echoed in merchant or terminal fields.
+	Transaction        Transaction `json:"transaction"`
+	ResponseCode       string      `json:"response_code,omitempty"`
+	PartnerReferenceID string      `json:"partner_reference_id,omitempty"`
+	Reason             string      `json:"reason,omitempty"`
+	StandIn            bool        `json:"stand_in,omitempty"`
+	Variant            string      `json:"variant,omitempty"`
+	LatencyMS          float64     `json:"latency_ms"`
+	// Error is set when the decision failed or finished after its deadline.
+	// UQPAY then applies its configured timeout action.
+	Error string `json:"error,omitempty"`
+	// Stage is set when the request failed outside the decision function:
+	// AuditStageDecrypt, AuditStageDecode or AuditStageEncrypt. Replay skips
+	// these records.
+	Stage string `json:"stage,omitempty"`
+}
+
+// Stages of a request that failed outside the decision function.
+const (
+	AuditStageDecrypt = "decrypt"
+	AuditStageDecode  = "decode"
+	AuditStageEncrypt = "encrypt"
+)
+
+// auditTransaction encodes Transaction without its wire-format decoder, so
+// records of requests that omitted an amount read back without error.
+type auditTransaction Transaction
+
+// MarshalJSON encodes r for an audit log.
+func (r AuditRecord) MarshalJSON() ([]byte, error) {
+	type record AuditRecord
+	return json.Marshal(struct {
+		record
+		Transaction auditTransaction `json:"transaction"`
+	}{record(r), auditTransaction(r.Transaction)})
+}
+
+// UnmarshalJSON decodes a record written by MarshalJSON.
+func (r *AuditRecord) UnmarshalJSON(data []byte) error {
+	type record AuditRecord
+	var wire struct {
+		record
+		Transaction auditTransaction `json:"transaction"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*r = AuditRecord(wire.record)
+	r.Transaction = Transaction(wire.Transaction)
+	return nil
+}
+
+// Result returns the decision recorded in r.
+func (r AuditRecord) Result() Result {
+	return Result{ResponseCode: r.ResponseCode, PartnerReferenceID: r.PartnerReferenceID, Reason: r.Reason, StandIn: r.StandIn, Variant: r.Variant}
+}
+
+// AuditSink stores audit records. Implementations must be safe for
+// concurrent use.
+type AuditSink interface {
+	WriteAudit(ctx context.Context, record AuditRecord) error
+}
+
+// Auditor records every decision made by the DecisionFuncs it wraps.
+type Auditor struct {
+	sink AuditSink
+	// OnError is called when the sink fails to store a record. The decision
+	// itself is not affected.
+	OnError func(error)
+	now     func() time.Time
+}
+
+// NewAuditor creates an auditor that writes to sink.
+func NewAuditor(sink AuditSink) *Auditor {
+	return &Auditor{sink: sink, now: time.Now}
+}
+
+// Wrap returns a DecisionFunc that calls decide and records the transaction,
+// result, reason, latency and error. Wrap the outermost DecisionFunc so that
+// declines from velocity limits and rules are recorded too.
+func (a *Auditor) Wrap(decide DecisionFunc) DecisionFunc {
+	return func(ctx context.Context, transaction Transaction) (result Result, err error) {
+		started := a.now()
+		defer func() {
+			record := AuditRecord{
+				Time:               started.UTC(),
+				TransactionID:      transaction.TransactionID,
+				Transaction:        redactTransaction(transaction),
+				ResponseCode:       result.ResponseCode,
+				PartnerReferenceID: result.PartnerReferenceID,
+				Reason:             result.Reason,
+				StandIn:            result.StandIn,
+				Variant:            result.Variant,
+				LatencyMS:          float64(a.now().Sub(started)) / float64(time.Millisecond),
+			}
+			if recovered := recover(); recovered != nil {
+				record.Error = fmt.Sprintf("decision panic: %v", recovered)
+				a.write(ctx, record)
+				panic(recovered)
+			}
+			if err != nil {
+				record.Error = err.Error()
+			} else if ctxErr := ctx.Err(); ctxErr != nil {
+				record.Error = ctxErr.Error()
+			}
+			a.write(ctx, record)
+		}()
+		return decide(ctx, transaction)
+	}
+}
+
+// recordFailure records a request that failed at stage, outside the wrapped
+// decision. transaction holds whatever was decoded before the failure.
+func (a *Auditor) recordFailure(ctx context.Context, stage string, transaction Transaction, err error) {
+	a.write(ctx, AuditRecord{
+		Time:          a.now().UTC(),
+		TransactionID: transaction.TransactionID,
+		Transaction:   redactTransaction(transaction),
+		Error:         err.Error(),
+		Stage:         stage,
+	})
+}
+
+func (a *Auditor) write(ctx context.Context, record AuditRecord) {
+	if err := a.sink.WriteAudit(ctxutil.WithoutCancel(ctx), record); err != nil && a.OnError != nil {
+		a.OnError(fmt.Errorf("authdecision: write audit record: %w", err))
+	}
+}
+
+// JSONLAuditSink appends audit records to a writer as JSON lines.
+type JSONLAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLAuditSink creates a sink writing to w.
+func NewJSONLAuditSink(w io.Writer) *JSONLAuditSink {
+	return &JSONLAuditSink{w: w}
+}
+
+// OpenJSONLAuditLog opens path for appending, creating it if needed. Close
+// the returned file after the handler has stopped.
+func OpenJSONLAuditLog(path string) (*JSONLAuditSink, *os.File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("authdecision: open audit log: %w", err)
+	}
+	return NewJSONLAuditSink(file), file, nil
+}
+
+// WriteAudit writes record as one JSON line.
+func (s *JSONLAuditSink) WriteAudit(ctx context.Context, record AuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// ReadAuditLog decodes the JSON lines written by a JSONLAuditSink.
+func ReadAuditLog(r io.Reader) ([]AuditRecord, error) {
+	var records []AuditRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), int(defaultMaxBodyBytes))
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("authdecision: audit log line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("authdecision: read audit log: %w", err)
+	}
+	return records, nil
+}
+
+// redactTransaction masks card-number-like digit runs in every string field.
+func redactTransaction(transaction Transaction) Transaction {
+	value := reflect.ValueOf(&transaction).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		if field := value.Field(i); field.Kind() == reflect.String {
+			field.SetString(RedactPAN(field.String()))
+		}
+	}
+	return transaction
+}
+
+// RedactPAN masks runs of 13 to 19 digits that pass the Luhn check, keeping
+// the first six and last four digits.
+func RedactPAN(s string) string {
+	var out strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] < '0' || s[i] > '9' {
+			out.WriteByte(s[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			j++
+		}
+		digits := s[i:j]
+		if len(digits) >= 13 && len(digits) <= 19 && luhnValid(digits) {
+			out.WriteString(digits[:6])
+			out.WriteString(strings.Repeat("*", len(digits)-10))
+			out.WriteString(digits[len(digits)-4:])
+		} else {
+			out.WriteString(digits)
+		}
+		i = j
+	}
+	return out.String()
+}
+
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,200 @@
+package authdecision
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditorRecordsDecisionsAsJSONLines(t *testing.T) {
+	var log bytes.Buffer
+	auditor := NewAuditor(NewJSONLAuditSink(&log))
+	engine, err := NewRuleEngine(RuleSet{Rules: []Rule{
+		{Name: "gambling", Match: Match{MerchantCategoryCodes: []string{"7995"}}, Action: ActionDeny, ResponseCode: "57", Reason: "gambling MCC blocked"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decide := auditor.Wrap(engine.DecisionFunc())
+	ctx := context.Background()
+
+	transactions := []Transaction{
+		{TransactionID: "tx-1", CardID: "card-001", MerchantCategoryCode: "5411", BillingAmount: "12.50", MerchantName: "SHOP 4111111111111111"},
+		{TransactionID: "tx-2", CardID: "card-001", MerchantCategoryCode: "7995", BillingAmount: "99.00"},
+	}
+	for _, tx := range transactions {
+		if _, err := decide(ctx, tx); err != nil {
+			t.Fatalf("%s: %v", tx.TransactionID, err)
+		}
+	}
+	failing := auditor.Wrap(func(context.Context, Transaction) (Result, error) {
+		return Result{}, errors.New("risk service unavailable")
+	})
+	if _, err := failing(ctx, Transaction{TransactionID: "tx-3"}); err == nil {
+		t.Fatal("wrapped error was not returned")
+	}
+
+	if strings.Contains(log.String(), "4111111111111111") {
+		t.Fatalf("audit log contains a PAN: %s", log.String())
+	}
+	records, err := ReadAuditLog(&log)
+	if err != nil {
+		t.Fatalf("ReadAuditLog: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("records = %d, want 3", len(records))
+	}
+	if records[0].ResponseCode != "00" || records[0].Transaction.MerchantName != "SHOP 411111******1111" || records[0].LatencyMS < 0 {
+		t.Errorf("record 0 = %+v", records[0])
+	}
+	if records[1].ResponseCode != "57" || records[1].Reason != "gambling MCC blocked" || records[1].Transaction.BillingAmount != "99.00" {
+		t.Errorf("record 1 = %+v", records[1])
+	}
+	if records[2].Error == "" || records[2].TransactionID != "tx-3" {
+		t.Errorf("record 2 = %+v", records[2])
+	}
+}
+
+func TestReplayDiffsRecordedDecisions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, file, err := OpenJSONLAuditLog(path)
+	if err != nil {
+		t.Fatalf("OpenJSONLAuditLog: %v", err)
+	}
+	record := NewAuditor(sink).Wrap(approve)
+	ctx := context.Background()
+	for _, tx := range []Transaction{
+		{TransactionID: "tx-grocery", MerchantCategoryCode: "5411", MerchantCountry: "SG"},
+		{TransactionID: "tx-casino", MerchantCategoryCode: "7995", MerchantCountry: "SG"},
+		{TransactionID: "tx-abroad", MerchantCategoryCode: "5411", MerchantCountry: "US"},
+	} {
+		if _, err := record(ctx, tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := ReadAuditLog(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadAuditLog: %v", err)
+	}
+
+	engine, err := NewRuleEngine(RuleSet{Rules: []Rule{
+		{Name: "gambling", Match: Match{MerchantCategoryCodes: []string{"7995"}}, Action: ActionDeny, ResponseCode: "57"},
+		{Name: "domestic-reason", Match: Match{MerchantCountries: []string{"US"}}, Action: ActionAllow, Reason: "foreign allowed"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := Replay(ctx, records, engine.DecisionFunc(), ReplayOptions{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	if report.Total != 3 || report.Unchanged != 2 || report.NewlyDeclined != 1 || len(report.Diffs) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	if diff := report.Diffs[0]; diff.TransactionID != "tx-casino" || diff.Before.ResponseCode != "00" || diff.After.ResponseCode != "57" {
+		t.Fatalf("diff = %+v", diff)
+	}
+
+	withReasons, err := Replay(ctx, records, engine.DecisionFunc(), ReplayOptions{CompareReason: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(withReasons.Diffs) != 2 {
+		t.Fatalf("diffs with reasons = %+v", withReasons.Diffs)
+	}
+}
+
+func TestRedactPAN(t *testing.T) {
+	tests := map[string]string{
+		"4111111111111111":         "411111******1111",
+		"card 5500005555555559 ok": "card 550000******5559 ok",
+		"4111111111111112":         "4111111111111112",
+		"RRN 123456789012":         "RRN 123456789012",
+	}
+	for in, want := range tests {
+		if got := RedactPAN(in); got != want {
+			t.Errorf("RedactPAN(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestHandlerAuditsRequestsThatFailBeforeTheDecision(t *testing.T) {
+	client, _ := configuredTestClient(t)
+	var log bytes.Buffer
+	handler, err := client.Handler(HandlerOptions{Decide: approve, Auditor: NewAuditor(NewJSONLAuditSink(&log))})
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Fatalf("recovered = %v, want http.ErrAbortHandler", recovered)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/auth-decision", strings.NewReader("not pgp")))
+	}()
+
+	records, err := ReadAuditLog(&log)
+	if err != nil || len(records) != 1 || records[0].Stage != AuditStageDecrypt || records[0].Error == "" {
+		t.Fatalf("audit records = %+v, %v", records, err)
+	}
+	report, err := Replay(context.Background(), records, approve, ReplayOptions{})
+	if err != nil || report.Total != 0 {
+		t.Fatalf("Replay = %+v, %v; failed requests should be skipped", report, err)
+	}
+}
+
+func TestHandlerAuditsMalformedResponseCodes(t *testing.T) {
+	client, uqpayContext := configuredTestClient(t)
+	var log bytes.Buffer
+	decide := func(context.Context, Transaction) (Result, error) { return Result{ResponseCode: "Z9"}, nil }
+	handler, err := client.Handler(HandlerOptions{Decide: decide, Auditor: NewAuditor(NewJSONLAuditSink(&log))})
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+	encrypted, err := uqpayContext.encrypt(authorizationRequest)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != http.ErrAbortHandler {
+				t.Fatalf("recovered = %v, want http.ErrAbortHandler", recovered)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/auth-decision", strings.NewReader(encrypted)))
+	}()
+
+	records, err := ReadAuditLog(&log)
+	if err != nil || len(records) != 2 || records[1].Stage != AuditStageEncrypt || !strings.Contains(records[1].Error, "two digits") {
+		t.Fatalf("audit records = %+v, %v", records, err)
+	}
+}
+
+func TestTransactionJSONKeepsEmptyAmounts(t *testing.T) {
+	data, err := json.Marshal(Transaction{TransactionID: "tx-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"billing_amount":""`, `"transaction_amount":""`, `"auth_amount":""`, `"card_balance":""`} {
+		if !strings.Contains(string(data), field) {
+			t.Errorf("%s missing from %s", field, data)
+		}
+	}
+}
@@ -8,9 +8,13 @@ import (
 	"io"
 	"net/http"
 	"sync"
+	"time"
 )
 
-const defaultMaxBodyBytes int64 = 1 << 20
+const (
+	defaultMaxBodyBytes  int64 = 1 << 20
+	defaultExpiryWarning       = 30 * 24 * time.Hour
+)
 
 var errNotConfigured = errors.New("authdecision: not configured; call Configure first")
 
@@ -18,6 +22,8 @@ var errNotConfigured = errors.New("authdecision: not configured; call Configure
 type Client struct {
 	mu  sync.RWMutex
 	pgp *pgpContext
+	// keyValues are the values last applied from KeySources.
+	keyValues []string
 }
 
 // NewClient creates an unconfigured authorization decision client.
@@ -29,19 +35,80 @@ func NewClient() *Client {
 // It is safe to replace the configuration while existing handlers are running;
 // each request uses one immutable configuration snapshot.
 func (c *Client) Configure(config Config) error {
+	if err := c.configure(config); err != nil {
+		return fmt.Errorf("authdecision: %w", err)
+	}
+	return nil
+}
+
+// configure applies config; its errors carry no package prefix, so every
+// exported caller adds exactly one.
+func (c *Client) configure(config Config) error {
 	pgp, err := newPGPContext(config)
 	if err != nil {
-		return fmt.Errorf("authdecision: configure: %w", err)
+		return fmt.Errorf("configure: %w", err)
 	}
 	c.mu.Lock()
 	c.pgp = pgp
+	c.keyValues = nil
 	c.mu.Unlock()
+
+	if config.OnKeyExpiring != nil {
+		warning := config.ExpiryWarning
+		if warning <= 0 {
+			warning = defaultExpiryWarning
+		}
+		for _, key := range c.ExpiringKeys(warning) {
+			config.OnKeyExpiring(key)
+		}
+	}
 	return nil
 }
 
+// Keys describes every configured customer and UQPAY key.
+func (c *Client) Keys() []KeyInfo {
+	pgp := c.snapshot()
+	if pgp == nil {
+		return nil
+	}
+	return pgp.keys(pgp.now())
+}
+
+// ExpiringKeys returns the configured keys that expire within d, including
+// keys that have already expired.
+func (c *Client) ExpiringKeys(d time.Duration) []KeyInfo {
+	pgp := c.snapshot()
+	if pgp == nil {
+		return nil
+	}
+	now := pgp.now()
+	var expiring []KeyInfo
+	for _, key := range pgp.keys(now) {
+		if key.ExpiresWithin(now, d) {
+			expiring = append(expiring, key)
+		}
+	}
+	return expiring
+}
+
+// ActivePublicKey returns the ASCII-armored public key of the active customer
+// private key, for upload to UQPAY.
+func (c *Client) ActivePublicKey() (string, error) {
+	pgp := c.snapshot()
+	if pgp == nil {
+		return "", errNotConfigured
+	}
+	return serializePublicKeys(pgp.activePrivate)
+}
+
 // Process decrypts one request, invokes decide, injects the transaction ID, and
 // returns an ASCII-armored encrypted response.
 func (c *Client) Process(ctx context.Context, encryptedBody []byte, decide DecisionFunc) ([]byte, error) {
+	return c.process(ctx, encryptedBody, decide, nil)
+}
+
+// process is Process with failures outside decide recorded by auditor, if set.
+func (c *Client) process(ctx context.Context, encryptedBody []byte, decide DecisionFunc, auditor *Auditor) ([]byte, error) {
 	if ctx == nil {
 		return nil, fmt.Errorf("authdecision: context is required")
 	}
@@ -56,16 +123,22 @@ func (c *Client) Process(ctx context.Context, encryptedBody []byte, decide Decis
 		return nil, err
 	}
 
+	var transaction Transaction
+	fail := func(stage string, err error) ([]byte, error) {
+		if auditor != nil {
+			auditor.recordFailure(ctx, stage, transaction, err)
+		}
+		return nil, err
+	}
 	plaintext, err := pgp.decrypt(string(encryptedBody))
 	if err != nil {
-		return nil, err
+		return fail(AuditStageDecrypt, err)
 	}
-	var transaction Transaction
 	if err := json.Unmarshal([]byte(plaintext), &transaction); err != nil {
-		return nil, fmt.Errorf("authdecision: decode transaction: %w", err)
+		return fail(AuditStageDecode, fmt.Errorf("authdecision: decode transaction: %w", err))
 	}
 	if transaction.TransactionID == "" {
-		return nil, fmt.Errorf("authdecision: transaction_id is required")
+		return fail(AuditStageDecode, fmt.Errorf("authdecision: transaction_id is required"))
 	}
 
 	result, err := invokeDecision(ctx, decide, transaction)
@@ -75,8 +148,13 @@ func (c *Client) Process(ctx context.Context, encryptedBody []byte, decide Decis
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	// Only the format is checked here, so codes UQPAY supports beyond the
+	// catalog still reach it; Approve and Decline enforce the catalog.
 	if result.ResponseCode == "" {
-		return nil, fmt.Errorf("authdecision: response_code is required")
+		return fail(AuditStageEncrypt, fmt.Errorf("authdecision: response_code is required"))
+	}
+	if !result.Code().wellFormed() {
+		return fail(AuditStageEncrypt, fmt.Errorf("authdecision: response_code must be two digits, got %q", result.ResponseCode))
 	}
 
 	response, err := json.Marshal(struct {
@@ -89,11 +167,11 @@ func (c *Client) Process(ctx context.Context, encryptedBody []byte, decide Decis
 		PartnerReferenceID: result.PartnerReferenceID,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("authdecision: encode response: %w", err)
+		return fail(AuditStageEncrypt, fmt.Errorf("authdecision: encode response: %w", err))
 	}
 	encryptedResponse, err := pgp.encrypt(string(response))
 	if err != nil {
-		return nil, err
+		return fail(AuditStageEncrypt, err)
 	}
 	return []byte(encryptedResponse), nil
 }
@@ -103,28 +181,18 @@ type decisionOutcome struct {
 	err    error
 }
 
+// invokeDecision calls decide, returning early when ctx is done. A panic in
+// decide is returned as an error.
 func invokeDecision(ctx context.Context, decide DecisionFunc, transaction Transaction) (Result, error) {
 	if ctx.Done() == nil {
-		result, err := decide(ctx, transaction)
-		if err != nil {
-			return Result{}, fmt.Errorf("authdecision: decide: %w", err)
-		}
-		return result, nil
+		return callDecision(ctx, decide, transaction)
 	}
 
 	outcome := make(chan decisionOutcome, 1)
 	go func() {
-		deferred := decisionOutcome{}
-		defer func() {
-			if recovered := recover(); recovered != nil {
-				deferred.err = fmt.Errorf("authdecision: decision panic: %v", recovered)
-			}
-			outcome <- deferred
-		}()
-		deferred.result, deferred.err = decide(ctx, transaction)
-		if deferred.err != nil {
-			deferred.err = fmt.Errorf("authdecision: decide: %w", deferred.err)
-		}
+		var completed decisionOutcome
+		completed.result, completed.err = callDecision(ctx, decide, transaction)
+		outcome <- completed
 	}()
 
 	select {
@@ -135,6 +203,20 @@ func invokeDecision(ctx context.Context, decide DecisionFunc, transaction Transa
 	}
 }
 
+// callDecision calls decide, recovering a panic as an error.
+func callDecision(ctx context.Context, decide DecisionFunc, transaction Transaction) (result Result, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result, err = Result{}, fmt.Errorf("authdecision: decision panic: %v", recovered)
+		}
+	}()
+	result, err = decide(ctx, transaction)
+	if err != nil {
+		return Result{}, fmt.Errorf("authdecision: decide: %w", err)
+	}
+	return result, nil
+}
+
 // Handler returns a net/http handler for UQPAY authorization decision requests.
 // On processing errors it invokes OnError and aborts the response so UQPAY can
 // apply the configured timeout action instead of receiving an accidental empty
@@ -156,6 +238,31 @@ func (c *Client) Handler(options HandlerOptions) (http.HandlerFunc, error) {
 	if maxBodyBytes < 0 {
 		return nil, fmt.Errorf("authdecision: max body bytes cannot be negative")
 	}
+	decide := options.Decide
+	if options.StandIn != nil {
+		standInAfter := options.StandInAfter
+		if standInAfter < 0 {
+			return nil, fmt.Errorf("authdecision: stand-in delay cannot be negative")
+		}
+		if standInAfter == 0 {
+			standInAfter = options.DecisionTimeout * 4 / 5
+		}
+		if options.DecisionTimeout > 0 && standInAfter >= options.DecisionTimeout {
+			return nil, fmt.Errorf("authdecision: stand-in delay must be shorter than the decision timeout")
+		}
+		decide = WithStandIn(decide, options.StandIn, StandInOptions{After: standInAfter, OnStandIn: options.OnStandIn})
+	}
+	if len(options.Shadows) > 0 {
+		for i, shadow := range options.Shadows {
+			if shadow.Decide == nil {
+				return nil, fmt.Errorf("authdecision: shadow %d: decision function is required", i)
+			}
+		}
+		decide = WithShadows(decide, options.Shadows, ShadowOptions{Timeout: options.ShadowTimeout, OnResult: options.OnShadowResult})
+	}
+	if options.Auditor != nil {
+		decide = options.Auditor.Wrap(decide)
+	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		body := http.MaxBytesReader(w, r.Body, maxBodyBytes)
@@ -170,7 +277,7 @@ func (c *Client) Handler(options HandlerOptions) (http.HandlerFunc, error) {
 			ctx, cancel = context.WithTimeout(ctx, options.DecisionTimeout)
 			defer cancel()
 		}
-		encryptedResponse, err := c.Process(ctx, encryptedBody, options.Decide)
+		encryptedResponse, err := c.process(ctx, encryptedBody, decide, options.Auditor)
 		if err != nil {
 			abortHTTPResponse(options.OnError, err)
 		}
@@ -132,6 +132,17 @@ func TestProcessRequiresConfigurationAndValidDecision(t *testing.T) {
 	}); err == nil {
 		t.Fatal("expected empty response code error")
 	}
+	if _, err := configured.Process(context.Background(), []byte(encrypted), func(context.Context, Transaction) (Result, error) {
+		return Result{ResponseCode: "Z9"}, nil
+	}); err == nil || !strings.Contains(err.Error(), "must be two digits") {
+		t.Fatalf("Process with a malformed code = %v, want a format error", err)
+	}
+	// Codes outside the catalog are passed through to UQPAY.
+	if _, err := configured.Process(context.Background(), []byte(encrypted), func(context.Context, Transaction) (Result, error) {
+		return Result{ResponseCode: "06"}, nil
+	}); err != nil {
+		t.Fatalf("Process with an uncatalogued code = %v", err)
+	}
 }
 
 func TestProcessPropagatesContextAndDecisionErrors(t *testing.T) {
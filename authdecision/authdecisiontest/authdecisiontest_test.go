@@ -0,0 +1,157 @@
+package authdecisiontest
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/authdecision"
+	"github.com/uqpay/uqpay-sdk-go/v2/simulator"
+)
+
+var (
+	keysOnce sync.Once
+	testKeys *Keys
+	keysErr  error
+)
+
+// sharedKeys generates one key set for the package; RSA generation is slow.
+func sharedKeys(t *testing.T) *Keys {
+	t.Helper()
+	keysOnce.Do(func() { testKeys, keysErr = GenerateKeys() })
+	if keysErr != nil {
+		t.Fatalf("GenerateKeys: %v", keysErr)
+	}
+	return testKeys
+}
+
+func newTestEmulator(t *testing.T, options authdecision.HandlerOptions) *Emulator {
+	t.Helper()
+	keys := sharedKeys(t)
+	client := authdecision.NewClient()
+	if err := client.Configure(keys.Config()); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	handler, err := client.Handler(options)
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+	emulator, err := keys.Emulator()
+	if err != nil {
+		t.Fatalf("Emulator: %v", err)
+	}
+	emulator.Handler = handler
+	return emulator
+}
+
+func TestEmulatorRoundTripsThroughHandler(t *testing.T) {
+	var got authdecision.Transaction
+	emulator := newTestEmulator(t, authdecision.HandlerOptions{
+		Decide: func(_ context.Context, tx authdecision.Transaction) (authdecision.Result, error) {
+			got = tx
+			return authdecision.Result{ResponseCode: "00", PartnerReferenceID: "ref-" + tx.CardID}, nil
+		},
+	})
+
+	sent := FromAuthorizationRequest(&simulator.AuthorizationRequest{
+		CardID:               "card-123",
+		TransactionAmount:    42.5,
+		TransactionCurrency:  "SGD",
+		MerchantName:         "Test Merchant",
+		MerchantCategoryCode: "5411",
+	})
+	resp, err := emulator.Send(context.Background(), sent)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if resp.ResponseCode != "00" || resp.PartnerReferenceID != "ref-card-123" || resp.TransactionID != sent.TransactionID {
+		t.Fatalf("response = %+v", resp)
+	}
+	if got.TransactionAmount != "42.5" || got.TransactionCurrencyCode != "SGD" || got.MerchantCategoryCode != "5411" || got.PosEntryMode != "81" {
+		t.Fatalf("handler saw %+v", got)
+	}
+
+	server := httptest.NewServer(emulator.Handler)
+	defer server.Close()
+	emulator.Handler = nil
+	emulator.URL = server.URL
+	if _, err := emulator.Send(context.Background(), NewTransaction(CardPresent)); err != nil {
+		t.Fatalf("Send over HTTP: %v", err)
+	}
+}
+
+func TestEmulatorReportsAbortedAndForeignResponses(t *testing.T) {
+	emulator := newTestEmulator(t, authdecision.HandlerOptions{
+		Decide: func(context.Context, authdecision.Transaction) (authdecision.Result, error) {
+			return authdecision.Result{}, nil
+		},
+	})
+	if _, err := emulator.Send(context.Background(), NewTransaction(Ecommerce)); err == nil || !strings.Contains(err.Error(), "aborted") {
+		t.Fatalf("Send error = %v, want aborted response", err)
+	}
+
+	other, err := authdecision.GenerateKeyPair("Other", "other@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger, err := NewEmulator(other.PrivateKey, sharedKeys(t).Customer.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := emulator.Encrypt(NewTransaction(Ecommerce))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stranger.DecryptResponse(body); err == nil {
+		t.Fatal("a message encrypted to another key was decrypted")
+	}
+}
+
+func TestLoadTestReportsPercentilesAndTimeouts(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	// Slow decisions block until the test releases them, so they always time
+	// out; the timeout leaves fast decisions ample room under the race
+	// detector.
+	release := make(chan struct{})
+	defer close(release)
+	emulator := newTestEmulator(t, authdecision.HandlerOptions{
+		Decide: func(ctx context.Context, tx authdecision.Transaction) (authdecision.Result, error) {
+			mu.Lock()
+			calls++
+			slow := calls%10 == 0
+			mu.Unlock()
+			if slow {
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+				return authdecision.Result{}, errors.New("released")
+			}
+			return authdecision.Result{ResponseCode: "00"}, nil
+		},
+	})
+
+	report, err := emulator.LoadTest(context.Background(), LoadOptions{
+		Requests:    20,
+		Concurrency: 4,
+		Timeout:     2 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("LoadTest: %v", err)
+	}
+	t.Log(report)
+	if report.Requests != 20 || report.Succeeded != 18 || report.TimedOut != 2 || report.ResponseCodes["00"] != 18 {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.P99 != report.Timeout || report.WithinTimeout() {
+		t.Fatalf("p99 = %s, WithinTimeout = %v; timed-out requests should count at the timeout", report.P99, report.WithinTimeout())
+	}
+	if report.Min > report.P50 || report.P50 > report.P95 || report.P95 > report.Max {
+		t.Fatalf("percentiles out of order: %s", report)
+	}
+}
@@ -0,0 +1,254 @@
+// Package authdecisiontest plays UQPAY's side of the authorization decision
+// flow so decision endpoints can be tested without UQPAY's private key.
+//
+// GenerateKeys creates a fake UQPAY key pair matched with a customer key pair.
+// Configure the endpoint with Keys.Config, then use the Emulator to encrypt
+// Transactions to the customer key, post them and decrypt the responses:
+//
+//	keys, err := authdecisiontest.GenerateKeys()
+//	client := authdecision.NewClient()
+//	err = client.Configure(keys.Config())
+//	handler, err := client.Handler(authdecision.HandlerOptions{Decide: decide})
+//
+//	emulator, err := keys.Emulator()
+//	emulator.Handler = handler
+//	resp, err := emulator.Send(ctx, authdecisiontest.NewTransaction(authdecisiontest.Ecommerce))
+//
+// LoadTest sends many requests concurrently and reports latency percentiles
+// against UQPAY's decision timeout.
+package authdecisiontest
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/uqpay/uqpay-sdk-go/v2/authdecision"
+)
+
+const maxResponseBytes = 1 << 20
+
+// Keys is a fake UQPAY key pair matched with a customer key pair.
+type Keys struct {
+	UQPAY    *authdecision.KeyPair
+	Customer *authdecision.KeyPair
+}
+
+// GenerateKeys generates a fake UQPAY key pair and a customer key pair.
+func GenerateKeys() (*Keys, error) {
+	uqpay, err := authdecision.GenerateKeyPair("UQPAY Test", "issuing.test@uqpay.com")
+	if err != nil {
+		return nil, err
+	}
+	customer, err := authdecision.GenerateKeyPair("Customer Test", "customer.test@example.com")
+	if err != nil {
+		return nil, err
+	}
+	return &Keys{UQPAY: uqpay, Customer: customer}, nil
+}
+
+// Config returns the customer-side configuration that accepts requests from
+// an Emulator built from k.
+func (k *Keys) Config() authdecision.Config {
+	return authdecision.Config{
+		PrivateKey:     k.Customer.PrivateKey,
+		UQPayPublicKey: k.UQPAY.PublicKey,
+	}
+}
+
+// Emulator returns an emulator that encrypts to k's customer key.
+func (k *Keys) Emulator() (*Emulator, error) {
+	return NewEmulator(k.UQPAY.PrivateKey, k.Customer.PublicKey)
+}
+
+// Response is a decrypted authorization decision response.
+type Response struct {
+	TransactionID      string `json:"transaction_id"`
+	ResponseCode       string `json:"response_code"`
+	PartnerReferenceID string `json:"partner_reference_id"`
+
+	StatusCode int           `json:"-"`
+	Latency    time.Duration `json:"-"`
+}
+
+// Emulator encrypts authorization requests the way UQPAY does and decrypts
+// the endpoint's responses with the fake UQPAY private key.
+type Emulator struct {
+	// URL is the decision endpoint requests are posted to.
+	URL        string
+	HTTPClient *http.Client
+	// Handler, when set, serves requests in-process instead of posting to URL.
+	Handler http.Handler
+
+	customerKeys openpgp.EntityList
+	uqpayKeys    openpgp.EntityList
+}
+
+// NewEmulator creates an emulator that decrypts responses with the armored
+// uqpayPrivateKey and encrypts requests to the armored customerPublicKey.
+func NewEmulator(uqpayPrivateKey, customerPublicKey string) (*Emulator, error) {
+	uqpayKeys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(uqpayPrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("authdecisiontest: parse UQPAY private key: %w", err)
+	}
+	customerKeys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(customerPublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("authdecisiontest: parse customer public key: %w", err)
+	}
+	return &Emulator{
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+		customerKeys: customerKeys,
+		uqpayKeys:    uqpayKeys,
+	}, nil
+}
+
+// Encrypt encodes transaction as JSON and encrypts it to the customer key,
+// producing the body UQPAY posts to the decision endpoint.
+func (e *Emulator) Encrypt(transaction authdecision.Transaction) ([]byte, error) {
+	plaintext, err := json.Marshal(transaction)
+	if err != nil {
+		return nil, fmt.Errorf("authdecisiontest: encode transaction: %w", err)
+	}
+	return e.EncryptRaw(plaintext)
+}
+
+// EncryptRaw encrypts an arbitrary plaintext body to the customer key. Use it
+// to send malformed or unusual transactions.
+func (e *Emulator) EncryptRaw(plaintext []byte) ([]byte, error) {
+	var output bytes.Buffer
+	armoredWriter, err := armor.Encode(&output, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, fmt.Errorf("authdecisiontest: create armored request: %w", err)
+	}
+	plaintextWriter, err := openpgp.Encrypt(armoredWriter, e.customerKeys, nil, nil, packetConfig())
+	if err != nil {
+		return nil, fmt.Errorf("authdecisiontest: encrypt request: %w", err)
+	}
+	if _, err := plaintextWriter.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("authdecisiontest: write encrypted request: %w", err)
+	}
+	if err := plaintextWriter.Close(); err != nil {
+		return nil, fmt.Errorf("authdecisiontest: finalize encrypted request: %w", err)
+	}
+	if err := armoredWriter.Close(); err != nil {
+		return nil, fmt.Errorf("authdecisiontest: finalize armored request: %w", err)
+	}
+	return output.Bytes(), nil
+}
+
+// DecryptResponse decrypts and decodes an encrypted response body.
+func (e *Emulator) DecryptResponse(body []byte) (*Response, error) {
+	block, err := armor.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("authdecisiontest: decode armored response: %w", err)
+	}
+	message, err := openpgp.ReadMessage(block.Body, e.uqpayKeys, nil, packetConfig())
+	if err != nil {
+		return nil, fmt.Errorf("authdecisiontest: decrypt response: %w", err)
+	}
+	plaintext, err := io.ReadAll(io.LimitReader(message.UnverifiedBody, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("authdecisiontest: read decrypted response: %w", err)
+	}
+	var resp Response
+	if err := json.Unmarshal(plaintext, &resp); err != nil {
+		return nil, fmt.Errorf("authdecisiontest: decode response: %w", err)
+	}
+	return &resp, nil
+}
+
+// Send encrypts transaction, posts it to the endpoint and returns the
+// decrypted response. It fails if the endpoint does not answer with HTTP 200,
+// or if the response does not echo the transaction ID or lacks a two-digit
+// response code.
+func (e *Emulator) Send(ctx context.Context, transaction authdecision.Transaction) (*Response, error) {
+	body, err := e.Encrypt(transaction)
+	if err != nil {
+		return nil, err
+	}
+	started := time.Now()
+	statusCode, respBody, err := e.post(ctx, body)
+	latency := time.Since(started)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return &Response{StatusCode: statusCode, Latency: latency}, fmt.Errorf("authdecisiontest: endpoint returned status %d", statusCode)
+	}
+	resp, err := e.DecryptResponse(respBody)
+	if err != nil {
+		return nil, err
+	}
+	resp.StatusCode = statusCode
+	resp.Latency = latency
+	if resp.TransactionID != transaction.TransactionID {
+		return resp, fmt.Errorf("authdecisiontest: response transaction_id %q does not match request %q", resp.TransactionID, transaction.TransactionID)
+	}
+	if len(resp.ResponseCode) != 2 {
+		return resp, fmt.Errorf("authdecisiontest: invalid response_code %q", resp.ResponseCode)
+	}
+	return resp, nil
+}
+
+func (e *Emulator) post(ctx context.Context, body []byte) (int, []byte, error) {
+	url := e.URL
+	if e.Handler != nil && url == "" {
+		url = "http://authdecisiontest.local/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, fmt.Errorf("authdecisiontest: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	if e.Handler != nil {
+		return serve(e.Handler, req)
+	}
+	client := e.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("authdecisiontest: post request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return 0, nil, fmt.Errorf("authdecisiontest: read response: %w", err)
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// serve runs handler in-process. A handler that aborts the response, as
+// authdecision handlers do on errors, is reported as an error just as the
+// dropped connection would be over the network.
+func serve(handler http.Handler, req *http.Request) (statusCode int, body []byte, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			statusCode, body = 0, nil
+			err = fmt.Errorf("authdecisiontest: handler aborted the response: %v", recovered)
+		}
+	}()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	return recorder.Code, recorder.Body.Bytes(), nil
+}
+
+func packetConfig() *packet.Config {
+	return &packet.Config{
+		DefaultHash:            crypto.SHA256,
+		DefaultCipher:          packet.CipherAES256,
+		DefaultCompressionAlgo: packet.CompressionZLIB,
+	}
+}
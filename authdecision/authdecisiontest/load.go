@@ -0,0 +1,177 @@
+package authdecisiontest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/authdecision"
+)
+
+// UQPAY waits between MinDecisionTimeout and MaxDecisionTimeout for a
+// decision, depending on the program configuration, before applying the
+// timeout action.
+const (
+	MinDecisionTimeout = 1 * time.Second
+	MaxDecisionTimeout = 5 * time.Second
+)
+
+const (
+	defaultLoadRequests    = 1000
+	defaultLoadConcurrency = 10
+)
+
+// LoadOptions configures Emulator.LoadTest.
+type LoadOptions struct {
+	// Requests is the total number of requests. Zero sends 1000.
+	Requests int
+	// Concurrency is the number of requests in flight at once. Zero uses 10.
+	Concurrency int
+	// Timeout is the UQPAY decision timeout being tested against; requests
+	// still running when it expires are abandoned and counted as timed out.
+	// Zero uses MinDecisionTimeout, the strictest UQPAY setting.
+	Timeout time.Duration
+	// Transaction builds the i-th request. Nil cycles through Templates.
+	Transaction func(i int) authdecision.Transaction
+}
+
+// LoadReport summarizes a load test. Latency percentiles cover every request,
+// with timed-out requests counted at Timeout.
+type LoadReport struct {
+	Requests  int
+	Succeeded int
+	Failed    int
+	TimedOut  int
+	// ResponseCodes counts successful responses by response code.
+	ResponseCodes map[string]int
+	// FirstError is the first failure other than a timeout, if any.
+	FirstError error
+
+	Timeout time.Duration
+	Elapsed time.Duration
+	Min     time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// WithinTimeout reports whether no request timed out and p99 latency is
+// below the tested timeout.
+func (r *LoadReport) WithinTimeout() bool {
+	return r.TimedOut == 0 && r.P99 < r.Timeout
+}
+
+// String formats the report for test logs.
+func (r *LoadReport) String() string {
+	return fmt.Sprintf("%d requests in %s: %d ok, %d failed, %d timed out; latency min %s p50 %s p95 %s p99 %s max %s (timeout %s)",
+		r.Requests, r.Elapsed, r.Succeeded, r.Failed, r.TimedOut, r.Min, r.P50, r.P95, r.P99, r.Max, r.Timeout)
+}
+
+// LoadTest sends opts.Requests requests with opts.Concurrency workers and
+// measures how quickly the endpoint answers. Individual request failures are
+// recorded in the report; an error is returned only for invalid options or if
+// ctx is cancelled.
+func (e *Emulator) LoadTest(ctx context.Context, opts LoadOptions) (*LoadReport, error) {
+	if opts.Requests < 0 || opts.Concurrency < 0 || opts.Timeout < 0 {
+		return nil, errors.New("authdecisiontest: load options cannot be negative")
+	}
+	if opts.Requests == 0 {
+		opts.Requests = defaultLoadRequests
+	}
+	if opts.Concurrency == 0 {
+		opts.Concurrency = defaultLoadConcurrency
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = MinDecisionTimeout
+	}
+	build := opts.Transaction
+	if build == nil {
+		build = cycleTemplates()
+	}
+
+	report := &LoadReport{Requests: opts.Requests, Timeout: opts.Timeout, ResponseCodes: map[string]int{}}
+	latencies := make([]time.Duration, 0, opts.Requests)
+	var mu sync.Mutex
+	record := func(latency time.Duration, resp *Response, err error, timedOut bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		latencies = append(latencies, latency)
+		switch {
+		case timedOut:
+			report.TimedOut++
+		case err != nil:
+			report.Failed++
+			if report.FirstError == nil {
+				report.FirstError = err
+			}
+		default:
+			report.Succeeded++
+			report.ResponseCodes[resp.ResponseCode]++
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	started := time.Now()
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				requestCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+				begin := time.Now()
+				resp, err := e.Send(requestCtx, build(i))
+				latency := time.Since(begin)
+				timedOut := errors.Is(requestCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+				cancel()
+				if timedOut {
+					latency = opts.Timeout
+				}
+				record(latency, resp, err, timedOut)
+			}
+		}()
+	}
+send:
+	for i := 0; i < opts.Requests; i++ {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	report.Elapsed = time.Since(started)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.Min = latencies[0]
+	report.P50 = percentile(latencies, 0.50)
+	report.P95 = percentile(latencies, 0.95)
+	report.P99 = percentile(latencies, 0.99)
+	report.Max = latencies[len(latencies)-1]
+	return report, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func cycleTemplates() func(int) authdecision.Transaction {
+	templates := []authdecision.Transaction{Ecommerce, CardPresent, WalletContactless, CrossBorder}
+	return func(i int) authdecision.Transaction {
+		return NewTransaction(templates[i%len(templates)])
+	}
+}
@@ -0,0 +1,185 @@
+package authdecisiontest
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uqpay/uqpay-sdk-go/v2/authdecision"
+	"github.com/uqpay/uqpay-sdk-go/v2/simulator"
+)
+
+// FixtureCardID is the card ID used by the transaction templates.
+const FixtureCardID = "a738d29b-3dd7-4fe4-9119-3a3024100f30"
+
+// dateLayout is the date_of_transaction format UQPAY sends.
+const dateLayout = "2006-01-02 15:04:05"
+
+// Transaction templates for common authorization shapes. Pass them to
+// NewTransaction, which fills in fresh identifiers and the current time.
+var (
+	// Ecommerce is a card-not-present purchase authenticated with 3-D Secure.
+	Ecommerce = authdecision.Transaction{
+		TransactionType:                 1000,
+		CardID:                          FixtureCardID,
+		ProcessingCode:                  "00",
+		BillingAmount:                   "25.00",
+		TransactionAmount:               "25.00",
+		AuthAmount:                      "25.00",
+		BillingCurrencyCode:             "USD",
+		TransactionCurrencyCode:         "USD",
+		AuthCurrencyCode:                "USD",
+		CardBalance:                     "1000.00",
+		MerchantID:                      "ONLINESTORE01",
+		MerchantName:                    "Example Online Store",
+		MerchantCategoryCode:            "5999",
+		MerchantCity:                    "SAN FRANCISCO",
+		MerchantCountry:                 "US",
+		TerminalID:                      "WEB00001",
+		PosEntryMode:                    "81",
+		PosConditionCode:                "59",
+		ECI:                             "05",
+		PinEntryCapability:              "0",
+		AcquiringInstitutionCountryCode: "US",
+		AcquiringInstitutionID:          "40000000001",
+	}
+
+	// CardPresent is a chip purchase at a physical terminal.
+	CardPresent = authdecision.Transaction{
+		TransactionType:                 1000,
+		CardID:                          FixtureCardID,
+		ProcessingCode:                  "00",
+		BillingAmount:                   "12.80",
+		TransactionAmount:               "12.80",
+		AuthAmount:                      "12.80",
+		BillingCurrencyCode:             "SGD",
+		TransactionCurrencyCode:         "SGD",
+		AuthCurrencyCode:                "SGD",
+		CardBalance:                     "1000.00",
+		MerchantID:                      "CAFE0001",
+		MerchantName:                    "Example Cafe",
+		MerchantCategoryCode:            "5814",
+		MerchantCity:                    "SINGAPORE",
+		MerchantCountry:                 "SG",
+		TerminalID:                      "TERM0001",
+		PosEntryMode:                    "05",
+		PosConditionCode:                "00",
+		PinEntryCapability:              "1",
+		AcquiringInstitutionCountryCode: "SG",
+		AcquiringInstitutionID:          "40000000002",
+	}
+
+	// WalletContactless is a contactless tap with a mobile wallet.
+	WalletContactless = authdecision.Transaction{
+		TransactionType:                 1000,
+		CardID:                          FixtureCardID,
+		ProcessingCode:                  "00",
+		BillingAmount:                   "8.50",
+		TransactionAmount:               "8.50",
+		AuthAmount:                      "8.50",
+		BillingCurrencyCode:             "USD",
+		TransactionCurrencyCode:         "USD",
+		AuthCurrencyCode:                "USD",
+		CardBalance:                     "1000.00",
+		MerchantID:                      "TRANSIT001",
+		MerchantName:                    "Example Transit",
+		MerchantCategoryCode:            "4111",
+		MerchantCity:                    "NEW YORK",
+		MerchantCountry:                 "US",
+		TerminalID:                      "GATE0001",
+		PosEntryMode:                    "07",
+		PosConditionCode:                "00",
+		PinEntryCapability:              "1",
+		AcquiringInstitutionCountryCode: "US",
+		AcquiringInstitutionID:          "40000000003",
+		WalletType:                      "APPLE PAY",
+	}
+
+	// CrossBorder is an e-commerce purchase in a currency other than the
+	// card's billing currency.
+	CrossBorder = authdecision.Transaction{
+		TransactionType:                 1000,
+		CardID:                          FixtureCardID,
+		ProcessingCode:                  "00",
+		BillingAmount:                   "73.40",
+		TransactionAmount:               "99.00",
+		AuthAmount:                      "73.40",
+		BillingCurrencyCode:             "USD",
+		TransactionCurrencyCode:         "CAD",
+		AuthCurrencyCode:                "USD",
+		CardBalance:                     "1000.00",
+		MerchantID:                      "CAMERCH01",
+		MerchantName:                    "Example Outfitters",
+		MerchantCategoryCode:            "5651",
+		MerchantCity:                    "TORONTO",
+		MerchantCountry:                 "CA",
+		TerminalID:                      "WEB00002",
+		PosEntryMode:                    "81",
+		PosConditionCode:                "59",
+		ECI:                             "06",
+		PinEntryCapability:              "0",
+		AcquiringInstitutionCountryCode: "CA",
+		AcquiringInstitutionID:          "40000000004",
+	}
+)
+
+// Templates returns the built-in transaction templates by name.
+func Templates() map[string]authdecision.Transaction {
+	return map[string]authdecision.Transaction{
+		"ecommerce":          Ecommerce,
+		"card_present":       CardPresent,
+		"wallet_contactless": WalletContactless,
+		"cross_border":       CrossBorder,
+	}
+}
+
+// NewTransaction copies template and gives it a new transaction ID,
+// retrieval reference number, system trace audit number and the current
+// date. Identifiers already set on template are kept.
+func NewTransaction(template authdecision.Transaction) authdecision.Transaction {
+	transaction := template
+	if transaction.TransactionID == "" {
+		transaction.TransactionID = uuid.NewString()
+	}
+	if transaction.DateOfTransaction == "" {
+		transaction.DateOfTransaction = time.Now().UTC().Format(dateLayout)
+	}
+	if transaction.RetrievalReferenceNumber == "" {
+		transaction.RetrievalReferenceNumber = fmt.Sprintf("%012d", rand.Int63n(1e12))
+	}
+	if transaction.SystemTraceAuditNumber == "" {
+		transaction.SystemTraceAuditNumber = fmt.Sprintf("%06d", rand.Int63n(1e6))
+	}
+	return transaction
+}
+
+// FromAuthorizationRequest builds the transaction UQPAY would send for a
+// simulator authorization request, starting from the Ecommerce template.
+// Billing and authorization amounts follow the transaction amount.
+func FromAuthorizationRequest(req *simulator.AuthorizationRequest) authdecision.Transaction {
+	transaction := Ecommerce
+	if req == nil {
+		return NewTransaction(transaction)
+	}
+	if req.CardID != "" {
+		transaction.CardID = req.CardID
+	}
+	amount := strconv.FormatFloat(req.TransactionAmount, 'f', -1, 64)
+	transaction.TransactionAmount = amount
+	transaction.BillingAmount = amount
+	transaction.AuthAmount = amount
+	if req.TransactionCurrency != "" {
+		transaction.TransactionCurrencyCode = req.TransactionCurrency
+		transaction.BillingCurrencyCode = req.TransactionCurrency
+		transaction.AuthCurrencyCode = req.TransactionCurrency
+	}
+	if req.MerchantName != "" {
+		transaction.MerchantName = req.MerchantName
+	}
+	if req.MerchantCategoryCode != "" {
+		transaction.MerchantCategoryCode = req.MerchantCategoryCode
+	}
+	return NewTransaction(transaction)
+}
@@ -0,0 +1,144 @@
+package authdecision
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ResponseCode is an ISO 8583 field 39 response code returned to UQPAY in
+// Result.ResponseCode.
+type ResponseCode string
+
+// Response codes accepted in authorization decisions. ResponseApproved is the
+// only approving code; every other code declines the transaction.
+const (
+	ResponseApproved                 ResponseCode = "00"
+	ResponseReferToIssuer            ResponseCode = "01"
+	ResponseInvalidMerchant          ResponseCode = "03"
+	ResponsePickUpCard               ResponseCode = "04"
+	ResponseDoNotHonor               ResponseCode = "05"
+	ResponseInvalidTransaction       ResponseCode = "12"
+	ResponseInvalidAmount            ResponseCode = "13"
+	ResponseInvalidCardNumber        ResponseCode = "14"
+	ResponseLostCard                 ResponseCode = "41"
+	ResponseStolenCard               ResponseCode = "43"
+	ResponseInsufficientFunds        ResponseCode = "51"
+	ResponseExpiredCard              ResponseCode = "54"
+	ResponseIncorrectPIN             ResponseCode = "55"
+	ResponseNotPermittedToCardholder ResponseCode = "57"
+	ResponseNotPermittedToTerminal   ResponseCode = "58"
+	ResponseSuspectedFraud           ResponseCode = "59"
+	ResponseExceedsAmountLimit       ResponseCode = "61"
+	ResponseRestrictedCard           ResponseCode = "62"
+	ResponseSecurityViolation        ResponseCode = "63"
+	ResponseExceedsFrequencyLimit    ResponseCode = "65"
+	ResponsePINTriesExceeded         ResponseCode = "75"
+	ResponseBlockedFirstUse          ResponseCode = "78"
+	ResponseSystemMalfunction        ResponseCode = "96"
+)
+
+var responseCodeDescriptions = map[ResponseCode]string{
+	ResponseApproved:                 "approved",
+	ResponseReferToIssuer:            "refer to card issuer",
+	ResponseInvalidMerchant:          "invalid merchant",
+	ResponsePickUpCard:               "pick up card",
+	ResponseDoNotHonor:               "do not honor",
+	ResponseInvalidTransaction:       "invalid transaction",
+	ResponseInvalidAmount:            "invalid amount",
+	ResponseInvalidCardNumber:        "invalid card number",
+	ResponseLostCard:                 "lost card",
+	ResponseStolenCard:               "stolen card",
+	ResponseInsufficientFunds:        "insufficient funds",
+	ResponseExpiredCard:              "expired card",
+	ResponseIncorrectPIN:             "incorrect PIN",
+	ResponseNotPermittedToCardholder: "transaction not permitted to cardholder",
+	ResponseNotPermittedToTerminal:   "transaction not permitted to terminal",
+	ResponseSuspectedFraud:           "suspected fraud",
+	ResponseExceedsAmountLimit:       "exceeds withdrawal amount limit",
+	ResponseRestrictedCard:           "restricted card",
+	ResponseSecurityViolation:        "security violation",
+	ResponseExceedsFrequencyLimit:    "exceeds withdrawal frequency limit",
+	ResponsePINTriesExceeded:         "allowable PIN tries exceeded",
+	ResponseBlockedFirstUse:          "blocked, first use",
+	ResponseSystemMalfunction:        "system malfunction",
+}
+
+// ResponseCodes returns every supported response code in ascending order.
+func ResponseCodes() []ResponseCode {
+	codes := make([]ResponseCode, 0, len(responseCodeDescriptions))
+	for code := range responseCodeDescriptions {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+// Valid reports whether c is a supported response code.
+func (c ResponseCode) Valid() bool {
+	_, ok := responseCodeDescriptions[c]
+	return ok
+}
+
+// wellFormed reports whether c has the two-digit format of a response code,
+// whether or not it is in the catalog.
+func (c ResponseCode) wellFormed() bool {
+	return len(c) == 2 && c[0] >= '0' && c[0] <= '9' && c[1] >= '0' && c[1] <= '9'
+}
+
+// Approved reports whether c approves the transaction.
+func (c ResponseCode) Approved() bool {
+	return c == ResponseApproved
+}
+
+// Description returns a short English description of c, or "unknown
+// response code" for unsupported codes.
+func (c ResponseCode) Description() string {
+	if description, ok := responseCodeDescriptions[c]; ok {
+		return description
+	}
+	return "unknown response code"
+}
+
+// Approve returns an approving Result.
+func Approve() Result {
+	return Result{ResponseCode: string(ResponseApproved)}
+}
+
+// Decline returns a declining Result with code and reason. Unsupported codes
+// and ResponseApproved are replaced with ResponseDoNotHonor, so the result is
+// always a valid decline; the replacement is noted in the reason so it shows
+// up in audit records.
+func Decline(code ResponseCode, reason string) Result {
+	if !code.Valid() || code.Approved() {
+		note := fmt.Sprintf("response code %q replaced with %s", string(code), ResponseDoNotHonor)
+		if reason == "" {
+			reason = note
+		} else {
+			reason += " (" + note + ")"
+		}
+		code = ResponseDoNotHonor
+	}
+	return Result{ResponseCode: string(code), Reason: reason}
+}
+
+// WithPartnerReference returns a copy of r with PartnerReferenceID set.
+func (r Result) WithPartnerReference(id string) Result {
+	r.PartnerReferenceID = id
+	return r
+}
+
+// WithReason returns a copy of r with Reason set.
+func (r Result) WithReason(reason string) Result {
+	r.Reason = reason
+	return r
+}
+
+// Code returns r.ResponseCode as a ResponseCode.
+func (r Result) Code() ResponseCode {
+	return ResponseCode(r.ResponseCode)
+}
+
+// Approved reports whether r approves the transaction.
+func (r Result) Approved() bool {
+	return r.Code().Approved()
+}
@@ -0,0 +1,501 @@
+// Package enrich gives authorization decisions the card and cardholder behind
+// each transaction without calling the Issuing API inline.
+//
+// An Enricher keeps a read-through cache of cards and cardholders. It is
+// warmed by periodic List sweeps (Run or Sweep) and kept current by card.*
+// webhook events (HandleEvent). Wrap turns a decision function that takes a
+// *Context into an authdecision.DecisionFunc; on a cache miss it waits at
+// most Options.Budget for the records before deciding without them. Cards
+// and cardholders that could not be fetched are not retried for
+// Options.MissTTL, so unknown cards do not spend the budget on every
+// decision.
+//
+//	enricher := enrich.NewEnricher(client.Issuing, enrich.Options{})
+//	go enricher.Run(ctx)
+//	handler, err := client.Issuing.AuthDecision.Handler(authdecision.HandlerOptions{
+//		Decide: enricher.Wrap(func(ctx context.Context, dc *enrich.Context) (authdecision.Result, error) {
+//			if dc.Cardholder != nil && dc.Cardholder.CountryCode != dc.Transaction.MerchantCountry {
+//				return authdecision.Decline(authdecision.ResponseNotPermittedToCardholder, "foreign merchant"), nil
+//			}
+//			return authdecision.Approve(), nil
+//		}),
+//	})
+package enrich
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/authdecision"
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+	"github.com/uqpay/uqpay-sdk-go/v2/internal/ctxutil"
+	"github.com/uqpay/uqpay-sdk-go/v2/issuing"
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+const (
+	// DefaultBudget is how long Wrap waits for a cache miss to be fetched.
+	DefaultBudget = 150 * time.Millisecond
+	// DefaultTTL is the age after which a cached record is refreshed in the
+	// background.
+	DefaultTTL = 15 * time.Minute
+	// DefaultSweepInterval is how often Run lists all cards and cardholders.
+	DefaultSweepInterval = 15 * time.Minute
+	// DefaultMissTTL is how long a failed fetch is remembered before the
+	// record is fetched again.
+	DefaultMissTTL = time.Minute
+
+	defaultPageSize     = 100
+	defaultFetchTimeout = 10 * time.Second
+)
+
+// Context is what a DecisionFunc sees: the transaction and, when they could
+// be found within the budget, its card and cardholder. Card and Cardholder
+// are copies of the cached records, so changing them does not affect other
+// decisions.
+type Context struct {
+	Transaction authdecision.Transaction
+	// Card is nil when the card is unknown and could not be fetched in time.
+	Card *issuing.RetrieveCardResponse
+	// Cardholder is nil when the card has no cardholder or it could not be
+	// fetched in time.
+	Cardholder *issuing.Cardholder
+	// Stale is set when a cached record older than Options.TTL was used while
+	// a refresh runs in the background.
+	Stale bool
+}
+
+// DecisionFunc decides a transaction with its enrichment context.
+type DecisionFunc func(ctx context.Context, dc *Context) (authdecision.Result, error)
+
+// Options configures an Enricher.
+type Options struct {
+	// Budget bounds how long a decision waits for records missing from the
+	// cache. The fetch continues in the background so the next decision for
+	// the card finds it. Zero uses DefaultBudget.
+	Budget time.Duration
+	// TTL is the age after which cached records are refreshed in the
+	// background. The cached record is still used meanwhile. Zero uses
+	// DefaultTTL.
+	TTL time.Duration
+	// MissTTL is how long a card or cardholder whose fetch failed, e.g.
+	// because it does not exist, is looked up without fetching it again.
+	// Zero uses DefaultMissTTL.
+	MissTTL time.Duration
+	// SweepInterval is the period between sweeps in Run. Zero uses
+	// DefaultSweepInterval.
+	SweepInterval time.Duration
+	// PageSize is the List page size used by sweeps. Zero uses 100.
+	PageSize int
+	// RequestOptions is passed to every API call, e.g. OnBehalfOf.
+	RequestOptions *common.RequestOptions
+	// OnError is called when a background fetch or a sweep in Run fails.
+	OnError func(error)
+}
+
+type cardEntry struct {
+	card      *issuing.RetrieveCardResponse
+	fetchedAt time.Time
+}
+
+type cardholderEntry struct {
+	cardholder *issuing.Cardholder
+	fetchedAt  time.Time
+}
+
+// fetch is an in-flight API call shared by concurrent lookups of one key.
+type fetch struct {
+	done chan struct{}
+	err  error
+}
+
+// Enricher caches cards and cardholders for authorization decisions. It is
+// safe for concurrent use.
+type Enricher struct {
+	cards       *issuing.CardsClient
+	cardholders *issuing.CardholdersClient
+	opts        Options
+	now         func() time.Time
+
+	mu              sync.RWMutex
+	cardCache       map[string]cardEntry
+	cardholderCache map[string]cardholderEntry
+	inflight        map[string]*fetch
+	// misses holds when the last fetch of a key failed.
+	misses map[string]time.Time
+}
+
+// NewEnricher creates an enricher that fetches from client's Cards and
+// Cardholders APIs.
+func NewEnricher(client *issuing.Client, opts Options) *Enricher {
+	if opts.Budget <= 0 {
+		opts.Budget = DefaultBudget
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.SweepInterval <= 0 {
+		opts.SweepInterval = DefaultSweepInterval
+	}
+	if opts.MissTTL <= 0 {
+		opts.MissTTL = DefaultMissTTL
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	return &Enricher{
+		cards:           client.Cards,
+		cardholders:     client.Cardholders,
+		opts:            opts,
+		now:             time.Now,
+		cardCache:       map[string]cardEntry{},
+		cardholderCache: map[string]cardholderEntry{},
+		inflight:        map[string]*fetch{},
+		misses:          map[string]time.Time{},
+	}
+}
+
+// Wrap returns an authdecision.DecisionFunc that builds a Context for each
+// transaction and passes it to decide.
+func (e *Enricher) Wrap(decide DecisionFunc) authdecision.DecisionFunc {
+	return func(ctx context.Context, transaction authdecision.Transaction) (authdecision.Result, error) {
+		return decide(ctx, e.Lookup(ctx, transaction))
+	}
+}
+
+// Lookup builds the Context for transaction, waiting at most Options.Budget
+// (or until ctx is done) for records that are not cached.
+func (e *Enricher) Lookup(ctx context.Context, transaction authdecision.Transaction) *Context {
+	dc := &Context{Transaction: transaction}
+	if transaction.CardID == "" {
+		return dc
+	}
+	budgetCtx, cancel := context.WithTimeout(ctx, e.opts.Budget)
+	defer cancel()
+
+	card, stale := e.card(budgetCtx, transaction.CardID)
+	dc.Card, dc.Stale = card, stale
+	if card == nil || card.Cardholder.CardholderID == "" {
+		return dc
+	}
+	cardholder, stale := e.cardholder(budgetCtx, card.Cardholder.CardholderID)
+	dc.Cardholder = cardholder
+	dc.Stale = dc.Stale || stale
+	return dc
+}
+
+// Card returns a copy of the cached card, or nil.
+func (e *Enricher) Card(cardID string) *issuing.RetrieveCardResponse {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return copyCard(e.cardCache[cardID].card)
+}
+
+// Cardholder returns a copy of the cached cardholder, or nil.
+func (e *Enricher) Cardholder(cardholderID string) *issuing.Cardholder {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return copyCardholder(e.cardholderCache[cardholderID].cardholder)
+}
+
+// PutCard stores card in the cache, e.g. when warming it from a local store.
+func (e *Enricher) PutCard(card *issuing.RetrieveCardResponse) {
+	if card == nil || card.CardID == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cardCache[card.CardID] = cardEntry{card: copyCard(card), fetchedAt: e.now()}
+	delete(e.misses, "card:"+card.CardID)
+}
+
+// PutCardholder stores cardholder in the cache.
+func (e *Enricher) PutCardholder(cardholder *issuing.Cardholder) {
+	if cardholder == nil || cardholder.CardholderID == "" {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.cardholderCache[cardholder.CardholderID] = cardholderEntry{cardholder: copyCardholder(cardholder), fetchedAt: e.now()}
+	delete(e.misses, "cardholder:"+cardholder.CardholderID)
+}
+
+// Len returns the number of cached cards and cardholders.
+func (e *Enricher) Len() (cards, cardholders int) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.cardCache), len(e.cardholderCache)
+}
+
+func (e *Enricher) card(ctx context.Context, cardID string) (*issuing.RetrieveCardResponse, bool) {
+	e.mu.RLock()
+	entry, ok := e.cardCache[cardID]
+	e.mu.RUnlock()
+	if ok {
+		stale := e.now().Sub(entry.fetchedAt) > e.opts.TTL
+		if stale {
+			e.start(ctx, "card:"+cardID, e.fetchCard(cardID))
+		}
+		return copyCard(entry.card), stale
+	}
+	if e.missed("card:" + cardID) {
+		return nil, false
+	}
+	e.wait(ctx, e.start(ctx, "card:"+cardID, e.fetchCard(cardID)))
+	return e.Card(cardID), false
+}
+
+func (e *Enricher) cardholder(ctx context.Context, cardholderID string) (*issuing.Cardholder, bool) {
+	e.mu.RLock()
+	entry, ok := e.cardholderCache[cardholderID]
+	e.mu.RUnlock()
+	if ok {
+		stale := e.now().Sub(entry.fetchedAt) > e.opts.TTL
+		if stale {
+			e.start(ctx, "cardholder:"+cardholderID, e.fetchCardholder(cardholderID))
+		}
+		return copyCardholder(entry.cardholder), stale
+	}
+	if e.missed("cardholder:" + cardholderID) {
+		return nil, false
+	}
+	e.wait(ctx, e.start(ctx, "cardholder:"+cardholderID, e.fetchCardholder(cardholderID)))
+	return e.Cardholder(cardholderID), false
+}
+
+func (e *Enricher) fetchCard(cardID string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		card, err := e.cards.Get(ctx, cardID, e.opts.RequestOptions)
+		if err != nil {
+			return err
+		}
+		e.PutCard(card)
+		return nil
+	}
+}
+
+func (e *Enricher) fetchCardholder(cardholderID string) func(context.Context) error {
+	return func(ctx context.Context) error {
+		cardholder, err := e.cardholders.Get(ctx, cardholderID, e.opts.RequestOptions)
+		if err != nil {
+			return err
+		}
+		e.PutCardholder(cardholder)
+		return nil
+	}
+}
+
+// missed reports whether the last fetch of key failed less than
+// Options.MissTTL ago.
+func (e *Enricher) missed(key string) bool {
+	e.mu.RLock()
+	at, ok := e.misses[key]
+	e.mu.RUnlock()
+	return ok && e.now().Sub(at) < e.opts.MissTTL
+}
+
+// start runs get in the background unless a fetch for key is already in
+// flight, and returns the in-flight fetch. The fetch is detached from ctx so
+// it outlives the decision that triggered it.
+func (e *Enricher) start(ctx context.Context, key string, get func(context.Context) error) *fetch {
+	e.mu.Lock()
+	if f, ok := e.inflight[key]; ok {
+		e.mu.Unlock()
+		return f
+	}
+	f := &fetch{done: make(chan struct{})}
+	e.inflight[key] = f
+	e.mu.Unlock()
+
+	go func() {
+		fetchCtx, cancel := context.WithTimeout(ctxutil.WithoutCancel(ctx), defaultFetchTimeout)
+		defer cancel()
+		f.err = get(fetchCtx)
+		if f.err != nil && e.opts.OnError != nil {
+			e.opts.OnError(fmt.Errorf("failed to fetch %s: %w", key, f.err))
+		}
+		e.mu.Lock()
+		delete(e.inflight, key)
+		if f.err != nil {
+			e.misses[key] = e.now()
+		}
+		e.mu.Unlock()
+		close(f.done)
+	}()
+	return f
+}
+
+func (e *Enricher) wait(ctx context.Context, f *fetch) {
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+	}
+}
+
+// HandleEvent refreshes the cached card named by a card webhook event. The
+// event's card status is applied to the cached card straight away, then the
+// full card is fetched from the API. Other events are ignored; events whose
+// data cannot be decoded return an error.
+func (e *Enricher) HandleEvent(ctx context.Context, event *webhook.Event) error {
+	if event == nil {
+		return nil
+	}
+	payload, err := event.Payload()
+	if errors.Is(err, webhook.ErrUnregisteredEventType) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode webhook event %s: %w", event.EventID, err)
+	}
+	var cardID, status string
+	switch payload := payload.(type) {
+	case *webhook.CardData:
+		cardID, status = payload.CardID, payload.CardStatus
+	case *webhook.CardStatusUpdateData:
+		cardID, status = payload.CardID, payload.CardStatus
+	case *webhook.CardLifecycleData:
+		cardID, status = payload.CardID, payload.CardStatus
+	case *webhook.CardRechargeData:
+		cardID, status = payload.CardID, payload.CardStatus
+	case *webhook.CardActivationCodeData:
+		cardID = payload.CardID
+	default:
+		return nil
+	}
+	if cardID == "" {
+		return nil
+	}
+	if status != "" {
+		e.mu.Lock()
+		if entry, ok := e.cardCache[cardID]; ok {
+			updated := *entry.card
+			updated.CardStatus = status
+			e.cardCache[cardID] = cardEntry{card: &updated, fetchedAt: entry.fetchedAt}
+		}
+		e.mu.Unlock()
+	}
+	card, err := e.cards.Get(ctx, cardID, e.opts.RequestOptions)
+	if err != nil {
+		return fmt.Errorf("failed to refresh card %s: %w", cardID, err)
+	}
+	e.PutCard(card)
+	return nil
+}
+
+// Sweep lists every card and cardholder into the cache and returns how many
+// of each were fetched.
+func (e *Enricher) Sweep(ctx context.Context) (cards, cardholders int, err error) {
+	for page := 1; ; page++ {
+		resp, err := e.cards.List(ctx, &issuing.ListCardsRequest{PageSize: e.opts.PageSize, PageNumber: page}, e.opts.RequestOptions)
+		if err != nil {
+			return cards, cardholders, fmt.Errorf("failed to sweep cards: %w", err)
+		}
+		for i := range resp.Data {
+			e.PutCard(&resp.Data[i])
+		}
+		cards += len(resp.Data)
+		if page >= resp.TotalPages || len(resp.Data) == 0 {
+			break
+		}
+	}
+	for page := 1; ; page++ {
+		resp, err := e.cardholders.List(ctx, &issuing.ListCardholdersRequest{PageSize: e.opts.PageSize, PageNumber: page}, e.opts.RequestOptions)
+		if err != nil {
+			return cards, cardholders, fmt.Errorf("failed to sweep cardholders: %w", err)
+		}
+		for i := range resp.Data {
+			e.PutCardholder(&resp.Data[i])
+		}
+		cardholders += len(resp.Data)
+		if page >= resp.TotalPages || len(resp.Data) == 0 {
+			break
+		}
+	}
+	return cards, cardholders, nil
+}
+
+// Run sweeps immediately and then every SweepInterval until ctx is done.
+// Sweep errors are passed to OnError and do not stop the loop.
+func (e *Enricher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.opts.SweepInterval)
+	defer ticker.Stop()
+	for {
+		if _, _, err := e.Sweep(ctx); err != nil && e.opts.OnError != nil && ctx.Err() == nil {
+			e.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func copyCard(card *issuing.RetrieveCardResponse) *issuing.RetrieveCardResponse {
+	if card == nil {
+		return nil
+	}
+	copied := *card
+	copied.Cardholder.DateOfBirth = copyString(card.Cardholder.DateOfBirth)
+	copied.Cardholder.CountryCode = copyString(card.Cardholder.CountryCode)
+	copied.Cardholder.PhoneNumber = copyString(card.Cardholder.PhoneNumber)
+	if card.SpendingControls != nil {
+		copied.SpendingControls = append([]issuing.SpendingControl(nil), card.SpendingControls...)
+	}
+	if card.RiskControls != nil {
+		controls := *card.RiskControls
+		controls.Allow3DSTransactions = copyString(controls.Allow3DSTransactions)
+		if controls.AllowedMCC != nil {
+			controls.AllowedMCC = append([]string{}, controls.AllowedMCC...)
+		}
+		if controls.BlockedMCC != nil {
+			controls.BlockedMCC = append([]string{}, controls.BlockedMCC...)
+		}
+		copied.RiskControls = &controls
+	}
+	if card.Metadata != nil {
+		copied.Metadata = make(common.FlexibleStringMap, len(card.Metadata))
+		for key, value := range card.Metadata {
+			copied.Metadata[key] = value
+		}
+	}
+	copied.UpdateReason = copyString(card.UpdateReason)
+	copied.ConsumedAmount = copyString(card.ConsumedAmount)
+	return &copied
+}
+
+func copyCardholder(cardholder *issuing.Cardholder) *issuing.Cardholder {
+	if cardholder == nil {
+		return nil
+	}
+	copied := *cardholder
+	copied.DateOfBirth = copyString(cardholder.DateOfBirth)
+	copied.PhoneNumber = copyString(cardholder.PhoneNumber)
+	copied.Gender = copyString(cardholder.Gender)
+	copied.Nationality = copyString(cardholder.Nationality)
+	if cardholder.ResidentialAddress != nil {
+		address := *cardholder.ResidentialAddress
+		address.State = copyString(address.State)
+		address.District = copyString(address.District)
+		address.Line2 = copyString(address.Line2)
+		address.LineEn = copyString(address.LineEn)
+		address.PostalCode = copyString(address.PostalCode)
+		copied.ResidentialAddress = &address
+	}
+	copied.ReviewStatus = copyString(cardholder.ReviewStatus)
+	copied.IdvStatus = copyString(cardholder.IdvStatus)
+	copied.IdvVerificationURL = copyString(cardholder.IdvVerificationURL)
+	copied.IdvURLExpiresAt = copyString(cardholder.IdvURLExpiresAt)
+	return &copied
+}
+
+func copyString(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	copied := *value
+	return &copied
+}
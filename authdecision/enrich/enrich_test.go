@@ -0,0 +1,181 @@
+package enrich
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/authdecision"
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+	"github.com/uqpay/uqpay-sdk-go/v2/configuration"
+	"github.com/uqpay/uqpay-sdk-go/v2/issuing"
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+type staticTokenProvider struct{}
+
+func (*staticTokenProvider) GetToken() (string, error) { return "token_123", nil }
+
+type fakeIssuing struct {
+	mu         sync.Mutex
+	cardStatus string
+	delay      time.Duration
+	gets       int32
+	misses     int32
+}
+
+func (f *fakeIssuing) setStatus(status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cardStatus = status
+}
+
+func newTestEnricher(t *testing.T, fake *fakeIssuing, opts Options) *Enricher {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.mu.Lock()
+		status, delay := fake.cardStatus, fake.delay
+		fake.mu.Unlock()
+		card := `{"card_id":"card-001","card_currency":"USD","card_limit":"500","card_status":"` + status + `","metadata":{"team":"ops"},"cardholder":{"cardholder_id":"holder-001"}}`
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/issuing/cards/card-001":
+			atomic.AddInt32(&fake.gets, 1)
+			time.Sleep(delay)
+			_, _ = w.Write([]byte(card))
+		case "/v1/issuing/cards/card-missing":
+			atomic.AddInt32(&fake.misses, 1)
+			http.Error(w, `{"code":"not_found","message":"card not found"}`, http.StatusNotFound)
+		case "/v1/issuing/cardholders/holder-001":
+			_, _ = w.Write([]byte(`{"cardholder_id":"holder-001","country_code":"SG"}`))
+		case "/v1/issuing/cards":
+			_, _ = w.Write([]byte(`{"total_pages":1,"total_items":1,"data":[` + card + `]}`))
+		case "/v1/issuing/cardholders":
+			_, _ = w.Write([]byte(`{"total_pages":1,"total_items":1,"data":[{"cardholder_id":"holder-001","country_code":"SG"}]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	config := &configuration.Configuration{
+		Environment: &configuration.Environment{BaseURL: server.URL},
+		HTTPClient:  server.Client(),
+	}
+	return NewEnricher(issuing.NewClient(common.NewAPIClient(config, &staticTokenProvider{})), opts)
+}
+
+func TestWrapReadsThroughCacheWithinBudget(t *testing.T) {
+	fake := &fakeIssuing{cardStatus: "ACTIVE"}
+	enricher := newTestEnricher(t, fake, Options{Budget: time.Second})
+
+	var seen *Context
+	decide := enricher.Wrap(func(_ context.Context, dc *Context) (authdecision.Result, error) {
+		seen = dc
+		return authdecision.Approve(), nil
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := decide(context.Background(), authdecision.Transaction{TransactionID: "tx", CardID: "card-001"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if seen.Card == nil || seen.Card.Metadata["team"] != "ops" || seen.Cardholder == nil || seen.Cardholder.CountryCode != "SG" || seen.Stale {
+		t.Fatalf("context = %+v", seen)
+	}
+	if gets := atomic.LoadInt32(&fake.gets); gets != 1 {
+		t.Fatalf("card fetched %d times, want 1", gets)
+	}
+}
+
+func TestLookupGivesUpAfterBudgetButKeepsFetching(t *testing.T) {
+	fake := &fakeIssuing{cardStatus: "ACTIVE", delay: 100 * time.Millisecond}
+	enricher := newTestEnricher(t, fake, Options{Budget: 10 * time.Millisecond})
+
+	started := time.Now()
+	dc := enricher.Lookup(context.Background(), authdecision.Transaction{CardID: "card-001"})
+	if dc.Card != nil {
+		t.Fatal("card returned before the slow fetch finished")
+	}
+	if elapsed := time.Since(started); elapsed > 80*time.Millisecond {
+		t.Fatalf("Lookup took %s, budget was 10ms", elapsed)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for enricher.Card("card-001") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("background fetch never filled the cache")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSweepAndCardEventsKeepCacheCurrent(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeIssuing{cardStatus: "ACTIVE"}
+	enricher := newTestEnricher(t, fake, Options{})
+
+	cards, cardholders, err := enricher.Sweep(ctx)
+	if err != nil || cards != 1 || cardholders != 1 {
+		t.Fatalf("Sweep = %d, %d, %v", cards, cardholders, err)
+	}
+	dc := enricher.Lookup(ctx, authdecision.Transaction{CardID: "card-001"})
+	if dc.Card == nil || dc.Cardholder == nil || atomic.LoadInt32(&fake.gets) != 0 {
+		t.Fatalf("sweep did not warm the cache: %+v", dc)
+	}
+
+	fake.setStatus("FROZEN")
+	event := &webhook.Event{EventType: webhook.EventTypeCardSuspended, EventID: "evt-1", Data: []byte(`{"card_id":"card-001","card_status":"FROZEN"}`)}
+	if err := enricher.HandleEvent(ctx, event); err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if got := enricher.Card("card-001").CardStatus; got != "FROZEN" {
+		t.Fatalf("card status = %s", got)
+	}
+	if err := enricher.HandleEvent(ctx, &webhook.Event{EventType: webhook.EventTypePayoutCompleted, Data: []byte(`{}`)}); err != nil {
+		t.Fatalf("non-card event: %v", err)
+	}
+	if err := enricher.HandleEvent(ctx, &webhook.Event{EventType: webhook.EventTypeCardSuspended, EventID: "evt-2", Data: []byte(`{"card_id":1}`)}); err == nil {
+		t.Fatal("undecodable card event was not reported")
+	}
+
+	enricher.now = func() time.Time { return time.Now().Add(DefaultTTL + time.Minute) }
+	if dc := enricher.Lookup(ctx, authdecision.Transaction{CardID: "card-001"}); !dc.Stale || dc.Card == nil {
+		t.Fatalf("expired entry should be served stale: %+v", dc)
+	}
+}
+
+func TestLookupCachesMissesAndReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeIssuing{cardStatus: "ACTIVE"}
+	enricher := newTestEnricher(t, fake, Options{Budget: time.Second})
+
+	for i := 0; i < 3; i++ {
+		if dc := enricher.Lookup(ctx, authdecision.Transaction{CardID: "card-missing"}); dc.Card != nil {
+			t.Fatalf("unknown card returned: %+v", dc.Card)
+		}
+	}
+	if misses := atomic.LoadInt32(&fake.misses); misses != 1 {
+		t.Fatalf("unknown card fetched %d times, want 1", misses)
+	}
+	enricher.now = func() time.Time { return time.Now().Add(DefaultMissTTL + time.Second) }
+	enricher.Lookup(ctx, authdecision.Transaction{CardID: "card-missing"})
+	if misses := atomic.LoadInt32(&fake.misses); misses != 2 {
+		t.Fatalf("unknown card fetched %d times after MissTTL, want 2", misses)
+	}
+
+	dc := enricher.Lookup(ctx, authdecision.Transaction{CardID: "card-001"})
+	if dc.Card == nil || dc.Cardholder == nil {
+		t.Fatalf("context = %+v", dc)
+	}
+	dc.Card.CardStatus = "CANCELLED"
+	dc.Card.Metadata["team"] = "changed"
+	dc.Cardholder.CountryCode = "US"
+	again := enricher.Lookup(ctx, authdecision.Transaction{CardID: "card-001"})
+	if again.Card.CardStatus != "ACTIVE" || again.Card.Metadata["team"] != "ops" || again.Cardholder.CountryCode != "SG" {
+		t.Fatalf("decision changed the cached records: %+v, %+v", again.Card, again.Cardholder)
+	}
+}
@@ -0,0 +1,217 @@
+package authdecision
+
+// TransactionKind is the transaction type carried in the first two digits of
+// the ISO 8583 processing code.
+type TransactionKind string
+
+const (
+	KindUnknown        TransactionKind = "unknown"
+	KindPurchase       TransactionKind = "purchase"
+	KindCashWithdrawal TransactionKind = "cash_withdrawal"
+	KindPurchaseCash   TransactionKind = "purchase_with_cashback"
+	KindQuasiCash      TransactionKind = "quasi_cash"
+	KindAccountFunding TransactionKind = "account_funding"
+	KindRefund         TransactionKind = "refund"
+	KindPayment        TransactionKind = "payment"
+	KindBalanceInquiry TransactionKind = "balance_inquiry"
+)
+
+var processingCodeKinds = map[string]TransactionKind{
+	"00": KindPurchase,
+	"01": KindCashWithdrawal,
+	"09": KindPurchaseCash,
+	"10": KindAccountFunding,
+	"11": KindQuasiCash,
+	"20": KindRefund,
+	"26": KindPayment,
+	"28": KindPayment,
+	"30": KindBalanceInquiry,
+}
+
+// RequestType is the kind of decision request, decoded from the numeric
+// Transaction.TransactionType.
+type RequestType string
+
+const (
+	RequestTypeUnknown       RequestType = "unknown"
+	RequestTypeAuthorization RequestType = "authorization"
+)
+
+// TransactionTypeAuthorization is the TransactionType of an authorization
+// request.
+const TransactionTypeAuthorization = 1000
+
+var transactionTypes = map[int]RequestType{
+	TransactionTypeAuthorization: RequestTypeAuthorization,
+}
+
+// EntryMode is how the card details were captured, decoded from the first
+// two digits of the POS entry mode.
+type EntryMode string
+
+const (
+	EntryModeUnknown           EntryMode = "unknown"
+	EntryModeManual            EntryMode = "manual"
+	EntryModeMagstripe         EntryMode = "magstripe"
+	EntryModeChip              EntryMode = "chip"
+	EntryModeContactless       EntryMode = "contactless"
+	EntryModeContactlessStripe EntryMode = "contactless_magstripe"
+	EntryModeCredentialOnFile  EntryMode = "credential_on_file"
+	EntryModeEcommerce         EntryMode = "ecommerce"
+	EntryModeChipFallback      EntryMode = "chip_fallback"
+)
+
+var posEntryModes = map[string]EntryMode{
+	"01": EntryModeManual,
+	"02": EntryModeMagstripe,
+	"90": EntryModeMagstripe,
+	"05": EntryModeChip,
+	"95": EntryModeChip,
+	"07": EntryModeContactless,
+	"91": EntryModeContactlessStripe,
+	"10": EntryModeCredentialOnFile,
+	"81": EntryModeEcommerce,
+	"79": EntryModeChipFallback,
+	"80": EntryModeChipFallback,
+}
+
+// ThreeDSStatus is the 3-D Secure outcome decoded from the ECI.
+type ThreeDSStatus string
+
+const (
+	// ThreeDSNone means no ECI was sent, as for card-present transactions.
+	ThreeDSNone ThreeDSStatus = "none"
+	// ThreeDSAuthenticated means the cardholder was fully authenticated.
+	ThreeDSAuthenticated ThreeDSStatus = "authenticated"
+	// ThreeDSAttempted means authentication was attempted but the issuer or
+	// cardholder did not take part.
+	ThreeDSAttempted ThreeDSStatus = "attempted"
+	// ThreeDSNotAuthenticated means the e-commerce transaction was not
+	// authenticated.
+	ThreeDSNotAuthenticated ThreeDSStatus = "not_authenticated"
+)
+
+// Visa and Mastercard use different ECI values for the same outcome.
+var eciStatuses = map[string]ThreeDSStatus{
+	"05": ThreeDSAuthenticated,
+	"02": ThreeDSAuthenticated,
+	"06": ThreeDSAttempted,
+	"01": ThreeDSAttempted,
+	"07": ThreeDSNotAuthenticated,
+	"00": ThreeDSNotAuthenticated,
+}
+
+// POS condition codes that mean the card was not at the terminal.
+var cardNotPresentConditions = map[string]bool{
+	"01": true, // customer not present
+	"08": true, // mail or telephone order
+	"59": true, // e-commerce
+}
+
+const (
+	atmMCC          = "6011"
+	posEnvRecurring = "R"
+	posEnvInstall   = "I"
+	posEnvCOF       = "C"
+	pinCapable      = "1"
+)
+
+// Kind decodes ProcessingCode.
+func (t Transaction) Kind() TransactionKind {
+	if len(t.ProcessingCode) < 2 {
+		return KindUnknown
+	}
+	if kind, ok := processingCodeKinds[t.ProcessingCode[:2]]; ok {
+		return kind
+	}
+	return KindUnknown
+}
+
+// RequestType decodes TransactionType. Types this package does not know
+// decode as RequestTypeUnknown.
+func (t Transaction) RequestType() RequestType {
+	if requestType, ok := transactionTypes[t.TransactionType]; ok {
+		return requestType
+	}
+	return RequestTypeUnknown
+}
+
+// EntryMode decodes PosEntryMode.
+func (t Transaction) EntryMode() EntryMode {
+	if len(t.PosEntryMode) < 2 {
+		return EntryModeUnknown
+	}
+	if mode, ok := posEntryModes[t.PosEntryMode[:2]]; ok {
+		return mode
+	}
+	return EntryModeUnknown
+}
+
+// ThreeDS decodes ECI.
+func (t Transaction) ThreeDS() ThreeDSStatus {
+	if t.ECI == "" {
+		return ThreeDSNone
+	}
+	if status, ok := eciStatuses[t.ECI]; ok {
+		return status
+	}
+	return ThreeDSNotAuthenticated
+}
+
+// CardPresent reports whether the card was read at a terminal: the POS
+// condition code does not mark the transaction as remote and the entry mode
+// is chip, contactless or magnetic stripe.
+func (t Transaction) CardPresent() bool {
+	if cardNotPresentConditions[t.PosConditionCode] {
+		return false
+	}
+	switch t.EntryMode() {
+	case EntryModeChip, EntryModeContactless, EntryModeContactlessStripe, EntryModeMagstripe, EntryModeChipFallback:
+		return true
+	}
+	return false
+}
+
+// Ecommerce reports whether the transaction is an e-commerce purchase.
+func (t Transaction) Ecommerce() bool {
+	return t.EntryMode() == EntryModeEcommerce || t.PosConditionCode == "59" || t.ECI != ""
+}
+
+// Contactless reports whether the card or device was tapped.
+func (t Transaction) Contactless() bool {
+	mode := t.EntryMode()
+	return mode == EntryModeContactless || mode == EntryModeContactlessStripe
+}
+
+// Chip reports whether the card's chip was read by contact.
+func (t Transaction) Chip() bool {
+	return t.EntryMode() == EntryModeChip
+}
+
+// ThreeDSAuthenticated reports whether the cardholder was fully
+// authenticated with 3-D Secure.
+func (t Transaction) ThreeDSAuthenticated() bool {
+	return t.ThreeDS() == ThreeDSAuthenticated
+}
+
+// Recurring reports whether the merchant flagged the transaction as a
+// recurring or installment payment.
+func (t Transaction) Recurring() bool {
+	return t.PosEnv == posEnvRecurring || t.PosEnv == posEnvInstall
+}
+
+// CredentialOnFile reports whether the merchant used stored card details.
+func (t Transaction) CredentialOnFile() bool {
+	return t.PosEnv == posEnvCOF || t.EntryMode() == EntryModeCredentialOnFile || t.Recurring()
+}
+
+// ATMWithdrawal reports whether the transaction is a cash withdrawal at an
+// ATM.
+func (t Transaction) ATMWithdrawal() bool {
+	return t.Kind() == KindCashWithdrawal && t.MerchantCategoryCode == atmMCC
+}
+
+// PINCapable reports whether the terminal can accept a PIN.
+func (t Transaction) PINCapable() bool {
+	return t.PinEntryCapability == pinCapable
+}
@@ -0,0 +1,107 @@
+package authdecision
+
+import "testing"
+
+func TestResponseCodeCatalogAndBuilders(t *testing.T) {
+	codes := ResponseCodes()
+	if len(codes) == 0 || codes[0] != ResponseApproved {
+		t.Fatalf("ResponseCodes() = %v", codes)
+	}
+	for _, code := range codes {
+		if len(code) != 2 || !code.Valid() || code.Description() == "unknown response code" {
+			t.Errorf("catalog entry %q is incomplete", code)
+		}
+		if code.Approved() != (code == ResponseApproved) {
+			t.Errorf("%q Approved() = %v", code, code.Approved())
+		}
+	}
+	if ResponseCode("XX").Valid() {
+		t.Error("unknown code reported valid")
+	}
+
+	approved := Approve().WithPartnerReference("ref-1").WithReason("ok")
+	if approved.ResponseCode != "00" || !approved.Approved() || approved.PartnerReferenceID != "ref-1" || approved.Reason != "ok" {
+		t.Fatalf("Approve() = %+v", approved)
+	}
+	for _, tt := range []struct {
+		code   ResponseCode
+		want   ResponseCode
+		reason string
+	}{
+		{ResponseInsufficientFunds, ResponseInsufficientFunds, "blocked"},
+		{ResponseSuspectedFraud, ResponseSuspectedFraud, "blocked"},
+		{ResponseApproved, ResponseDoNotHonor, `blocked (response code "00" replaced with 05)`},
+		{ResponseCode("ZZ"), ResponseDoNotHonor, `blocked (response code "ZZ" replaced with 05)`},
+	} {
+		declined := Decline(tt.code, "blocked")
+		if declined.Code() != tt.want || declined.Approved() || declined.Reason != tt.reason {
+			t.Errorf("Decline(%q) = %+v, want code %q and reason %q", tt.code, declined, tt.want, tt.reason)
+		}
+	}
+}
+
+func TestTransactionFieldDecoders(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		transaction Transaction
+		kind        TransactionKind
+		mode        EntryMode
+		threeDS     ThreeDSStatus
+		present     bool
+		ecommerce   bool
+		contactless bool
+		chip        bool
+		recurring   bool
+		atm         bool
+	}{
+		{
+			name:        "3ds ecommerce",
+			transaction: Transaction{ProcessingCode: "000000", PosEntryMode: "810", PosConditionCode: "59", ECI: "05"},
+			kind:        KindPurchase, mode: EntryModeEcommerce, threeDS: ThreeDSAuthenticated, ecommerce: true,
+		},
+		{
+			name:        "chip purchase",
+			transaction: Transaction{ProcessingCode: "00", PosEntryMode: "051", PosConditionCode: "00", PinEntryCapability: "1"},
+			kind:        KindPurchase, mode: EntryModeChip, threeDS: ThreeDSNone, present: true, chip: true,
+		},
+		{
+			name:        "contactless wallet",
+			transaction: Transaction{ProcessingCode: "00", PosEntryMode: "07", PosConditionCode: "00", WalletType: "APPLE PAY"},
+			kind:        KindPurchase, mode: EntryModeContactless, threeDS: ThreeDSNone, present: true, contactless: true,
+		},
+		{
+			name:        "recurring subscription",
+			transaction: Transaction{ProcessingCode: "00", PosEntryMode: "10", PosConditionCode: "08", PosEnv: "R"},
+			kind:        KindPurchase, mode: EntryModeCredentialOnFile, threeDS: ThreeDSNone, recurring: true,
+		},
+		{
+			name:        "atm withdrawal",
+			transaction: Transaction{ProcessingCode: "011000", PosEntryMode: "05", PosConditionCode: "02", MerchantCategoryCode: "6011"},
+			kind:        KindCashWithdrawal, mode: EntryModeChip, threeDS: ThreeDSNone, present: true, chip: true, atm: true,
+		},
+		{
+			name:        "unknown fields",
+			transaction: Transaction{ProcessingCode: "7", PosEntryMode: "99", ECI: "99"},
+			kind:        KindUnknown, mode: EntryModeUnknown, threeDS: ThreeDSNotAuthenticated, ecommerce: true,
+		},
+	} {
+		tx := tt.transaction
+		if tx.Kind() != tt.kind || tx.EntryMode() != tt.mode || tx.ThreeDS() != tt.threeDS {
+			t.Errorf("%s: kind %s mode %s 3ds %s", tt.name, tx.Kind(), tx.EntryMode(), tx.ThreeDS())
+		}
+		if tx.CardPresent() != tt.present || tx.Ecommerce() != tt.ecommerce || tx.Contactless() != tt.contactless ||
+			tx.Chip() != tt.chip || tx.Recurring() != tt.recurring || tx.ATMWithdrawal() != tt.atm {
+			t.Errorf("%s: present %v ecommerce %v contactless %v chip %v recurring %v atm %v", tt.name,
+				tx.CardPresent(), tx.Ecommerce(), tx.Contactless(), tx.Chip(), tx.Recurring(), tx.ATMWithdrawal())
+		}
+	}
+	if !(Transaction{PosEnv: "R"}).CredentialOnFile() || !(Transaction{PinEntryCapability: "1"}).PINCapable() {
+		t.Error("recurring transactions should be credential-on-file and capability 1 should accept PIN")
+	}
+	if got := (Transaction{TransactionType: TransactionTypeAuthorization}).RequestType(); got != RequestTypeAuthorization {
+		t.Errorf("RequestType(1000) = %s", got)
+	}
+	if got := (Transaction{TransactionType: 4242}).RequestType(); got != RequestTypeUnknown {
+		t.Errorf("RequestType(4242) = %s", got)
+	}
+}
@@ -0,0 +1,158 @@
+package authdecision
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/configuration"
+)
+
+// KeySource supplies key material from outside the process, such as an
+// environment variable, a mounted file or a secret manager. See
+// configuration.EnvKey, configuration.FileKey and configuration.KeySourceFunc.
+type KeySource = configuration.KeySource
+
+// KeySources names where the key fields of a Config come from. A nil source
+// leaves the corresponding field of the base Config unchanged, and non-empty
+// AdditionalPrivateKeys or ScheduledUQPayPublicKeys replace the base Config's
+// lists. Fetched keys may be armored text or, as with Config, a path to an
+// .asc/.pgp/.gpg file.
+type KeySources struct {
+	PrivateKey     KeySource
+	UQPayPublicKey KeySource
+	Passphrase     KeySource
+
+	// AdditionalPrivateKeys supply Config.AdditionalPrivateKeys.
+	AdditionalPrivateKeys []PrivateKeySource
+	// ScheduledUQPayPublicKeys supply Config.ScheduledUQPayPublicKeys.
+	ScheduledUQPayPublicKeys []ScheduledPublicKeySource
+}
+
+// PrivateKeySource supplies a PrivateKey. A nil Passphrase falls back to
+// Config.Passphrase.
+type PrivateKeySource struct {
+	Key        KeySource
+	Passphrase KeySource
+}
+
+// ScheduledPublicKeySource supplies a ScheduledPublicKey that becomes active
+// at ActivateAt.
+type ScheduledPublicKeySource struct {
+	Key        KeySource
+	ActivateAt time.Time
+}
+
+func (s KeySources) list() []KeySource {
+	sources := []KeySource{s.PrivateKey, s.UQPayPublicKey, s.Passphrase}
+	for _, key := range s.AdditionalPrivateKeys {
+		sources = append(sources, key.Key, key.Passphrase)
+	}
+	for _, key := range s.ScheduledUQPayPublicKeys {
+		sources = append(sources, key.Key)
+	}
+	return sources
+}
+
+// apply overlays values, fetched in list order, onto base.
+func (s KeySources) apply(base Config, values []string) Config {
+	if s.PrivateKey != nil {
+		base.PrivateKey = values[0]
+	}
+	if s.UQPayPublicKey != nil {
+		base.UQPayPublicKey = values[1]
+	}
+	if s.Passphrase != nil {
+		base.Passphrase = values[2]
+	}
+	values = values[3:]
+	if len(s.AdditionalPrivateKeys) > 0 {
+		base.AdditionalPrivateKeys = make([]PrivateKey, len(s.AdditionalPrivateKeys))
+		for i := range s.AdditionalPrivateKeys {
+			base.AdditionalPrivateKeys[i] = PrivateKey{Key: values[0], Passphrase: values[1]}
+			values = values[2:]
+		}
+	}
+	if len(s.ScheduledUQPayPublicKeys) > 0 {
+		base.ScheduledUQPayPublicKeys = make([]ScheduledPublicKey, len(s.ScheduledUQPayPublicKeys))
+		for i, key := range s.ScheduledUQPayPublicKeys {
+			base.ScheduledUQPayPublicKeys[i] = ScheduledPublicKey{Key: values[0], ActivateAt: key.ActivateAt}
+			values = values[1:]
+		}
+	}
+	return base
+}
+
+// ConfigureFrom fetches the keys named by sources once and calls Configure
+// with base overlaid by the fetched values.
+func (c *Client) ConfigureFrom(ctx context.Context, base Config, sources KeySources) error {
+	values, err := configuration.FetchKeys(ctx, sources.list()...)
+	if err != nil {
+		return fmt.Errorf("authdecision: fetch keys: %w", err)
+	}
+	if err := c.configureFromValues(base, sources, values); err != nil {
+		return fmt.Errorf("authdecision: %w", err)
+	}
+	return nil
+}
+
+// configureFromValues configures the client from fetched key values and
+// remembers them, so WatchKeys can skip reapplying unchanged keys.
+func (c *Client) configureFromValues(base Config, sources KeySources, values []string) error {
+	if err := c.configure(sources.apply(base, values)); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.keyValues = append([]string(nil), values...)
+	c.mu.Unlock()
+	return nil
+}
+
+// configuredWith reports whether the client's keys were last set from values.
+func (c *Client) configuredWith(values []string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.pgp == nil || c.keyValues == nil || len(c.keyValues) != len(values) {
+		return false
+	}
+	for i := range values {
+		if c.keyValues[i] != values[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchKeys configures the client from sources and then re-fetches them every
+// interval, calling Configure again whenever a fetched value changes. Zero
+// interval uses configuration.DefaultReloadInterval. If a reload fails the
+// error is passed to onError and the client keeps its previous keys; an error
+// from the initial load is returned. When ConfigureFrom already applied the
+// same values, the initial load does not configure the client again.
+// WatchKeys blocks until ctx is done, so run it in its own goroutine after the
+// initial ConfigureFrom:
+//
+//	if err := client.ConfigureFrom(ctx, base, sources); err != nil {
+//		return err
+//	}
+//	go func() {
+//		if err := client.WatchKeys(ctx, base, sources, time.Minute, logError); err != nil && ctx.Err() == nil {
+//			logError(err)
+//		}
+//	}()
+func (c *Client) WatchKeys(ctx context.Context, base Config, sources KeySources, interval time.Duration, onError func(error)) error {
+	err := configuration.WatchKeys(ctx, interval, func(values []string) error {
+		if c.configuredWith(values) {
+			return nil
+		}
+		return c.configureFromValues(base, sources, values)
+	}, func(err error) {
+		if onError != nil {
+			onError(fmt.Errorf("authdecision: watch keys: %w", err))
+		}
+	}, sources.list()...)
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("authdecision: watch keys: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,148 @@
+package authdecision
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/configuration"
+)
+
+func TestConfigureFromKeySources(t *testing.T) {
+	customer := mustGenerateKeyPair(t, "Customer", "customer@example.com")
+	uqpay := mustGenerateKeyPair(t, "UQPAY", "issuing.tech@uqpay.com")
+	t.Setenv("UQPAY_TEST_PRIVATE_KEY", customer.PrivateKey)
+	publicPath := filepath.Join(t.TempDir(), "uqpay-public")
+	if err := os.WriteFile(publicPath, []byte(uqpay.PublicKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient()
+	if err := client.ConfigureFrom(context.Background(), Config{}, KeySources{
+		PrivateKey:     configuration.EnvKey("UQPAY_TEST_PRIVATE_KEY"),
+		UQPayPublicKey: configuration.FileKey(publicPath),
+	}); err != nil {
+		t.Fatalf("ConfigureFrom: %v", err)
+	}
+	if got, want := activeFingerprint(t, client), fingerprint(t, customer.PublicKey); got != want {
+		t.Fatalf("active key = %s, want %s", got, want)
+	}
+
+	err := NewClient().ConfigureFrom(context.Background(), Config{UQPayPublicKey: uqpay.PublicKey}, KeySources{
+		PrivateKey: configuration.EnvKey("UQPAY_TEST_MISSING_KEY"),
+	})
+	if err == nil {
+		t.Fatal("ConfigureFrom succeeded with a missing environment variable")
+	}
+}
+
+func TestWatchKeysReconfiguresWhenSecretChanges(t *testing.T) {
+	first := mustGenerateKeyPair(t, "Customer 2025", "customer@example.com")
+	second := mustGenerateKeyPair(t, "Customer 2026", "customer@example.com")
+	uqpay := mustGenerateKeyPair(t, "UQPAY", "issuing.tech@uqpay.com")
+
+	var mu sync.Mutex
+	current, fetchErr := first.PrivateKey, error(nil)
+	source := configuration.KeySourceFunc(func(context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return current, fetchErr
+	})
+	set := func(key string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		current, fetchErr = key, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := NewClient()
+	errs := make(chan error, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- client.WatchKeys(ctx, Config{UQPayPublicKey: uqpay.PublicKey}, KeySources{PrivateKey: source}, 5*time.Millisecond, func(err error) { errs <- err })
+	}()
+
+	waitForFingerprint(t, client, fingerprint(t, first.PublicKey))
+	set("", errors.New("secret manager unavailable"))
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("onError called with nil")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("reload error was not reported")
+	}
+	if got, want := activeFingerprint(t, client), fingerprint(t, first.PublicKey); got != want {
+		t.Fatalf("failed reload replaced the key: %s, want %s", got, want)
+	}
+
+	set(second.PrivateKey, nil)
+	waitForFingerprint(t, client, fingerprint(t, second.PublicKey))
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("WatchKeys returned %v, want context.Canceled", err)
+	}
+}
+
+func activeFingerprint(t *testing.T, client *Client) string {
+	t.Helper()
+	exported, err := client.ActivePublicKey()
+	if err != nil {
+		return ""
+	}
+	return fingerprint(t, exported)
+}
+
+func waitForFingerprint(t *testing.T, client *Client, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for activeFingerprint(t, client) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("active key never became %s", want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConfigureFromSuppliesRotationKeysAndWatchKeysSkipsUnchangedKeys(t *testing.T) {
+	customer := mustGenerateKeyPair(t, "Customer 2026", "customer@example.com")
+	previous := mustGenerateKeyPair(t, "Customer 2025", "customer@example.com")
+	uqpay := mustGenerateKeyPair(t, "UQPAY", "issuing.tech@uqpay.com")
+	nextUQPay := mustGenerateKeyPair(t, "UQPAY 2027", "issuing.tech@uqpay.com")
+
+	base := Config{UQPayPublicKey: uqpay.PublicKey}
+	sources := KeySources{
+		PrivateKey:            configuration.StaticKey(customer.PrivateKey),
+		AdditionalPrivateKeys: []PrivateKeySource{{Key: configuration.StaticKey(previous.PrivateKey)}},
+		ScheduledUQPayPublicKeys: []ScheduledPublicKeySource{
+			{Key: configuration.StaticKey(nextUQPay.PublicKey), ActivateAt: time.Now().Add(24 * time.Hour)},
+		},
+	}
+	client := NewClient()
+	if err := client.ConfigureFrom(context.Background(), base, sources); err != nil {
+		t.Fatalf("ConfigureFrom: %v", err)
+	}
+	roles := map[KeyRole]int{}
+	for _, key := range client.Keys() {
+		roles[key.Role]++
+	}
+	if roles[KeyRoleCustomerPrivate] != 2 || roles[KeyRoleUQPayPublic] != 2 {
+		t.Fatalf("keys by role = %v, want two of each", roles)
+	}
+
+	configured := client.snapshot()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := client.WatchKeys(ctx, base, sources, time.Hour, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WatchKeys = %v", err)
+	}
+	if client.snapshot() != configured {
+		t.Fatal("WatchKeys configured the client again with unchanged keys")
+	}
+}
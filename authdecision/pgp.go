@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"crypto"
 	"crypto/rsa"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -50,40 +52,159 @@ func GenerateKeyPair(name, email string) (*KeyPair, error) {
 }
 
 type pgpContext struct {
-	privateKeys openpgp.EntityList
-	publicKeys  openpgp.EntityList
+	// privateKeys holds every customer key accepted for decryption, starting
+	// with the active key's entities.
+	privateKeys   openpgp.EntityList
+	activePrivate openpgp.EntityList
+	// publicKeys is ordered by activation time; the first entry is active
+	// from the start.
+	publicKeys []scheduledKeys
+	now        func() time.Time
+}
+
+type scheduledKeys struct {
+	activateAt time.Time
+	keys       openpgp.EntityList
 }
 
 func newPGPContext(config Config) (*pgpContext, error) {
-	privateArmored, err := resolveKey(config.PrivateKey)
+	now := time.Now()
+	activePrivate, err := readPrivateKey(config.PrivateKey, config.Passphrase)
 	if err != nil {
-		return nil, fmt.Errorf("authdecision: resolve private key: %w", err)
+		return nil, err
+	}
+	privateKeys := append(openpgp.EntityList(nil), activePrivate...)
+	for i, additional := range config.AdditionalPrivateKeys {
+		passphrase := additional.Passphrase
+		if passphrase == "" {
+			passphrase = config.Passphrase
+		}
+		entities, err := readPrivateKey(additional.Key, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("additional private key %d: %w", i+1, err)
+		}
+		privateKeys = append(privateKeys, entities...)
 	}
-	publicArmored, err := resolveKey(config.UQPayPublicKey)
+
+	initial, err := readUQPayPublicKey(config.UQPayPublicKey, now)
 	if err != nil {
-		return nil, fmt.Errorf("authdecision: resolve UQPAY public key: %w", err)
+		return nil, err
+	}
+	publicKeys := []scheduledKeys{{keys: initial}}
+	for i, scheduled := range config.ScheduledUQPayPublicKeys {
+		if scheduled.ActivateAt.IsZero() {
+			return nil, fmt.Errorf("scheduled UQPAY public key %d: activation time is required", i+1)
+		}
+		checkAt := scheduled.ActivateAt
+		if checkAt.Before(now) {
+			checkAt = now
+		}
+		keys, err := readUQPayPublicKey(scheduled.Key, checkAt)
+		if err != nil {
+			return nil, fmt.Errorf("scheduled UQPAY public key %d: %w", i+1, err)
+		}
+		publicKeys = append(publicKeys, scheduledKeys{activateAt: scheduled.ActivateAt, keys: keys})
 	}
+	sort.SliceStable(publicKeys[1:], func(i, j int) bool {
+		return publicKeys[1+i].activateAt.Before(publicKeys[1+j].activateAt)
+	})
+
+	return &pgpContext{
+		privateKeys:   privateKeys,
+		activePrivate: activePrivate,
+		publicKeys:    publicKeys,
+		now:           time.Now,
+	}, nil
+}
 
-	privateKeys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(privateArmored))
+func readPrivateKey(value, passphrase string) (openpgp.EntityList, error) {
+	armored, err := resolveKey(value)
+	if err != nil {
+		return nil, fmt.Errorf("resolve private key: %w", err)
+	}
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
 	if err != nil {
-		return nil, fmt.Errorf("authdecision: parse private key: %w", err)
+		return nil, fmt.Errorf("parse private key: %w", err)
 	}
-	if err := unlockPrivateKeys(privateKeys, []byte(config.Passphrase)); err != nil {
+	if err := unlockPrivateKeys(entities, []byte(passphrase)); err != nil {
 		return nil, err
 	}
-	if !hasRSADecryptionKey(privateKeys) {
-		return nil, fmt.Errorf("authdecision: private key has no RSA decryption key of at least 2048 bits")
+	if !hasRSADecryptionKey(entities) {
+		return nil, fmt.Errorf("private key has no RSA decryption key of at least 2048 bits")
 	}
+	return entities, nil
+}
 
-	publicKeys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(publicArmored))
+func readUQPayPublicKey(value string, at time.Time) (openpgp.EntityList, error) {
+	armored, err := resolveKey(value)
 	if err != nil {
-		return nil, fmt.Errorf("authdecision: parse UQPAY public key: %w", err)
+		return nil, fmt.Errorf("resolve UQPAY public key: %w", err)
 	}
-	if !hasRSAEncryptionKey(publicKeys, time.Now()) {
-		return nil, fmt.Errorf("authdecision: UQPAY public key has no RSA encryption key of at least 2048 bits")
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil {
+		return nil, fmt.Errorf("parse UQPAY public key: %w", err)
 	}
+	if !hasRSAEncryptionKey(entities, at) {
+		return nil, fmt.Errorf("UQPAY public key has no RSA encryption key of at least 2048 bits")
+	}
+	return entities, nil
+}
+
+// activePublicKeys returns the UQPAY keys responses are encrypted to at t.
+func (p *pgpContext) activePublicKeys(t time.Time) (openpgp.EntityList, int) {
+	active := 0
+	for i := 1; i < len(p.publicKeys); i++ {
+		if p.publicKeys[i].activateAt.After(t) {
+			break
+		}
+		active = i
+	}
+	return p.publicKeys[active].keys, active
+}
 
-	return &pgpContext{privateKeys: privateKeys, publicKeys: publicKeys}, nil
+// keys describes every configured key as of now.
+func (p *pgpContext) keys(now time.Time) []KeyInfo {
+	var infos []KeyInfo
+	active := map[*openpgp.Entity]bool{}
+	for _, entity := range p.activePrivate {
+		active[entity] = true
+	}
+	for _, entity := range p.privateKeys {
+		infos = append(infos, describeKey(entity, KeyRoleCustomerPrivate, now, active[entity]))
+	}
+	_, activeIndex := p.activePublicKeys(now)
+	for i, scheduled := range p.publicKeys {
+		for _, entity := range scheduled.keys {
+			info := describeKey(entity, KeyRoleUQPayPublic, now, i == activeIndex)
+			info.ActivateAt = scheduled.activateAt
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+func describeKey(entity *openpgp.Entity, role KeyRole, now time.Time, active bool) KeyInfo {
+	info := KeyInfo{
+		Role:        role,
+		Fingerprint: strings.ToUpper(hex.EncodeToString(entity.PrimaryKey.Fingerprint)),
+		CreatedAt:   entity.PrimaryKey.CreationTime,
+		Active:      active,
+	}
+	consider := func(publicKey *packet.PublicKey, signature *packet.Signature) {
+		if signature == nil || signature.KeyLifetimeSecs == nil || *signature.KeyLifetimeSecs == 0 {
+			return
+		}
+		expiry := publicKey.CreationTime.Add(time.Duration(*signature.KeyLifetimeSecs) * time.Second)
+		if info.ExpiresAt.IsZero() || expiry.Before(info.ExpiresAt) {
+			info.ExpiresAt = expiry
+		}
+	}
+	selfSignature, _ := entity.PrimarySelfSignature()
+	consider(entity.PrimaryKey, selfSignature)
+	if key, ok := entity.EncryptionKey(now); ok && key.PublicKey != entity.PrimaryKey {
+		consider(key.PublicKey, key.SelfSignature)
+	}
+	return info
 }
 
 func hasRSADecryptionKey(entities openpgp.EntityList) bool {
@@ -143,7 +264,8 @@ func (p *pgpContext) encrypt(plaintext string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("authdecision: create armored response: %w", err)
 	}
-	plaintextWriter, err := openpgp.Encrypt(armoredWriter, p.publicKeys, nil, nil, pgpPacketConfig())
+	publicKeys, _ := p.activePublicKeys(p.now())
+	plaintextWriter, err := openpgp.Encrypt(armoredWriter, publicKeys, nil, nil, pgpPacketConfig())
 	if err != nil {
 		_ = armoredWriter.Close()
 		return "", fmt.Errorf("authdecision: encrypt response: %w", err)
@@ -195,15 +317,15 @@ func unlockPrivateKeys(entities openpgp.EntityList, passphrase []byte) error {
 				continue
 			}
 			if len(passphrase) == 0 {
-				return fmt.Errorf("authdecision: private key is passphrase-protected")
+				return fmt.Errorf("private key is passphrase-protected")
 			}
 			if err := privateKey.Decrypt(passphrase); err != nil {
-				return fmt.Errorf("authdecision: unlock private key: %w", err)
+				return fmt.Errorf("unlock private key: %w", err)
 			}
 		}
 	}
 	if !foundPrivateKey {
-		return fmt.Errorf("authdecision: configured private key contains no private material")
+		return fmt.Errorf("configured private key contains no private material")
 	}
 	return nil
 }
@@ -225,14 +347,20 @@ func serializePrivateKey(entity *openpgp.Entity) (string, error) {
 }
 
 func serializePublicKey(entity *openpgp.Entity) (string, error) {
+	return serializePublicKeys(openpgp.EntityList{entity})
+}
+
+func serializePublicKeys(entities openpgp.EntityList) (string, error) {
 	var output bytes.Buffer
 	armoredWriter, err := armor.Encode(&output, openpgp.PublicKeyType, nil)
 	if err != nil {
 		return "", fmt.Errorf("authdecision: armor public key: %w", err)
 	}
-	if err := entity.Serialize(armoredWriter); err != nil {
-		_ = armoredWriter.Close()
-		return "", fmt.Errorf("authdecision: serialize public key: %w", err)
+	for _, entity := range entities {
+		if err := entity.Serialize(armoredWriter); err != nil {
+			_ = armoredWriter.Close()
+			return "", fmt.Errorf("authdecision: serialize public key: %w", err)
+		}
 	}
 	if err := armoredWriter.Close(); err != nil {
 		return "", fmt.Errorf("authdecision: finalize public key: %w", err)
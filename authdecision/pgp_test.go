@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
@@ -197,6 +198,17 @@ func TestPGPContextRejectsInvalidKeys(t *testing.T) {
 	}); err == nil {
 		t.Fatal("expected invalid public key error")
 	}
+
+	for _, config := range []Config{
+		{PrivateKey: "not-a-key", UQPayPublicKey: keys.PublicKey},
+		{PrivateKey: keys.PrivateKey, UQPayPublicKey: keys.PublicKey, AdditionalPrivateKeys: []PrivateKey{{Key: "not-a-key"}}},
+		{PrivateKey: keys.PrivateKey, UQPayPublicKey: keys.PublicKey, ScheduledUQPayPublicKeys: []ScheduledPublicKey{{Key: "not-a-key", ActivateAt: time.Now()}}},
+	} {
+		err := NewClient().Configure(config)
+		if err == nil || !strings.HasPrefix(err.Error(), "authdecision: ") || strings.Count(err.Error(), "authdecision:") != 1 {
+			t.Errorf("Configure error = %v, want a single authdecision prefix", err)
+		}
+	}
 }
 
 func TestPGPContextRejectsRSAKeysBelow2048Bits(t *testing.T) {
@@ -0,0 +1,98 @@
+package authdecision
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ReplayOptions configures Replay.
+type ReplayOptions struct {
+	// Timeout bounds each replayed decision. Zero uses no deadline.
+	Timeout time.Duration
+	// CompareReason also reports decisions whose reason changed while the
+	// response code stayed the same.
+	CompareReason bool
+}
+
+// ReplayDiff is a recorded decision whose outcome changed on replay.
+type ReplayDiff struct {
+	TransactionID string
+	Before        Result
+	After         Result
+	BeforeError   string
+	AfterError    string
+}
+
+// ReplayReport summarises a Replay run.
+type ReplayReport struct {
+	Total     int
+	Unchanged int
+	Diffs     []ReplayDiff
+	// NewlyDeclined and NewlyApproved count decisions that flipped between
+	// approval and decline.
+	NewlyDeclined int
+	NewlyApproved int
+}
+
+// Replay re-runs recorded transactions against decide and reports every
+// decision whose response code, error or, optionally, reason differs from the
+// recording. Use it to check a rule change against production traffic before
+// deploying it. Records are replayed in order and the current time is not
+// simulated, so time-dependent logic such as velocity limits should be given
+// a fresh store. Records of requests that failed outside the decision, such as
+// decryption failures, are skipped.
+func Replay(ctx context.Context, records []AuditRecord, decide DecisionFunc, options ReplayOptions) (*ReplayReport, error) {
+	if decide == nil {
+		return nil, fmt.Errorf("authdecision: decision function is required")
+	}
+	report := &ReplayReport{}
+	for _, record := range records {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if record.Stage != "" {
+			continue
+		}
+		after, afterErr := replayOne(ctx, decide, record.Transaction, options.Timeout)
+		report.Total++
+
+		before := record.Result()
+		afterError := ""
+		if afterErr != nil {
+			afterError = afterErr.Error()
+		}
+		changed := before.ResponseCode != after.ResponseCode ||
+			(record.Error == "") != (afterError == "") ||
+			(options.CompareReason && before.Reason != after.Reason)
+		if !changed {
+			report.Unchanged++
+			continue
+		}
+		report.Diffs = append(report.Diffs, ReplayDiff{
+			TransactionID: record.TransactionID,
+			Before:        before,
+			After:         after,
+			BeforeError:   record.Error,
+			AfterError:    afterError,
+		})
+		wasApproved := record.Error == "" && isApproval(before.ResponseCode)
+		isApproved := afterError == "" && isApproval(after.ResponseCode)
+		switch {
+		case wasApproved && !isApproved:
+			report.NewlyDeclined++
+		case !wasApproved && isApproved:
+			report.NewlyApproved++
+		}
+	}
+	return report, nil
+}
+
+func replayOne(ctx context.Context, decide DecisionFunc, transaction Transaction, timeout time.Duration) (Result, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return invokeDecision(ctx, decide, transaction)
+}
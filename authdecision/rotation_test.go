@@ -0,0 +1,136 @@
+package authdecision
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+func TestConfigDecryptsWithEveryCustomerKey(t *testing.T) {
+	previous := mustGenerateKeyPair(t, "Customer 2025", "customer@example.com")
+	current := mustGenerateKeyPair(t, "Customer 2026", "customer@example.com")
+	uqpay := mustGenerateKeyPair(t, "UQPAY", "issuing.tech@uqpay.com")
+
+	client := NewClient()
+	if err := client.Configure(Config{
+		PrivateKey:            current.PrivateKey,
+		UQPayPublicKey:        uqpay.PublicKey,
+		AdditionalPrivateKeys: []PrivateKey{{Key: previous.PrivateKey}},
+	}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	pgp := client.snapshot()
+	for _, customer := range []*KeyPair{previous, current} {
+		sender := mustNewPGPContext(t, Config{PrivateKey: uqpay.PrivateKey, UQPayPublicKey: customer.PublicKey})
+		encrypted, err := sender.encrypt(`{"transaction_id":"tx-1"}`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := pgp.decrypt(encrypted); err != nil {
+			t.Fatalf("decrypt request for %s: %v", customer.PublicKey[:20], err)
+		}
+	}
+
+	exported, err := client.ActivePublicKey()
+	if err != nil {
+		t.Fatalf("ActivePublicKey: %v", err)
+	}
+	if got, want := fingerprint(t, exported), fingerprint(t, current.PublicKey); got != want {
+		t.Fatalf("ActivePublicKey fingerprint = %s, want %s", got, want)
+	}
+	if _, err := NewClient().ActivePublicKey(); err == nil {
+		t.Fatal("ActivePublicKey succeeded without configuration")
+	}
+}
+
+func TestConfigRollsOverScheduledUQPayPublicKeys(t *testing.T) {
+	customer := mustGenerateKeyPair(t, "Customer", "customer@example.com")
+	uqpayOld := mustGenerateKeyPair(t, "UQPAY 2025", "issuing.tech@uqpay.com")
+	uqpayNew := mustGenerateKeyPair(t, "UQPAY 2026", "issuing.tech@uqpay.com")
+	activateAt := time.Now().Add(time.Hour)
+
+	pgp := mustNewPGPContext(t, Config{
+		PrivateKey:               customer.PrivateKey,
+		UQPayPublicKey:           uqpayOld.PublicKey,
+		ScheduledUQPayPublicKeys: []ScheduledPublicKey{{Key: uqpayNew.PublicKey, ActivateAt: activateAt}},
+	})
+	oldReceiver := mustNewPGPContext(t, Config{PrivateKey: uqpayOld.PrivateKey, UQPayPublicKey: customer.PublicKey})
+	newReceiver := mustNewPGPContext(t, Config{PrivateKey: uqpayNew.PrivateKey, UQPayPublicKey: customer.PublicKey})
+
+	for _, step := range []struct {
+		at       time.Time
+		receiver *pgpContext
+		other    *pgpContext
+	}{
+		{activateAt.Add(-time.Minute), oldReceiver, newReceiver},
+		{activateAt, newReceiver, oldReceiver},
+	} {
+		at := step.at
+		pgp.now = func() time.Time { return at }
+		encrypted, err := pgp.encrypt(`{"response_code":"00"}`)
+		if err != nil {
+			t.Fatalf("encrypt at %s: %v", at, err)
+		}
+		if _, err := step.receiver.decrypt(encrypted); err != nil {
+			t.Fatalf("active UQPAY key could not decrypt at %s: %v", at, err)
+		}
+		if _, err := step.other.decrypt(encrypted); err == nil {
+			t.Fatalf("inactive UQPAY key decrypted the response at %s", at)
+		}
+	}
+
+	if _, err := newPGPContext(Config{
+		PrivateKey:               customer.PrivateKey,
+		UQPayPublicKey:           uqpayOld.PublicKey,
+		ScheduledUQPayPublicKeys: []ScheduledPublicKey{{Key: uqpayNew.PublicKey}},
+	}); err == nil {
+		t.Fatal("newPGPContext accepted a scheduled key without an activation time")
+	}
+}
+
+func TestConfigureWarnsAboutExpiringKeys(t *testing.T) {
+	config := pgpPacketConfig()
+	config.KeyLifetimeSecs = uint32((10 * 24 * time.Hour).Seconds())
+	entity, err := openpgp.NewEntity("Customer", "", "customer@example.com", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiring, err := serializePrivateKey(entity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uqpay := mustGenerateKeyPair(t, "UQPAY", "issuing.tech@uqpay.com")
+
+	var warnings []KeyInfo
+	client := NewClient()
+	if err := client.Configure(Config{
+		PrivateKey:     expiring,
+		UQPayPublicKey: uqpay.PublicKey,
+		OnKeyExpiring:  func(key KeyInfo) { warnings = append(warnings, key) },
+	}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Role != KeyRoleCustomerPrivate || !warnings[0].Active {
+		t.Fatalf("warnings = %+v", warnings)
+	}
+	if until := time.Until(warnings[0].ExpiresAt); until < 9*24*time.Hour || until > 10*24*time.Hour {
+		t.Fatalf("expires in %s, want about 10 days", until)
+	}
+	if keys := client.ExpiringKeys(24 * time.Hour); len(keys) != 0 {
+		t.Fatalf("ExpiringKeys(1 day) = %+v", keys)
+	}
+	if keys := client.Keys(); len(keys) != 2 || keys[1].Role != KeyRoleUQPayPublic || !keys[1].ExpiresAt.IsZero() {
+		t.Fatalf("Keys = %+v", keys)
+	}
+}
+
+func fingerprint(t *testing.T, armored string) string {
+	t.Helper()
+	entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(armored))
+	if err != nil || len(entities) != 1 {
+		t.Fatalf("read key: %d entities, %v", len(entities), err)
+	}
+	return describeKey(entities[0], KeyRoleCustomerPrivate, time.Now(), false).Fingerprint
+}
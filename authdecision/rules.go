@@ -0,0 +1,366 @@
+package authdecision
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Action is what a Rule does when it matches a transaction.
+type Action string
+
+const (
+	// ActionAllow approves the transaction and stops evaluation.
+	ActionAllow Action = "allow"
+	// ActionDeny declines the transaction and stops evaluation.
+	ActionDeny Action = "deny"
+	// ActionNext records the rule's reason and continues with the next rule.
+	ActionNext Action = "next"
+)
+
+const (
+	defaultApproveCode = string(ResponseApproved)
+	defaultDeclineCode = string(ResponseDoNotHonor)
+)
+
+// RuleSet is an ordered list of rules. The first matching allow or deny rule
+// decides the transaction; when none matches, the default action applies.
+// RuleSets load from JSON with ParseRuleSet or LoadRuleSet, or from YAML by
+// passing a YAML decoder to UnmarshalRuleSet:
+//
+//	rules, err := authdecision.UnmarshalRuleSet(data, yaml.Unmarshal)
+type RuleSet struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+	// DefaultAction is ActionAllow or ActionDeny. Empty allows.
+	DefaultAction       Action `json:"default_action,omitempty" yaml:"default_action,omitempty"`
+	DefaultResponseCode string `json:"default_response_code,omitempty" yaml:"default_response_code,omitempty"`
+	DefaultReason       string `json:"default_reason,omitempty" yaml:"default_reason,omitempty"`
+}
+
+// Rule applies Action to transactions that satisfy Match. ResponseCode
+// defaults to "00" for allow and "05" for deny; NewRuleEngine rejects codes
+// that are unsupported or contradict the action.
+type Rule struct {
+	Name         string `json:"name" yaml:"name"`
+	Match        Match  `json:"match" yaml:"match"`
+	Action       Action `json:"action" yaml:"action"`
+	ResponseCode string `json:"response_code,omitempty" yaml:"response_code,omitempty"`
+	Reason       string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Match selects transactions. Every non-empty condition must hold, and a list
+// condition holds when the transaction field equals any entry. An empty Match
+// matches every transaction.
+type Match struct {
+	// MerchantCategoryCodes accepts single codes ("7995") and inclusive
+	// ranges ("5960-5969").
+	MerchantCategoryCodes []string `json:"merchant_category_codes,omitempty" yaml:"merchant_category_codes,omitempty"`
+	MerchantCountries     []string `json:"merchant_countries,omitempty" yaml:"merchant_countries,omitempty"`
+	MerchantIDs           []string `json:"merchant_ids,omitempty" yaml:"merchant_ids,omitempty"`
+	PosEntryModes         []string `json:"pos_entry_modes,omitempty" yaml:"pos_entry_modes,omitempty"`
+	ECIs                  []string `json:"ecis,omitempty" yaml:"ecis,omitempty"`
+	WalletTypes           []string `json:"wallet_types,omitempty" yaml:"wallet_types,omitempty"`
+	CardIDs               []string `json:"card_ids,omitempty" yaml:"card_ids,omitempty"`
+	// TransactionCurrencies matches TransactionCurrencyCode.
+	TransactionCurrencies []string `json:"transaction_currencies,omitempty" yaml:"transaction_currencies,omitempty"`
+	// AmountExceeds maps a transaction currency code to a decimal cap. It
+	// holds when the transaction currency has a cap and TransactionAmount is
+	// greater than it or cannot be parsed.
+	AmountExceeds map[string]string `json:"amount_exceeds,omitempty" yaml:"amount_exceeds,omitempty"`
+	// Not holds when the nested match does not, e.g. to deny every country
+	// except an allowed list.
+	Not *Match `json:"not,omitempty" yaml:"not,omitempty"`
+}
+
+// Decision is the outcome of evaluating a RuleSet.
+type Decision struct {
+	Result Result
+	// Rule is the name of the rule that decided the transaction, or empty if
+	// the default action applied.
+	Rule string
+	// Reasons lists the reasons of every matching rule in evaluation order,
+	// including ActionNext rules.
+	Reasons []string
+}
+
+// RuleEngine evaluates a validated RuleSet. It is safe for concurrent use.
+type RuleEngine struct {
+	rules         []compiledRule
+	defaultResult Result
+}
+
+type compiledRule struct {
+	Rule
+	match compiledMatch
+}
+
+type compiledMatch struct {
+	mccs       []mccRange
+	countries  []string
+	merchants  []string
+	posModes   []string
+	ecis       []string
+	wallets    []string
+	cardIDs    []string
+	currencies []string
+	caps       map[string]*big.Rat
+	not        *compiledMatch
+}
+
+type mccRange struct{ low, high string }
+
+// ParseRuleSet decodes a JSON rule set and rejects unknown fields.
+func ParseRuleSet(data []byte) (*RuleSet, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var rules RuleSet
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("authdecision: decode rules: %w", err)
+	}
+	return &rules, nil
+}
+
+// UnmarshalRuleSet decodes a rule set with unmarshal, such as yaml.Unmarshal
+// from gopkg.in/yaml.v3. RuleSet fields carry both json and yaml tags.
+func UnmarshalRuleSet(data []byte, unmarshal func([]byte, interface{}) error) (*RuleSet, error) {
+	var rules RuleSet
+	if err := unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("authdecision: decode rules: %w", err)
+	}
+	return &rules, nil
+}
+
+// LoadRuleSet reads a JSON rule set from path.
+func LoadRuleSet(path string) (*RuleSet, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		return nil, fmt.Errorf("authdecision: read %s: YAML rules must be decoded with UnmarshalRuleSet", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("authdecision: read rules: %w", err)
+	}
+	return ParseRuleSet(data)
+}
+
+// NewRuleEngine validates rules and prepares them for evaluation.
+func NewRuleEngine(rules RuleSet) (*RuleEngine, error) {
+	engine := &RuleEngine{}
+	switch rules.DefaultAction {
+	case "", ActionAllow:
+		engine.defaultResult = Result{ResponseCode: defaultApproveCode}
+	case ActionDeny:
+		engine.defaultResult = Result{ResponseCode: defaultDeclineCode}
+	default:
+		return nil, fmt.Errorf("authdecision: default action must be %q or %q, got %q", ActionAllow, ActionDeny, rules.DefaultAction)
+	}
+	if rules.DefaultResponseCode != "" {
+		if err := checkResponseCode(rules.DefaultAction == ActionDeny, rules.DefaultResponseCode); err != nil {
+			return nil, fmt.Errorf("authdecision: default response code: %w", err)
+		}
+		engine.defaultResult.ResponseCode = rules.DefaultResponseCode
+	}
+	engine.defaultResult.Reason = rules.DefaultReason
+
+	for i, rule := range rules.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		switch rule.Action {
+		case ActionAllow, ActionDeny, ActionNext:
+		default:
+			return nil, fmt.Errorf("authdecision: rule %s: unknown action %q", name, rule.Action)
+		}
+		if rule.Action == ActionNext && rule.ResponseCode != "" {
+			return nil, fmt.Errorf("authdecision: rule %s: next rules cannot set a response code", name)
+		}
+		if rule.ResponseCode != "" {
+			if err := checkResponseCode(rule.Action == ActionDeny, rule.ResponseCode); err != nil {
+				return nil, fmt.Errorf("authdecision: rule %s: %w", name, err)
+			}
+		}
+		match, err := compileMatch(rule.Match)
+		if err != nil {
+			return nil, fmt.Errorf("authdecision: rule %s: %w", name, err)
+		}
+		rule.Name = name
+		engine.rules = append(engine.rules, compiledRule{Rule: rule, match: *match})
+	}
+	return engine, nil
+}
+
+// checkResponseCode requires a supported code that declines for deny actions
+// and approves for allow actions.
+func checkResponseCode(deny bool, code string) error {
+	responseCode := ResponseCode(code)
+	switch {
+	case !responseCode.Valid():
+		return fmt.Errorf("unsupported response code %q", code)
+	case deny && responseCode.Approved():
+		return fmt.Errorf("deny response code %q approves the transaction", code)
+	case !deny && !responseCode.Approved():
+		return fmt.Errorf("allow response code %q declines the transaction", code)
+	}
+	return nil
+}
+
+// Evaluate applies the rules to transaction.
+func (e *RuleEngine) Evaluate(transaction Transaction) Decision {
+	var decision Decision
+	for _, rule := range e.rules {
+		if !rule.match.matches(transaction) {
+			continue
+		}
+		if rule.Reason != "" {
+			decision.Reasons = append(decision.Reasons, rule.Reason)
+		}
+		if rule.Action == ActionNext {
+			continue
+		}
+		code := rule.ResponseCode
+		if code == "" {
+			code = defaultApproveCode
+			if rule.Action == ActionDeny {
+				code = defaultDeclineCode
+			}
+		}
+		decision.Rule = rule.Name
+		decision.Result = Result{ResponseCode: code, Reason: rule.Reason}
+		return decision
+	}
+	decision.Result = e.defaultResult
+	if e.defaultResult.Reason != "" {
+		decision.Reasons = append(decision.Reasons, e.defaultResult.Reason)
+	}
+	return decision
+}
+
+// DecisionFunc returns a DecisionFunc that evaluates the rules. The Result
+// reason is the deciding rule's reason, or its name when it has none.
+func (e *RuleEngine) DecisionFunc() DecisionFunc {
+	return func(ctx context.Context, transaction Transaction) (Result, error) {
+		decision := e.Evaluate(transaction)
+		if decision.Result.Reason == "" && decision.Rule != "" {
+			decision.Result.Reason = "rule " + decision.Rule
+		}
+		return decision.Result, nil
+	}
+}
+
+func compileMatch(match Match) (*compiledMatch, error) {
+	compiled := &compiledMatch{
+		countries:  match.MerchantCountries,
+		merchants:  match.MerchantIDs,
+		posModes:   match.PosEntryModes,
+		ecis:       match.ECIs,
+		wallets:    match.WalletTypes,
+		cardIDs:    match.CardIDs,
+		currencies: match.TransactionCurrencies,
+	}
+	for _, code := range match.MerchantCategoryCodes {
+		low, high, isRange := strings.Cut(strings.TrimSpace(code), "-")
+		if !isRange {
+			high = low
+		}
+		low, high = strings.TrimSpace(low), strings.TrimSpace(high)
+		if !isMCC(low) || !isMCC(high) || low > high {
+			return nil, fmt.Errorf("invalid merchant category code %q", code)
+		}
+		compiled.mccs = append(compiled.mccs, mccRange{low: low, high: high})
+	}
+	if len(match.AmountExceeds) > 0 {
+		compiled.caps = map[string]*big.Rat{}
+		for currency, amount := range match.AmountExceeds {
+			limit, ok := new(big.Rat).SetString(amount)
+			if !ok || !decimalPattern.MatchString(amount) {
+				return nil, fmt.Errorf("invalid amount %q for %s", amount, currency)
+			}
+			compiled.caps[strings.ToUpper(currency)] = limit
+		}
+	}
+	if match.Not != nil {
+		not, err := compileMatch(*match.Not)
+		if err != nil {
+			return nil, err
+		}
+		compiled.not = not
+	}
+	return compiled, nil
+}
+
+func (m *compiledMatch) matches(t Transaction) bool {
+	if len(m.mccs) > 0 && !matchesMCC(m.mccs, t.MerchantCategoryCode) {
+		return false
+	}
+	if !matchesAny(m.countries, t.MerchantCountry) ||
+		!matchesAny(m.merchants, t.MerchantID) ||
+		!matchesAny(m.posModes, t.PosEntryMode) ||
+		!matchesAny(m.ecis, t.ECI) ||
+		!matchesAny(m.wallets, t.WalletType) ||
+		!matchesAny(m.cardIDs, t.CardID) ||
+		!matchesAny(m.currencies, t.TransactionCurrencyCode) {
+		return false
+	}
+	if m.caps != nil && !exceedsCap(m.caps, t) {
+		return false
+	}
+	if m.not != nil && m.not.matches(t) {
+		return false
+	}
+	return true
+}
+
+func matchesAny(values []string, field string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if strings.EqualFold(value, field) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesMCC(ranges []mccRange, code string) bool {
+	if !isMCC(code) {
+		return false
+	}
+	for _, r := range ranges {
+		if code >= r.low && code <= r.high {
+			return true
+		}
+	}
+	return false
+}
+
+func exceedsCap(caps map[string]*big.Rat, t Transaction) bool {
+	limit, ok := caps[strings.ToUpper(t.TransactionCurrencyCode)]
+	if !ok {
+		return false
+	}
+	amount, ok := new(big.Rat).SetString(strings.TrimSpace(t.TransactionAmount))
+	if !ok {
+		// An amount that cannot be checked against the cap is treated as
+		// exceeding it, so caps fail closed.
+		return true
+	}
+	return amount.Cmp(limit) > 0
+}
+
+func isMCC(code string) bool {
+	if len(code) != 4 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
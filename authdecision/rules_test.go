@@ -0,0 +1,127 @@
+package authdecision
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testRuleSet = `{
+  "rules": [
+    {"name": "trusted-card", "match": {"card_ids": ["card-vip"]}, "action": "allow", "reason": "allow-listed card"},
+    {"name": "gambling", "match": {"merchant_category_codes": ["7995", "7800-7802"]}, "action": "deny", "response_code": "57", "reason": "gambling MCC blocked"},
+    {"name": "sanctioned-countries", "match": {"merchant_countries": ["KP", "IR"]}, "action": "deny", "response_code": "62", "reason": "merchant country blocked"},
+    {"name": "wallet-tag", "match": {"wallet_types": ["103"]}, "action": "next", "reason": "apple pay token"},
+    {"name": "ecommerce-cap", "match": {"pos_entry_modes": ["81"], "amount_exceeds": {"USD": "500", "SGD": "700.00"}}, "action": "deny", "response_code": "61", "reason": "e-commerce cap exceeded"},
+    {"name": "non-3ds", "match": {"pos_entry_modes": ["81"], "not": {"ecis": ["05", "02"]}}, "action": "deny", "reason": "unauthenticated e-commerce"}
+  ],
+  "default_action": "allow",
+  "default_reason": "no rule matched"
+}`
+
+func TestRuleEngineEvaluatesOrderedRules(t *testing.T) {
+	rules, err := ParseRuleSet([]byte(testRuleSet))
+	if err != nil {
+		t.Fatalf("ParseRuleSet: %v", err)
+	}
+	engine, err := NewRuleEngine(*rules)
+	if err != nil {
+		t.Fatalf("NewRuleEngine: %v", err)
+	}
+
+	base := Transaction{
+		TransactionID:           "tx-1",
+		CardID:                  "card-001",
+		MerchantCategoryCode:    "5411",
+		MerchantCountry:         "SG",
+		PosEntryMode:            "81",
+		ECI:                     "05",
+		TransactionAmount:       "120.00",
+		TransactionCurrencyCode: "USD",
+	}
+	tests := []struct {
+		name     string
+		mutate   func(*Transaction)
+		wantCode string
+		wantRule string
+		reasons  int
+	}{
+		{"default", func(*Transaction) {}, "00", "", 1},
+		{"mcc", func(tx *Transaction) { tx.MerchantCategoryCode = "7995" }, "57", "gambling", 1},
+		{"mcc range", func(tx *Transaction) { tx.MerchantCategoryCode = "7801" }, "57", "gambling", 1},
+		{"country", func(tx *Transaction) { tx.MerchantCountry = "kp" }, "62", "sanctioned-countries", 1},
+		{"cap", func(tx *Transaction) { tx.TransactionAmount = "500.01" }, "61", "ecommerce-cap", 1},
+		{"cap boundary", func(tx *Transaction) { tx.TransactionAmount = "500" }, "00", "", 1},
+		{"cap unparsable amount", func(tx *Transaction) { tx.TransactionAmount = "12O.00" }, "61", "ecommerce-cap", 1},
+		{"cap other currency", func(tx *Transaction) { tx.TransactionCurrencyCode = "EUR"; tx.TransactionAmount = "9000" }, "00", "", 1},
+		{"next then deny", func(tx *Transaction) { tx.WalletType = "103"; tx.ECI = "07" }, "05", "non-3ds", 2},
+		{"first match wins", func(tx *Transaction) { tx.CardID = "card-vip"; tx.MerchantCategoryCode = "7995" }, "00", "trusted-card", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tx := base
+			test.mutate(&tx)
+			decision := engine.Evaluate(tx)
+			if decision.Result.ResponseCode != test.wantCode || decision.Rule != test.wantRule {
+				t.Fatalf("decision = %+v, want code %s from rule %q", decision, test.wantCode, test.wantRule)
+			}
+			if len(decision.Reasons) != test.reasons {
+				t.Fatalf("reasons = %q, want %d", decision.Reasons, test.reasons)
+			}
+		})
+	}
+
+	result, err := engine.DecisionFunc()(context.Background(), Transaction{MerchantCategoryCode: "7995"})
+	if err != nil || result.ResponseCode != "57" || result.Reason != "gambling MCC blocked" {
+		t.Fatalf("DecisionFunc = %+v, %v", result, err)
+	}
+}
+
+func TestRuleSetLoadingAndValidation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(testRuleSet), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRuleSet(path); err != nil {
+		t.Fatalf("LoadRuleSet: %v", err)
+	}
+	if _, err := LoadRuleSet(strings.TrimSuffix(path, ".json") + ".yaml"); err == nil {
+		t.Fatal("LoadRuleSet accepted a YAML path")
+	}
+	if _, err := UnmarshalRuleSet([]byte(`{"rules":[],"default_action":"deny"}`), json.Unmarshal); err != nil {
+		t.Fatalf("UnmarshalRuleSet: %v", err)
+	}
+	if _, err := ParseRuleSet([]byte(`{"rules":[],"unknown":true}`)); err == nil {
+		t.Fatal("ParseRuleSet accepted an unknown field")
+	}
+
+	invalid := []RuleSet{
+		{DefaultAction: "maybe"},
+		{Rules: []Rule{{Name: "bad-action", Action: "block"}}},
+		{Rules: []Rule{{Name: "bad-mcc", Action: ActionDeny, Match: Match{MerchantCategoryCodes: []string{"79"}}}}},
+		{Rules: []Rule{{Name: "bad-range", Action: ActionDeny, Match: Match{MerchantCategoryCodes: []string{"7999-7000"}}}}},
+		{Rules: []Rule{{Name: "bad-cap", Action: ActionDeny, Match: Match{AmountExceeds: map[string]string{"USD": "1e"}}}}},
+		{Rules: []Rule{{Name: "next-code", Action: ActionNext, ResponseCode: "05"}}},
+		{Rules: []Rule{{Name: "deny-approves", Action: ActionDeny, ResponseCode: "00"}}},
+		{Rules: []Rule{{Name: "allow-declines", Action: ActionAllow, ResponseCode: "05"}}},
+		{Rules: []Rule{{Name: "unknown-code", Action: ActionDeny, ResponseCode: "X9"}}},
+		{DefaultAction: ActionDeny, DefaultResponseCode: "00"},
+		{DefaultResponseCode: "51"},
+	}
+	for _, rules := range invalid {
+		if _, err := NewRuleEngine(rules); err == nil {
+			t.Errorf("NewRuleEngine accepted %+v", rules)
+		}
+	}
+
+	engine, err := NewRuleEngine(RuleSet{DefaultAction: ActionDeny, DefaultResponseCode: "59"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decision := engine.Evaluate(Transaction{}); decision.Result.ResponseCode != "59" {
+		t.Fatalf("default decision = %+v", decision)
+	}
+}
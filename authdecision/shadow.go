@@ -0,0 +1,265 @@
+package authdecision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/internal/ctxutil"
+)
+
+const (
+	defaultShadowTimeout     = 5 * time.Second
+	defaultShadowMaxInFlight = 256
+)
+
+// Shadow is a candidate DecisionFunc evaluated on live traffic without
+// affecting the decision sent to UQPAY.
+type Shadow struct {
+	Name   string
+	Decide DecisionFunc
+}
+
+// ShadowOptions configures WithShadows.
+type ShadowOptions struct {
+	// Timeout bounds each shadow decision. Shadows run detached from the
+	// request, so this is independent of the UQPAY deadline. Zero uses 5
+	// seconds.
+	Timeout time.Duration
+	// MaxInFlight caps the shadow decisions running at once across all
+	// requests. When the cap is reached new shadow evaluations are skipped
+	// and reported with Skipped set. Zero uses 256.
+	MaxInFlight int
+	// OnResult is called once per shadow and transaction after both the
+	// primary and the shadow have finished. It runs on a background goroutine.
+	OnResult func(ShadowResult)
+}
+
+// ShadowResult compares one shadow decision with the primary decision.
+type ShadowResult struct {
+	Shadow        string
+	TransactionID string
+	Transaction   Transaction
+	Primary       Result
+	PrimaryErr    error
+	Result        Result
+	Err           error
+	// Skipped is set when the shadow was not evaluated because MaxInFlight
+	// shadow decisions were already running.
+	Skipped bool
+	Elapsed time.Duration
+}
+
+// Agrees reports whether the primary and the shadow both decided and reached
+// the same approve or decline outcome.
+func (r ShadowResult) Agrees() bool {
+	return r.compared() && r.Primary.Approved() == r.Result.Approved()
+}
+
+func (r ShadowResult) compared() bool {
+	return !r.Skipped && r.PrimaryErr == nil && r.Err == nil
+}
+
+// WithShadows returns a DecisionFunc that answers with primary and evaluates
+// every shadow concurrently on the same transaction. Shadows never delay or
+// change the returned result: it is returned as soon as primary finishes, and
+// shadow results are delivered to options.OnResult afterwards.
+func WithShadows(primary DecisionFunc, shadows []Shadow, options ShadowOptions) DecisionFunc {
+	if len(shadows) == 0 {
+		return primary
+	}
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = defaultShadowTimeout
+	}
+	maxInFlight := options.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = defaultShadowMaxInFlight
+	}
+	slots := make(chan struct{}, maxInFlight)
+
+	return func(ctx context.Context, transaction Transaction) (Result, error) {
+		pending := make([]chan ShadowResult, len(shadows))
+		for i, shadow := range shadows {
+			pending[i] = make(chan ShadowResult, 1)
+			select {
+			case slots <- struct{}{}:
+			default:
+				pending[i] <- ShadowResult{Shadow: shadow.Name, Skipped: true}
+				continue
+			}
+			go func(shadow Shadow, out chan<- ShadowResult) {
+				defer func() { <-slots }()
+				shadowCtx, cancel := context.WithTimeout(ctxutil.WithoutCancel(ctx), timeout)
+				defer cancel()
+				started := time.Now()
+				result, err := invokeDecision(shadowCtx, shadow.Decide, transaction)
+				out <- ShadowResult{Shadow: shadow.Name, Result: result, Err: err, Elapsed: time.Since(started)}
+			}(shadow, pending[i])
+		}
+
+		result, err := primary(ctx, transaction)
+
+		if options.OnResult != nil {
+			go func() {
+				for _, out := range pending {
+					shadowResult := <-out
+					shadowResult.TransactionID = transaction.TransactionID
+					shadowResult.Transaction = transaction
+					shadowResult.Primary = result
+					shadowResult.PrimaryErr = err
+					options.OnResult(shadowResult)
+				}
+			}()
+		}
+		return result, err
+	}
+}
+
+// ShadowSummary aggregates the results of one shadow.
+type ShadowSummary struct {
+	Total  int
+	Agreed int
+	// NewlyDeclined counts transactions the primary approved and the shadow
+	// declined; NewlyApproved counts the reverse.
+	NewlyDeclined int
+	NewlyApproved int
+	// Errors counts evaluations where the primary or the shadow failed.
+	Errors  int
+	Skipped int
+	// SlowestElapsed is the longest shadow decision time seen.
+	SlowestElapsed time.Duration
+}
+
+// ShadowStats aggregates shadow results per shadow name. Its Record method
+// can be used directly as ShadowOptions.OnResult. It is safe for concurrent
+// use.
+type ShadowStats struct {
+	mu        sync.Mutex
+	summaries map[string]*ShadowSummary
+}
+
+// NewShadowStats creates an empty aggregator.
+func NewShadowStats() *ShadowStats {
+	return &ShadowStats{summaries: map[string]*ShadowSummary{}}
+}
+
+// Record adds result to the summary for its shadow.
+func (s *ShadowStats) Record(result ShadowResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summary, ok := s.summaries[result.Shadow]
+	if !ok {
+		summary = &ShadowSummary{}
+		s.summaries[result.Shadow] = summary
+	}
+	summary.Total++
+	switch {
+	case result.Skipped:
+		summary.Skipped++
+	case !result.compared():
+		summary.Errors++
+	case result.Agrees():
+		summary.Agreed++
+	case result.Primary.Approved():
+		summary.NewlyDeclined++
+	default:
+		summary.NewlyApproved++
+	}
+	if result.Elapsed > summary.SlowestElapsed {
+		summary.SlowestElapsed = result.Elapsed
+	}
+}
+
+// Summaries returns a copy of the summaries keyed by shadow name.
+func (s *ShadowStats) Summaries() map[string]ShadowSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	summaries := make(map[string]ShadowSummary, len(s.summaries))
+	for name, summary := range s.summaries {
+		summaries[name] = *summary
+	}
+	return summaries
+}
+
+// Variant is one candidate in a TrafficSplit.
+type Variant struct {
+	Name string
+	// Percent of cards routed to this variant. The percentages of a split
+	// must add up to 100.
+	Percent int
+	Decide  DecisionFunc
+}
+
+// TrafficSplit routes each card to one of several decision functions. A card
+// always lands in the same variant for a given salt, so a cardholder sees
+// consistent decisions for the length of an experiment.
+type TrafficSplit struct {
+	salt     string
+	variants []Variant
+}
+
+// NewTrafficSplit validates variants and creates a split. Change salt to
+// reshuffle cards between variants for a new experiment.
+func NewTrafficSplit(salt string, variants ...Variant) (*TrafficSplit, error) {
+	if len(variants) == 0 {
+		return nil, errors.New("authdecision: traffic split needs at least one variant")
+	}
+	total := 0
+	seen := map[string]bool{}
+	for i, variant := range variants {
+		if variant.Name == "" {
+			return nil, fmt.Errorf("authdecision: variant %d: name is required", i)
+		}
+		if seen[variant.Name] {
+			return nil, fmt.Errorf("authdecision: variant %q is defined twice", variant.Name)
+		}
+		seen[variant.Name] = true
+		if variant.Decide == nil {
+			return nil, fmt.Errorf("authdecision: variant %q: decision function is required", variant.Name)
+		}
+		if variant.Percent < 0 {
+			return nil, fmt.Errorf("authdecision: variant %q: percent cannot be negative", variant.Name)
+		}
+		total += variant.Percent
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("authdecision: variant percentages add up to %d, want 100", total)
+	}
+	return &TrafficSplit{salt: salt, variants: append([]Variant(nil), variants...)}, nil
+}
+
+// Assign returns the variant for cardID.
+func (s *TrafficSplit) Assign(cardID string) Variant {
+	bucket := splitBucket(s.salt, cardID)
+	for _, variant := range s.variants {
+		if bucket < variant.Percent {
+			return variant
+		}
+		bucket -= variant.Percent
+	}
+	return s.variants[len(s.variants)-1]
+}
+
+// DecisionFunc returns a DecisionFunc that decides each transaction with the
+// variant assigned to its CardID and sets Result.Variant to its name.
+func (s *TrafficSplit) DecisionFunc() DecisionFunc {
+	return func(ctx context.Context, transaction Transaction) (Result, error) {
+		variant := s.Assign(transaction.CardID)
+		result, err := variant.Decide(ctx, transaction)
+		result.Variant = variant.Name
+		return result, err
+	}
+}
+
+// splitBucket hashes cardID into [0, 100).
+func splitBucket(salt, cardID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(salt))
+	h.Write([]byte{0})
+	h.Write([]byte(cardID))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,159 @@
+package authdecision
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithShadowsNeverDelaysOrChangesTheDecision(t *testing.T) {
+	release := make(chan struct{})
+	results := make(chan ShadowResult, 3)
+	decide := WithShadows(approve, []Shadow{
+		{Name: "strict", Decide: func(context.Context, Transaction) (Result, error) {
+			return Decline(ResponseSuspectedFraud, "model score"), nil
+		}},
+		{Name: "slow", Decide: func(ctx context.Context, _ Transaction) (Result, error) {
+			<-release
+			return Approve(), nil
+		}},
+		{Name: "broken", Decide: func(context.Context, Transaction) (Result, error) {
+			panic("model not loaded")
+		}},
+	}, ShadowOptions{Timeout: time.Second, OnResult: func(r ShadowResult) { results <- r }})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := time.Now()
+	result, err := decide(ctx, Transaction{TransactionID: "tx-1", CardID: "card-1"})
+	cancel()
+	if err != nil || !result.Approved() {
+		t.Fatalf("decision = %+v, %v", result, err)
+	}
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Fatalf("shadows delayed the decision by %s", elapsed)
+	}
+
+	stats := NewShadowStats()
+	strict := <-results
+	stats.Record(strict)
+	if strict.Shadow != "strict" || strict.TransactionID != "tx-1" || strict.Agrees() || strict.Result.Code() != ResponseSuspectedFraud {
+		t.Fatalf("strict result = %+v", strict)
+	}
+	close(release)
+	slow, broken := <-results, <-results
+	stats.Record(slow)
+	stats.Record(broken)
+	if slow.Shadow != "slow" || !slow.Agrees() {
+		t.Fatalf("slow shadow was cancelled with the request: %+v", slow)
+	}
+	if broken.Err == nil || broken.Agrees() {
+		t.Fatalf("broken shadow = %+v", broken)
+	}
+	summaries := stats.Summaries()
+	if summaries["strict"].NewlyDeclined != 1 || summaries["slow"].Agreed != 1 || summaries["broken"].Errors != 1 {
+		t.Fatalf("summaries = %+v", summaries)
+	}
+}
+
+func TestWithShadowsSkipsWhenSaturated(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	results := make(chan ShadowResult, 2)
+	decide := WithShadows(approve, []Shadow{{Name: "busy", Decide: func(context.Context, Transaction) (Result, error) {
+		<-release
+		return Approve(), nil
+	}}}, ShadowOptions{MaxInFlight: 1, OnResult: func(r ShadowResult) { results <- r }})
+
+	for i := 0; i < 2; i++ {
+		if _, err := decide(context.Background(), Transaction{TransactionID: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if skipped := <-results; !skipped.Skipped || skipped.TransactionID != "1" {
+		t.Fatalf("second evaluation = %+v, want skipped", skipped)
+	}
+}
+
+func TestTrafficSplitIsDeterministicByCard(t *testing.T) {
+	decideWith := func(code ResponseCode) DecisionFunc {
+		return func(context.Context, Transaction) (Result, error) { return Decline(code, ""), nil }
+	}
+	split, err := NewTrafficSplit("fraud-model-2026",
+		Variant{Name: "control", Percent: 80, Decide: approve},
+		Variant{Name: "candidate", Percent: 20, Decide: decideWith(ResponseSuspectedFraud)},
+	)
+	if err != nil {
+		t.Fatalf("NewTrafficSplit: %v", err)
+	}
+	decide := split.DecisionFunc()
+	counts := map[string]int{}
+	for i := 0; i < 2000; i++ {
+		cardID := fmt.Sprintf("card-%d", i)
+		first, _ := decide(context.Background(), Transaction{CardID: cardID})
+		again, _ := decide(context.Background(), Transaction{CardID: cardID})
+		if first.Variant != again.Variant || first.Variant != split.Assign(cardID).Name {
+			t.Fatalf("card %s moved between variants", cardID)
+		}
+		if (first.Variant == "candidate") == first.Approved() {
+			t.Fatalf("card %s decided by the wrong variant: %+v", cardID, first)
+		}
+		counts[first.Variant]++
+	}
+	if counts["candidate"] < 300 || counts["candidate"] > 500 {
+		t.Fatalf("candidate share = %d of 2000, want about 400", counts["candidate"])
+	}
+
+	for _, variants := range [][]Variant{
+		nil,
+		{{Name: "a", Percent: 50, Decide: approve}},
+		{{Name: "a", Percent: 50, Decide: approve}, {Name: "a", Percent: 50, Decide: approve}},
+		{{Name: "a", Percent: 100}},
+	} {
+		if _, err := NewTrafficSplit("", variants...); err == nil {
+			t.Errorf("NewTrafficSplit(%+v) succeeded", variants)
+		}
+	}
+}
+
+func TestHandlerRunsShadowsAndAuditsVariant(t *testing.T) {
+	client, uqpayContext := configuredTestClient(t)
+	encrypted, err := uqpayContext.encrypt(authorizationRequest)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	split, err := NewTrafficSplit("", Variant{Name: "only", Percent: 100, Decide: approve})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan ShadowResult, 1)
+	var log strings.Builder
+	handler, err := client.Handler(HandlerOptions{
+		Decide:         split.DecisionFunc(),
+		Shadows:        []Shadow{{Name: "candidate", Decide: func(context.Context, Transaction) (Result, error) { return Approve(), nil }}},
+		OnShadowResult: func(r ShadowResult) { results <- r },
+		Auditor:        NewAuditor(NewJSONLAuditSink(&log)),
+	})
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/auth-decision", strings.NewReader(encrypted)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+	if r := <-results; !r.Agrees() || r.Primary.Variant != "only" {
+		t.Fatalf("shadow result = %+v", r)
+	}
+	records, err := ReadAuditLog(strings.NewReader(log.String()))
+	if err != nil || len(records) != 1 || records[0].Variant != "only" {
+		t.Fatalf("audit records = %+v, %v", records, err)
+	}
+
+	if _, err := client.Handler(HandlerOptions{Decide: approve, Shadows: []Shadow{{Name: "nil"}}}); err == nil {
+		t.Fatal("Handler accepted a shadow without a decision function")
+	}
+}
@@ -0,0 +1,124 @@
+package authdecision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StandInOptions configures WithStandIn.
+type StandInOptions struct {
+	// After bounds the primary decision. Zero leaves it bounded only by the
+	// caller's context.
+	After time.Duration
+	// OnStandIn is called whenever the stand-in result is used.
+	OnStandIn func(StandInEvent)
+}
+
+// StandInEvent reports a decision answered by the stand-in function.
+type StandInEvent struct {
+	TransactionID string
+	// Cause is the primary decision's error, panic or deadline.
+	Cause error
+	// Timeout is true when the primary decision ran past its deadline.
+	Timeout bool
+	Result  Result
+	Elapsed time.Duration
+}
+
+// WithStandIn returns a DecisionFunc that answers with standIn when primary
+// fails, panics or exceeds options.After. standIn is evaluated concurrently
+// with primary so its result is available as soon as the deadline passes.
+// Stand-in results have StandIn set and their Reason prefixed with
+// "stand-in".
+func WithStandIn(primary, standIn DecisionFunc, options StandInOptions) DecisionFunc {
+	return func(ctx context.Context, transaction Transaction) (Result, error) {
+		started := time.Now()
+		fallback := make(chan decisionOutcome, 1)
+		go func() {
+			var outcome decisionOutcome
+			outcome.result, outcome.err = invokeDecision(ctx, standIn, transaction)
+			fallback <- outcome
+		}()
+
+		primaryCtx, cancel := context.WithCancel(ctx)
+		if options.After > 0 {
+			cancel()
+			primaryCtx, cancel = context.WithTimeout(ctx, options.After)
+		}
+		defer cancel()
+		result, cause := invokeDecision(primaryCtx, primary, transaction)
+		if cause == nil {
+			return result, nil
+		}
+
+		var outcome decisionOutcome
+		select {
+		case outcome = <-fallback:
+		case <-ctx.Done():
+			return Result{}, ctx.Err()
+		}
+		if outcome.err != nil {
+			return Result{}, fmt.Errorf("authdecision: stand-in failed after %v: %w", cause, outcome.err)
+		}
+
+		result = outcome.result
+		result.StandIn = true
+		if result.Reason == "" {
+			result.Reason = "stand-in"
+		} else {
+			result.Reason = "stand-in: " + result.Reason
+		}
+		if options.OnStandIn != nil {
+			options.OnStandIn(StandInEvent{
+				TransactionID: transaction.TransactionID,
+				Cause:         cause,
+				Timeout:       errors.Is(cause, context.DeadlineExceeded) && ctx.Err() == nil,
+				Result:        result,
+				Elapsed:       time.Since(started),
+			})
+		}
+		return result, nil
+	}
+}
+
+// ApproveUnder returns a stand-in DecisionFunc that approves transactions
+// whose TransactionAmount is at most the cap for their transaction currency
+// and, when merchants is not empty, whose MerchantID is listed. Everything
+// else is declined with "05".
+func ApproveUnder(caps map[string]string, merchants ...string) (DecisionFunc, error) {
+	if len(caps) == 0 {
+		return nil, fmt.Errorf("authdecision: at least one stand-in amount cap is required")
+	}
+	rules := RuleSet{DefaultAction: ActionAllow, DefaultReason: "under stand-in limit"}
+	rules.Rules = append(rules.Rules, Rule{
+		Name:   "stand-in-limit",
+		Match:  Match{AmountExceeds: caps},
+		Action: ActionDeny,
+		Reason: "over stand-in limit",
+	})
+	currencies := make([]string, 0, len(caps))
+	for currency := range caps {
+		currencies = append(currencies, currency)
+	}
+	rules.Rules = append(rules.Rules, Rule{
+		Name:   "stand-in-currency",
+		Match:  Match{Not: &Match{TransactionCurrencies: currencies}},
+		Action: ActionDeny,
+		Reason: "no stand-in limit for currency",
+	})
+	if len(merchants) > 0 {
+		rules.Rules = append(rules.Rules, Rule{
+			Name:   "stand-in-merchant",
+			Match:  Match{Not: &Match{MerchantIDs: merchants}},
+			Action: ActionDeny,
+			Reason: "unknown merchant",
+		})
+	}
+	engine, err := NewRuleEngine(rules)
+	if err != nil {
+		return nil, err
+	}
+	return engine.DecisionFunc(), nil
+}
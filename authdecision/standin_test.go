@@ -0,0 +1,155 @@
+package authdecision
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithStandInAnswersFailuresAndTimeouts(t *testing.T) {
+	standIn, err := ApproveUnder(map[string]string{"USD": "50"}, "merchant-known")
+	if err != nil {
+		t.Fatalf("ApproveUnder: %v", err)
+	}
+	small := Transaction{TransactionID: "tx-small", MerchantID: "merchant-known", TransactionAmount: "20", TransactionCurrencyCode: "USD"}
+
+	tests := []struct {
+		name        string
+		primary     DecisionFunc
+		tx          Transaction
+		wantCode    string
+		wantStandIn bool
+		wantTimeout bool
+	}{
+		{"primary ok", approve, small, "00", false, false},
+		{"error", func(context.Context, Transaction) (Result, error) { return Result{}, errors.New("risk down") }, small, "00", true, false},
+		{"panic", func(context.Context, Transaction) (Result, error) { panic("boom") }, small, "00", true, false},
+		{"timeout", func(ctx context.Context, _ Transaction) (Result, error) {
+			time.Sleep(time.Second)
+			return Result{ResponseCode: "51"}, nil
+		}, small, "00", true, true},
+		{"over cap", func(context.Context, Transaction) (Result, error) { return Result{}, errors.New("risk down") },
+			Transaction{MerchantID: "merchant-known", TransactionAmount: "50.01", TransactionCurrencyCode: "USD"}, "05", true, false},
+		{"unknown merchant", func(context.Context, Transaction) (Result, error) { return Result{}, errors.New("risk down") },
+			Transaction{MerchantID: "merchant-new", TransactionAmount: "1", TransactionCurrencyCode: "USD"}, "05", true, false},
+		{"no cap for currency", func(context.Context, Transaction) (Result, error) { return Result{}, errors.New("risk down") },
+			Transaction{MerchantID: "merchant-known", TransactionAmount: "1", TransactionCurrencyCode: "EUR"}, "05", true, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var events []StandInEvent
+			decide := WithStandIn(test.primary, standIn, StandInOptions{
+				After:     20 * time.Millisecond,
+				OnStandIn: func(event StandInEvent) { events = append(events, event) },
+			})
+			started := time.Now()
+			result, err := decide(context.Background(), test.tx)
+			if err != nil {
+				t.Fatalf("decide: %v", err)
+			}
+			if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+				t.Fatalf("decision took %s", elapsed)
+			}
+			if result.ResponseCode != test.wantCode || result.StandIn != test.wantStandIn {
+				t.Fatalf("result = %+v", result)
+			}
+			if !test.wantStandIn {
+				if len(events) != 0 {
+					t.Fatalf("OnStandIn called for a primary result: %+v", events)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Cause == nil || events[0].Timeout != test.wantTimeout {
+				t.Fatalf("events = %+v", events)
+			}
+			if !strings.HasPrefix(result.Reason, "stand-in") {
+				t.Fatalf("reason = %q", result.Reason)
+			}
+		})
+	}
+
+	failingStandIn := WithStandIn(
+		func(context.Context, Transaction) (Result, error) { return Result{}, errors.New("risk down") },
+		func(context.Context, Transaction) (Result, error) { return Result{}, errors.New("cache down") },
+		StandInOptions{},
+	)
+	if _, err := failingStandIn(context.Background(), small); err == nil {
+		t.Fatal("stand-in failure was not returned")
+	}
+	// A panicking stand-in is reported as a failure, even with a context
+	// that cannot be cancelled.
+	panickingStandIn := WithStandIn(
+		func(context.Context, Transaction) (Result, error) { return Result{}, errors.New("risk down") },
+		func(context.Context, Transaction) (Result, error) { panic("cache down") },
+		StandInOptions{},
+	)
+	if _, err := panickingStandIn(context.Background(), small); err == nil || !strings.Contains(err.Error(), "panic") {
+		t.Fatalf("stand-in panic = %v", err)
+	}
+	if _, err := ApproveUnder(nil); err == nil {
+		t.Fatal("ApproveUnder accepted no caps")
+	}
+}
+
+func TestHandlerUsesStandInAndAuditsIt(t *testing.T) {
+	client, uqpayContext := configuredTestClient(t)
+	encrypted, err := uqpayContext.encrypt(authorizationRequest)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	release := make(chan struct{})
+	defer close(release)
+
+	var log bytes.Buffer
+	standIns := 0
+	handler, err := client.Handler(HandlerOptions{
+		DecisionTimeout: 3 * time.Second,
+		StandInAfter:    20 * time.Millisecond,
+		Decide: func(context.Context, Transaction) (Result, error) {
+			<-release
+			return Result{ResponseCode: "00"}, nil
+		},
+		StandIn: func(context.Context, Transaction) (Result, error) {
+			return Result{ResponseCode: "05", Reason: "primary unavailable"}, nil
+		},
+		OnStandIn: func(StandInEvent) { standIns++ },
+		Auditor:   NewAuditor(NewJSONLAuditSink(&log)),
+	})
+	if err != nil {
+		t.Fatalf("Handler: %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/auth-decision", strings.NewReader(encrypted)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d", recorder.Code)
+	}
+	plaintext, err := uqpayContext.decrypt(recorder.Body.String())
+	if err != nil {
+		t.Fatalf("decrypt response: %v", err)
+	}
+	if !strings.Contains(plaintext, `"response_code":"05"`) || strings.Contains(plaintext, "stand") {
+		t.Fatalf("response = %s", plaintext)
+	}
+	if standIns != 1 {
+		t.Fatalf("OnStandIn calls = %d", standIns)
+	}
+	records, err := ReadAuditLog(&log)
+	if err != nil || len(records) != 1 || !records[0].StandIn || records[0].Reason != "stand-in: primary unavailable" {
+		t.Fatalf("audit records = %+v, %v", records, err)
+	}
+
+	if _, err := client.Handler(HandlerOptions{
+		Decide:          approve,
+		StandIn:         approve,
+		DecisionTimeout: time.Second,
+		StandInAfter:    time.Second,
+	}); err == nil {
+		t.Fatal("Handler accepted a stand-in delay equal to the decision timeout")
+	}
+}
@@ -23,9 +23,67 @@ type KeyPair struct {
 // PrivateKey and UQPayPublicKey accept either ASCII-armored keys or paths ending
 // in .asc, .pgp, or .gpg.
 type Config struct {
+	// PrivateKey is the active customer key. Its public key is the one to
+	// upload to UQPAY; see Client.ActivePublicKey.
 	PrivateKey     string
 	UQPayPublicKey string
 	Passphrase     string
+
+	// AdditionalPrivateKeys are also used to decrypt requests, so requests
+	// encrypted to a previous or upcoming customer key keep working while
+	// UQPAY switches keys. Each request is decrypted with the key it targets.
+	AdditionalPrivateKeys []PrivateKey
+	// ScheduledUQPayPublicKeys replace UQPayPublicKey at their activation
+	// time. Responses are encrypted to the key with the latest activation time
+	// that is not in the future; UQPayPublicKey is active until the first one.
+	ScheduledUQPayPublicKeys []ScheduledPublicKey
+	// ExpiryWarning is how long before a key expires OnKeyExpiring is called.
+	// Zero uses 30 days.
+	ExpiryWarning time.Duration
+	// OnKeyExpiring is called by Configure for every configured key that
+	// expires within ExpiryWarning. Use Client.ExpiringKeys for periodic
+	// checks in long-running processes.
+	OnKeyExpiring func(KeyInfo)
+}
+
+// PrivateKey is a customer private key with its own passphrase. An empty
+// Passphrase falls back to Config.Passphrase.
+type PrivateKey struct {
+	Key        string
+	Passphrase string
+}
+
+// ScheduledPublicKey is a UQPAY public key that becomes active at ActivateAt.
+type ScheduledPublicKey struct {
+	Key        string
+	ActivateAt time.Time
+}
+
+// KeyRole identifies what a configured key is used for.
+type KeyRole string
+
+const (
+	KeyRoleCustomerPrivate KeyRole = "customer_private"
+	KeyRoleUQPayPublic     KeyRole = "uqpay_public"
+)
+
+// KeyInfo describes a configured key.
+type KeyInfo struct {
+	Role        KeyRole
+	Fingerprint string
+	CreatedAt   time.Time
+	// ExpiresAt is zero for keys without an expiry.
+	ExpiresAt time.Time
+	// ActivateAt is set for scheduled UQPAY public keys.
+	ActivateAt time.Time
+	// Active reports whether the key is the active customer key or the UQPAY
+	// key responses are currently encrypted to.
+	Active bool
+}
+
+// ExpiresWithin reports whether the key expires before now plus d.
+func (k KeyInfo) ExpiresWithin(now time.Time, d time.Duration) bool {
+	return !k.ExpiresAt.IsZero() && k.ExpiresAt.Before(now.Add(d))
 }
 
 // Transaction is the decrypted authorization decision request from UQPAY.
@@ -168,6 +226,14 @@ func (v *decimalValue) UnmarshalJSON(data []byte) error {
 type Result struct {
 	ResponseCode       string
 	PartnerReferenceID string
+	// Reason explains the decision for auditing. It is not sent to UQPAY.
+	Reason string
+	// StandIn is set when the result came from HandlerOptions.StandIn rather
+	// than Decide. It is not sent to UQPAY.
+	StandIn bool
+	// Variant names the TrafficSplit variant that decided. It is not sent to
+	// UQPAY.
+	Variant string
 }
 
 // DecisionFunc receives a decrypted transaction and returns an authorization
@@ -187,4 +253,28 @@ type HandlerOptions struct {
 	// OnError is called before the handler aborts the HTTP response. Aborting
 	// allows UQPAY's configured timeout action to decide the transaction.
 	OnError func(error)
+	// StandIn decides when Decide returns an error, panics, or has not
+	// returned after StandInAfter. It runs alongside Decide so its result is
+	// ready at the deadline, and should be cheap and free of remote calls,
+	// e.g. approving small amounts at known merchants. If StandIn also fails
+	// the handler aborts as usual.
+	StandIn DecisionFunc
+	// StandInAfter bounds Decide when StandIn is set. It must be shorter than
+	// DecisionTimeout to leave time to encrypt the response. Zero uses 80% of
+	// DecisionTimeout, or no bound when DecisionTimeout is zero.
+	StandInAfter time.Duration
+	// OnStandIn is called whenever a stand-in result is used, for metrics.
+	OnStandIn func(StandInEvent)
+	// Shadows are evaluated alongside Decide on every request without
+	// affecting or delaying the response; see WithShadows. Their results are
+	// compared with the final decision, including stand-in results.
+	Shadows []Shadow
+	// ShadowTimeout bounds each shadow decision. Zero uses 5 seconds.
+	ShadowTimeout time.Duration
+	// OnShadowResult receives each shadow result, e.g. ShadowStats.Record.
+	OnShadowResult func(ShadowResult)
+	// Auditor, if set, records every final decision, including stand-in
+	// results, and requests that could not be decrypted, decoded or
+	// answered.
+	Auditor *Auditor
 }
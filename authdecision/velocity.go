@@ -0,0 +1,424 @@
+package authdecision
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/internal/ctxutil"
+)
+
+// KeyField is a transaction field a velocity limit counts by.
+type KeyField string
+
+const (
+	KeyCardID          KeyField = "card_id"
+	KeyMerchantID      KeyField = "merchant_id"
+	KeyMerchantCountry KeyField = "merchant_country"
+)
+
+// WindowMode selects how a velocity window moves.
+type WindowMode string
+
+const (
+	// WindowSliding counts entries in the Window before each transaction.
+	WindowSliding WindowMode = "sliding"
+	// WindowFixed counts entries since the start of the current Window,
+	// aligned to UTC (a 24h window resets at midnight UTC).
+	WindowFixed WindowMode = "fixed"
+)
+
+const (
+	defaultCountLimitCode   = string(ResponseExceedsFrequencyLimit)
+	defaultAmountLimitCode  = string(ResponseExceedsAmountLimit)
+	defaultDeclineLimitCode = string(ResponseDoNotHonor)
+)
+
+// VelocityLimit declines transactions once the history for a key exceeds a
+// count or amount. Amounts are the transaction's BillingAmount, summed as
+// exact decimals per billing currency.
+type VelocityLimit struct {
+	Name  string
+	KeyBy []KeyField
+	// Window is the length of the window, e.g. time.Hour.
+	Window time.Duration
+	Mode   WindowMode
+	// MaxCount is the largest number of transactions allowed in the window,
+	// including the current one. Zero disables the count check.
+	MaxCount int
+	// MaxAmount is the largest billing amount allowed in the window,
+	// including the current transaction. Empty disables the amount check.
+	// A transaction without a billing amount is declined by the limit, as
+	// its spend cannot be checked.
+	MaxAmount string
+	// Currency limits MaxAmount to transactions billed in this currency.
+	Currency string
+	// Declines counts declined decisions instead of approved spend. The
+	// transaction is declined when MaxCount declines are already recorded,
+	// e.g. to stop repeated attempts at one merchant.
+	Declines     bool
+	ResponseCode string
+	Reason       string
+}
+
+// VelocityEntry is one transaction recorded under a velocity key.
+type VelocityEntry struct {
+	// ID is the UQPAY transaction ID.
+	ID     string
+	At     time.Time
+	Amount string
+}
+
+// VelocityStore holds velocity history. Implementations backed by a shared
+// store such as Redis let several decision endpoints enforce the same limits.
+// Entries may be dropped once they are older than ttl.
+type VelocityStore interface {
+	// Add records entry under key, replacing any entry with the same ID so a
+	// retried authorization is only counted once.
+	Add(ctx context.Context, key string, entry VelocityEntry, ttl time.Duration) error
+	// Entries returns the entries recorded at or after since.
+	Entries(ctx context.Context, key string, since time.Time) ([]VelocityEntry, error)
+	// Remove deletes the entry with id. Removing a missing entry is not an
+	// error.
+	Remove(ctx context.Context, key, id string) error
+}
+
+// Velocity enforces velocity limits around a DecisionFunc. Approved spend is
+// reserved before the wrapped decision runs and released again when the
+// decision declines, fails, or finishes after its context is done.
+type Velocity struct {
+	store  VelocityStore
+	limits []compiledLimit
+	now    func() time.Time
+}
+
+type compiledLimit struct {
+	VelocityLimit
+	maxAmount *big.Rat
+}
+
+// NewVelocity validates limits and creates a velocity checker using store.
+func NewVelocity(store VelocityStore, limits ...VelocityLimit) (*Velocity, error) {
+	if store == nil {
+		return nil, fmt.Errorf("authdecision: velocity store is required")
+	}
+	v := &Velocity{store: store, now: time.Now}
+	for _, limit := range limits {
+		if limit.Name == "" {
+			return nil, fmt.Errorf("authdecision: velocity limit name is required")
+		}
+		if len(limit.KeyBy) == 0 {
+			return nil, fmt.Errorf("authdecision: velocity limit %s: key_by is required", limit.Name)
+		}
+		for _, field := range limit.KeyBy {
+			switch field {
+			case KeyCardID, KeyMerchantID, KeyMerchantCountry:
+			default:
+				return nil, fmt.Errorf("authdecision: velocity limit %s: unknown key field %q", limit.Name, field)
+			}
+		}
+		if limit.Window <= 0 {
+			return nil, fmt.Errorf("authdecision: velocity limit %s: window must be positive", limit.Name)
+		}
+		switch limit.Mode {
+		case "":
+			limit.Mode = WindowSliding
+		case WindowSliding, WindowFixed:
+		default:
+			return nil, fmt.Errorf("authdecision: velocity limit %s: unknown window mode %q", limit.Name, limit.Mode)
+		}
+		if limit.ResponseCode != "" {
+			if err := checkResponseCode(true, limit.ResponseCode); err != nil {
+				return nil, fmt.Errorf("authdecision: velocity limit %s: %w", limit.Name, err)
+			}
+		}
+		if limit.MaxCount < 0 {
+			return nil, fmt.Errorf("authdecision: velocity limit %s: max_count cannot be negative", limit.Name)
+		}
+		compiled := compiledLimit{VelocityLimit: limit}
+		if limit.MaxAmount != "" {
+			if limit.Declines {
+				return nil, fmt.Errorf("authdecision: velocity limit %s: decline limits only support max_count", limit.Name)
+			}
+			maxAmount, ok := new(big.Rat).SetString(limit.MaxAmount)
+			if !ok || !decimalPattern.MatchString(limit.MaxAmount) {
+				return nil, fmt.Errorf("authdecision: velocity limit %s: invalid max_amount %q", limit.Name, limit.MaxAmount)
+			}
+			compiled.maxAmount = maxAmount
+		}
+		if limit.MaxCount == 0 && compiled.maxAmount == nil {
+			return nil, fmt.Errorf("authdecision: velocity limit %s: max_count or max_amount is required", limit.Name)
+		}
+		v.limits = append(v.limits, compiled)
+	}
+	return v, nil
+}
+
+// Wrap returns a DecisionFunc that applies the limits before calling decide.
+func (v *Velocity) Wrap(decide DecisionFunc) DecisionFunc {
+	return func(ctx context.Context, transaction Transaction) (Result, error) {
+		amount, err := billingAmount(transaction)
+		if err != nil {
+			return Result{}, err
+		}
+		at := v.now()
+
+		for _, limit := range v.limits {
+			if !limit.Declines || !limit.applies(transaction) {
+				continue
+			}
+			declines, _, err := v.totals(ctx, limit, transaction, at)
+			if err != nil {
+				return Result{}, err
+			}
+			if declines >= limit.MaxCount {
+				return limit.result(defaultDeclineLimitCode), nil
+			}
+		}
+
+		var reserved []compiledLimit
+		for _, limit := range v.limits {
+			if limit.Declines || !limit.applies(transaction) {
+				continue
+			}
+			key := limit.key(transaction)
+			entry := VelocityEntry{ID: transaction.TransactionID, At: at, Amount: transaction.BillingAmount}
+			if err := v.store.Add(ctx, key, entry, limit.Window); err != nil {
+				v.release(ctx, reserved, transaction)
+				return Result{}, fmt.Errorf("authdecision: velocity limit %s: %w", limit.Name, err)
+			}
+			reserved = append(reserved, limit)
+			count, total, err := v.totals(ctx, limit, transaction, at)
+			if err != nil {
+				v.release(ctx, reserved, transaction)
+				return Result{}, err
+			}
+			if limit.MaxCount > 0 && count > limit.MaxCount {
+				v.release(ctx, reserved, transaction)
+				return limit.result(defaultCountLimitCode), nil
+			}
+			if limit.maxAmount != nil && (amount == nil || total.Cmp(limit.maxAmount) > 0) {
+				v.release(ctx, reserved, transaction)
+				return limit.result(defaultAmountLimitCode), nil
+			}
+		}
+
+		result, err := decide(ctx, transaction)
+		if err == nil && ctx.Err() == nil && isApproval(result.ResponseCode) {
+			return result, nil
+		}
+		v.release(ctx, reserved, transaction)
+		if err == nil && ctx.Err() == nil {
+			v.recordDecline(ctx, transaction, at)
+		}
+		return result, err
+	}
+}
+
+// Rollback removes transaction from every spend limit, e.g. when the
+// encrypted response could not be delivered to UQPAY.
+func (v *Velocity) Rollback(ctx context.Context, transaction Transaction) error {
+	for _, limit := range v.limits {
+		if limit.Declines || !limit.applies(transaction) {
+			continue
+		}
+		if err := v.store.Remove(ctx, limit.key(transaction), transaction.TransactionID); err != nil {
+			return fmt.Errorf("authdecision: velocity limit %s: %w", limit.Name, err)
+		}
+	}
+	return nil
+}
+
+// release rolls back reservations. It uses a context that outlives an
+// expired decision deadline so the rollback still reaches the store.
+func (v *Velocity) release(ctx context.Context, limits []compiledLimit, transaction Transaction) {
+	ctx = ctxutil.WithoutCancel(ctx)
+	for _, limit := range limits {
+		_ = v.store.Remove(ctx, limit.key(transaction), transaction.TransactionID)
+	}
+}
+
+func (v *Velocity) recordDecline(ctx context.Context, transaction Transaction, at time.Time) {
+	for _, limit := range v.limits {
+		if !limit.Declines || !limit.applies(transaction) {
+			continue
+		}
+		entry := VelocityEntry{ID: transaction.TransactionID, At: at}
+		_ = v.store.Add(ctx, limit.key(transaction), entry, limit.Window)
+	}
+}
+
+func (v *Velocity) totals(ctx context.Context, limit compiledLimit, transaction Transaction, at time.Time) (int, *big.Rat, error) {
+	since := at.Add(-limit.Window)
+	if limit.Mode == WindowFixed {
+		since = at.UTC().Truncate(limit.Window)
+	}
+	entries, err := v.store.Entries(ctx, limit.key(transaction), since)
+	if err != nil {
+		return 0, nil, fmt.Errorf("authdecision: velocity limit %s: %w", limit.Name, err)
+	}
+	// Entries are counted once per transaction ID in case the store keeps
+	// duplicates from retried authorizations.
+	seen := map[string]bool{}
+	count := 0
+	total := new(big.Rat)
+	for _, entry := range entries {
+		if entry.ID != "" {
+			if seen[entry.ID] {
+				continue
+			}
+			seen[entry.ID] = true
+		}
+		count++
+		if entry.Amount == "" {
+			continue
+		}
+		amount, ok := new(big.Rat).SetString(entry.Amount)
+		if !ok {
+			return 0, nil, fmt.Errorf("authdecision: velocity limit %s: invalid stored amount %q", limit.Name, entry.Amount)
+		}
+		total.Add(total, amount)
+	}
+	return count, total, nil
+}
+
+func (l compiledLimit) applies(transaction Transaction) bool {
+	return l.Currency == "" || strings.EqualFold(l.Currency, transaction.BillingCurrencyCode)
+}
+
+// key identifies the history a limit counts for transaction. Amount limits
+// also key on billing currency so sums never mix currencies.
+func (l compiledLimit) key(transaction Transaction) string {
+	parts := []string{"velocity", l.Name}
+	for _, field := range l.KeyBy {
+		switch field {
+		case KeyCardID:
+			parts = append(parts, transaction.CardID)
+		case KeyMerchantID:
+			parts = append(parts, transaction.MerchantID)
+		case KeyMerchantCountry:
+			parts = append(parts, strings.ToUpper(transaction.MerchantCountry))
+		}
+	}
+	if l.maxAmount != nil {
+		parts = append(parts, strings.ToUpper(transaction.BillingCurrencyCode))
+	}
+	return strings.Join(parts, ":")
+}
+
+func (l compiledLimit) result(defaultCode string) Result {
+	code := l.ResponseCode
+	if code == "" {
+		code = defaultCode
+	}
+	reason := l.Reason
+	if reason == "" {
+		reason = "velocity limit " + l.Name + " exceeded"
+	}
+	return Result{ResponseCode: code, Reason: reason}
+}
+
+func billingAmount(transaction Transaction) (*big.Rat, error) {
+	if transaction.BillingAmount == "" {
+		return nil, nil
+	}
+	amount, ok := new(big.Rat).SetString(transaction.BillingAmount)
+	if !ok {
+		return nil, fmt.Errorf("authdecision: invalid billing_amount %q", transaction.BillingAmount)
+	}
+	return amount, nil
+}
+
+// isApproval reports whether code approves the transaction.
+func isApproval(code string) bool {
+	return ResponseCode(code).Approved()
+}
+
+// MemoryVelocityStore is an in-process VelocityStore. Limits are enforced per
+// process, so use a shared store when several instances receive decisions.
+type MemoryVelocityStore struct {
+	mu      sync.Mutex
+	entries map[string][]memoryVelocityEntry
+	now     func() time.Time
+}
+
+type memoryVelocityEntry struct {
+	VelocityEntry
+	expireAt time.Time
+}
+
+// NewMemoryVelocityStore creates an empty in-memory store.
+func NewMemoryVelocityStore() *MemoryVelocityStore {
+	return &MemoryVelocityStore{entries: map[string][]memoryVelocityEntry{}, now: time.Now}
+}
+
+// Add records entry under key, replacing any entry with the same ID.
+func (s *MemoryVelocityStore) Add(ctx context.Context, key string, entry VelocityEntry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := s.prune(key)
+	if entry.ID != "" {
+		kept := entries[:0]
+		for _, existing := range entries {
+			if existing.ID != entry.ID {
+				kept = append(kept, existing)
+			}
+		}
+		entries = kept
+	}
+	entries = append(entries, memoryVelocityEntry{VelocityEntry: entry, expireAt: entry.At.Add(ttl)})
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].At.Before(entries[j].At) })
+	s.entries[key] = entries
+	return nil
+}
+
+// Entries returns the entries under key recorded at or after since.
+func (s *MemoryVelocityStore) Entries(ctx context.Context, key string, since time.Time) ([]VelocityEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []VelocityEntry
+	for _, entry := range s.prune(key) {
+		if !entry.At.Before(since) {
+			entries = append(entries, entry.VelocityEntry)
+		}
+	}
+	return entries, nil
+}
+
+// Remove deletes the entries under key with id.
+func (s *MemoryVelocityStore) Remove(ctx context.Context, key, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.entries[key][:0]
+	for _, entry := range s.entries[key] {
+		if entry.ID != id {
+			kept = append(kept, entry)
+		}
+	}
+	s.setEntries(key, kept)
+	return nil
+}
+
+func (s *MemoryVelocityStore) prune(key string) []memoryVelocityEntry {
+	now := s.now()
+	kept := s.entries[key][:0]
+	for _, entry := range s.entries[key] {
+		if entry.expireAt.After(now) {
+			kept = append(kept, entry)
+		}
+	}
+	s.setEntries(key, kept)
+	return kept
+}
+
+func (s *MemoryVelocityStore) setEntries(key string, entries []memoryVelocityEntry) {
+	if len(entries) == 0 {
+		delete(s.entries, key)
+		return
+	}
+	s.entries[key] = entries
+}
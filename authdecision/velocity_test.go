@@ -0,0 +1,223 @@
+package authdecision
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestVelocity(t *testing.T, now *time.Time, limits ...VelocityLimit) (*Velocity, *MemoryVelocityStore) {
+	t.Helper()
+	store := NewMemoryVelocityStore()
+	store.now = func() time.Time { return *now }
+	velocity, err := NewVelocity(store, limits...)
+	if err != nil {
+		t.Fatalf("NewVelocity: %v", err)
+	}
+	velocity.now = func() time.Time { return *now }
+	return velocity, store
+}
+
+func velocityTransaction(id, amount string) Transaction {
+	return Transaction{
+		TransactionID:       id,
+		CardID:              "card-001",
+		MerchantID:          "merchant-001",
+		MerchantCountry:     "SG",
+		BillingAmount:       amount,
+		BillingCurrencyCode: "USD",
+	}
+}
+
+func approve(context.Context, Transaction) (Result, error) {
+	return Result{ResponseCode: "00"}, nil
+}
+
+func TestVelocityCountsSlidingWindowAndRollsBackDeclines(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	velocity, _ := newTestVelocity(t, &now, VelocityLimit{Name: "hourly", KeyBy: []KeyField{KeyCardID}, Window: time.Hour, MaxCount: 2})
+	ctx := context.Background()
+
+	declineNext := false
+	decide := velocity.Wrap(func(ctx context.Context, tx Transaction) (Result, error) {
+		if declineNext {
+			return Result{ResponseCode: "51"}, nil
+		}
+		return Result{ResponseCode: "00"}, nil
+	})
+
+	if result, _ := decide(ctx, velocityTransaction("tx-1", "10")); result.ResponseCode != "00" {
+		t.Fatalf("tx-1 = %+v", result)
+	}
+	declineNext = true
+	if result, _ := decide(ctx, velocityTransaction("tx-2", "10")); result.ResponseCode != "51" {
+		t.Fatalf("tx-2 = %+v", result)
+	}
+	declineNext = false
+	if result, _ := decide(ctx, velocityTransaction("tx-3", "10")); result.ResponseCode != "00" {
+		t.Fatalf("tx-3 = %+v, declined tx-2 should not count", result)
+	}
+	result, err := decide(ctx, velocityTransaction("tx-4", "10"))
+	if err != nil || result.ResponseCode != defaultCountLimitCode || result.Reason == "" {
+		t.Fatalf("tx-4 = %+v, %v; want count limit", result, err)
+	}
+
+	now = now.Add(59 * time.Minute)
+	if result, _ := decide(ctx, velocityTransaction("tx-5", "10")); result.ResponseCode != defaultCountLimitCode {
+		t.Fatalf("tx-5 = %+v, window should still be full", result)
+	}
+	now = now.Add(2 * time.Minute)
+	if result, _ := decide(ctx, velocityTransaction("tx-6", "10")); result.ResponseCode != "00" {
+		t.Fatalf("tx-6 = %+v, sliding window should have moved", result)
+	}
+}
+
+func TestVelocityFixedDailySpendUsesExactDecimals(t *testing.T) {
+	now := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	velocity, _ := newTestVelocity(t, &now, VelocityLimit{
+		Name:      "daily-spend",
+		KeyBy:     []KeyField{KeyCardID},
+		Window:    24 * time.Hour,
+		Mode:      WindowFixed,
+		MaxAmount: "0.3",
+		Currency:  "USD",
+	})
+	decide := velocity.Wrap(approve)
+	ctx := context.Background()
+
+	for i, amount := range []string{"0.1", "0.2"} {
+		if result, _ := decide(ctx, velocityTransaction(fmt.Sprint("tx-", i), amount)); result.ResponseCode != "00" {
+			t.Fatalf("amount %s = %+v; 0.1 + 0.2 must not exceed 0.3", amount, result)
+		}
+	}
+	if result, _ := decide(ctx, velocityTransaction("tx-over", "0.01")); result.ResponseCode != defaultAmountLimitCode {
+		t.Fatalf("over limit = %+v", result)
+	}
+	other := velocityTransaction("tx-sgd", "100")
+	other.BillingCurrencyCode = "SGD"
+	if result, _ := decide(ctx, other); result.ResponseCode != "00" {
+		t.Fatalf("other currency = %+v", result)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if result, _ := decide(ctx, velocityTransaction("tx-next-day", "0.3")); result.ResponseCode != "00" {
+		t.Fatalf("next day = %+v, fixed window should reset at midnight UTC", result)
+	}
+
+	// Without a billing amount the spend cannot be checked, so the limit
+	// declines it instead of letting it through.
+	if result, _ := decide(ctx, velocityTransaction("tx-no-amount", "")); result.ResponseCode != defaultAmountLimitCode {
+		t.Fatalf("missing amount = %+v", result)
+	}
+}
+
+func TestVelocityRollsBackOnTimeoutAndErrors(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	limit := VelocityLimit{Name: "single", KeyBy: []KeyField{KeyCardID, KeyMerchantID}, Window: time.Hour, MaxCount: 1}
+	velocity, store := newTestVelocity(t, &now, limit)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	timedOut := velocity.Wrap(func(ctx context.Context, tx Transaction) (Result, error) {
+		cancel()
+		return Result{ResponseCode: "00"}, nil
+	})
+	if _, err := timedOut(ctx, velocityTransaction("tx-timeout", "1")); err != nil {
+		t.Fatalf("timed out decision: %v", err)
+	}
+	failing := velocity.Wrap(func(context.Context, Transaction) (Result, error) {
+		return Result{}, fmt.Errorf("downstream unavailable")
+	})
+	if _, err := failing(context.Background(), velocityTransaction("tx-error", "1")); err == nil {
+		t.Fatal("wrapped error was not returned")
+	}
+	key := velocity.limits[0].key(velocityTransaction("", ""))
+	if entries, _ := store.Entries(context.Background(), key, now.Add(-time.Hour)); len(entries) != 0 {
+		t.Fatalf("entries after rollback = %+v", entries)
+	}
+
+	tx := velocityTransaction("tx-ok", "1")
+	if result, _ := velocity.Wrap(approve)(context.Background(), tx); result.ResponseCode != "00" {
+		t.Fatalf("tx-ok = %+v", result)
+	}
+	if err := velocity.Rollback(context.Background(), tx); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if result, _ := velocity.Wrap(approve)(context.Background(), velocityTransaction("tx-after", "1")); result.ResponseCode != "00" {
+		t.Fatalf("tx-after = %+v, Rollback did not release the count", result)
+	}
+}
+
+func TestVelocityLimitsRepeatedDeclinesAtMerchant(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	velocity, _ := newTestVelocity(t, &now, VelocityLimit{
+		Name:     "merchant-declines",
+		KeyBy:    []KeyField{KeyCardID, KeyMerchantID},
+		Window:   10 * time.Minute,
+		MaxCount: 2,
+		Declines: true,
+	})
+	calls := 0
+	decide := velocity.Wrap(func(context.Context, Transaction) (Result, error) {
+		calls++
+		return Result{ResponseCode: "51"}, nil
+	})
+	for i := 0; i < 3; i++ {
+		decide(context.Background(), velocityTransaction(fmt.Sprint("tx-", i), "1"))
+	}
+	if calls != 2 {
+		t.Fatalf("decision calls = %d, want the third attempt blocked by the decline limit", calls)
+	}
+	other := velocityTransaction("tx-other", "1")
+	other.MerchantID = "merchant-002"
+	decide(context.Background(), other)
+	if calls != 3 {
+		t.Fatal("decline limit applied to a different merchant")
+	}
+}
+
+func TestNewVelocityValidatesLimits(t *testing.T) {
+	store := NewMemoryVelocityStore()
+	invalid := []VelocityLimit{
+		{KeyBy: []KeyField{KeyCardID}, Window: time.Hour, MaxCount: 1},
+		{Name: "no-key", Window: time.Hour, MaxCount: 1},
+		{Name: "bad-key", KeyBy: []KeyField{"cardholder"}, Window: time.Hour, MaxCount: 1},
+		{Name: "no-window", KeyBy: []KeyField{KeyCardID}, MaxCount: 1},
+		{Name: "no-limit", KeyBy: []KeyField{KeyCardID}, Window: time.Hour},
+		{Name: "bad-amount", KeyBy: []KeyField{KeyCardID}, Window: time.Hour, MaxAmount: "ten"},
+		{Name: "bad-mode", KeyBy: []KeyField{KeyCardID}, Window: time.Hour, MaxCount: 1, Mode: "rolling"},
+		{Name: "approving-code", KeyBy: []KeyField{KeyCardID}, Window: time.Hour, MaxCount: 1, ResponseCode: "00"},
+		{Name: "unknown-code", KeyBy: []KeyField{KeyCardID}, Window: time.Hour, MaxCount: 1, ResponseCode: "ZZ"},
+	}
+	for _, limit := range invalid {
+		if _, err := NewVelocity(store, limit); err == nil {
+			t.Errorf("NewVelocity accepted %+v", limit)
+		}
+	}
+	if _, err := NewVelocity(nil); err == nil {
+		t.Error("NewVelocity accepted a nil store")
+	}
+}
+
+func TestVelocityCountsRetriedAuthorizationOnce(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	velocity, store := newTestVelocity(t, &now, VelocityLimit{Name: "daily", KeyBy: []KeyField{KeyCardID}, Window: 24 * time.Hour, MaxCount: 2, MaxAmount: "15"})
+	decide := velocity.Wrap(approve)
+	ctx := context.Background()
+
+	for attempt := 0; attempt < 3; attempt++ {
+		if result, _ := decide(ctx, velocityTransaction("tx-1", "10")); result.ResponseCode != "00" {
+			t.Fatalf("attempt %d = %+v; a retry must not count again", attempt, result)
+		}
+	}
+	key := velocity.limits[0].key(velocityTransaction("tx-1", "10"))
+	if entries, _ := store.Entries(ctx, key, now.Add(-time.Hour)); len(entries) != 1 {
+		t.Fatalf("entries = %+v, want one reservation", entries)
+	}
+	if result, _ := decide(ctx, velocityTransaction("tx-2", "5")); result.ResponseCode != "00" {
+		t.Fatalf("tx-2 = %+v", result)
+	}
+	if result, _ := decide(ctx, velocityTransaction("tx-3", "1")); result.ResponseCode != defaultCountLimitCode {
+		t.Fatalf("tx-3 = %+v, want count limit", result)
+	}
+}
@@ -30,4 +30,8 @@ type Configuration struct {
 	APIKey      string
 	Environment *Environment
 	HTTPClient  *http.Client
+
+	// APIKeySource, when set, supplies the API key instead of APIKey. It is
+	// fetched again each time the auth token is refreshed.
+	APIKeySource KeySource
 }
@@ -0,0 +1,140 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultReloadInterval is how often WatchKeys re-fetches sources when no
+// interval is given.
+const DefaultReloadInterval = 5 * time.Minute
+
+// KeySource supplies a secret such as an API key, webhook signing secret or
+// PGP key. Implementations are called again on every reload, so they should
+// return the current value rather than caching it forever.
+type KeySource interface {
+	FetchKey(ctx context.Context) (string, error)
+}
+
+// KeySourceFunc adapts a user-supplied fetch function, for example a call to
+// a cloud secret manager, to a KeySource.
+type KeySourceFunc func(ctx context.Context) (string, error)
+
+// FetchKey calls f.
+func (f KeySourceFunc) FetchKey(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// StaticKey returns a KeySource that always yields value.
+func StaticKey(value string) KeySource {
+	return KeySourceFunc(func(context.Context) (string, error) {
+		return value, nil
+	})
+}
+
+// EnvKey returns a KeySource that reads the named environment variable.
+// An unset or empty variable is an error.
+func EnvKey(name string) KeySource {
+	return KeySourceFunc(func(context.Context) (string, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	})
+}
+
+// FileKey returns a KeySource that reads the file at path on every fetch.
+// Surrounding whitespace is trimmed, which suits mounted secret files.
+func FileKey(path string) KeySource {
+	return KeySourceFunc(func(context.Context) (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read key file: %w", err)
+		}
+		value := strings.TrimSpace(string(data))
+		if value == "" {
+			return "", fmt.Errorf("key file %s is empty", path)
+		}
+		return value, nil
+	})
+}
+
+// FetchKeys fetches every source in order. A nil source yields an empty
+// string, so optional values such as a passphrase can be left unset.
+func FetchKeys(ctx context.Context, sources ...KeySource) ([]string, error) {
+	values := make([]string, len(sources))
+	for i, source := range sources {
+		if source == nil {
+			continue
+		}
+		value, err := source.FetchKey(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch key %d: %w", i, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// WatchKeys fetches sources immediately and then every interval, calling
+// apply with the fetched values whenever any of them differs from the last
+// values that were applied successfully. An error from the initial fetch or
+// apply is returned straight away; later errors are passed to onError and the
+// previous values stay in effect. WatchKeys blocks until ctx is done.
+func WatchKeys(ctx context.Context, interval time.Duration, apply func(values []string) error, onError func(error), sources ...KeySource) error {
+	if apply == nil {
+		return errors.New("watch keys: apply function is required")
+	}
+	if interval <= 0 {
+		interval = DefaultReloadInterval
+	}
+
+	current, err := FetchKeys(ctx, sources...)
+	if err != nil {
+		return err
+	}
+	if err := apply(current); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		values, err := FetchKeys(ctx, sources...)
+		if err == nil && equalValues(values, current) {
+			continue
+		}
+		if err == nil {
+			err = apply(values)
+		}
+		if err != nil {
+			if onError != nil && ctx.Err() == nil {
+				onError(err)
+			}
+			continue
+		}
+		current = values
+	}
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,20 @@
+// Package ctxutil holds context helpers shared by SDK packages.
+package ctxutil
+
+import (
+	"context"
+	"time"
+)
+
+// WithoutCancel returns a context that keeps ctx's values but not its
+// deadline or cancellation, for work that must outlive the request that
+// started it. It matches context.WithoutCancel from Go 1.21.
+func WithoutCancel(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+type detachedContext struct{ context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
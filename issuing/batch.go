@@ -0,0 +1,564 @@
+package issuing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+)
+
+// BatchOperation is the card operation applied to every item of a batch.
+type BatchOperation string
+
+const (
+	BatchCreate       BatchOperation = "create"
+	BatchUpdate       BatchOperation = "update"
+	BatchUpdateStatus BatchOperation = "update_status"
+	BatchRecharge     BatchOperation = "recharge"
+	BatchWithdraw     BatchOperation = "withdraw"
+)
+
+const (
+	defaultBatchConcurrency = 8
+	defaultBatchAttempts    = 3
+)
+
+// batchNamespace scopes the idempotency keys derived for batch items.
+var batchNamespace = uuid.MustParse("5b0e4a52-57c6-4f0b-9a3e-64a1f0d1c6a9")
+
+// BatchItem is one row of a batch. Set CardID for every operation except
+// BatchCreate, and the request field that matches the operation: Create,
+// Update, Status, or Order for recharges and withdrawals.
+type BatchItem struct {
+	// Key identifies the row across runs. It derives the row's idempotency
+	// key and its checkpoint entry, so it must be unique within the batch and
+	// stable when a batch is resumed. Empty uses the row's position.
+	Key    string
+	CardID string
+
+	Create *CreateCardRequest
+	Update *CardUpdateRequest
+	Status *UpdateCardStatusRequest
+	Order  *CardOrderRequest
+}
+
+// BatchResult is the outcome of one batch item.
+type BatchResult struct {
+	Index       int    `json:"index"`
+	Key         string `json:"key"`
+	CardID      string `json:"card_id,omitempty"`
+	CardOrderID string `json:"card_order_id,omitempty"`
+	CardStatus  string `json:"card_status,omitempty"`
+	OrderStatus string `json:"order_status,omitempty"`
+	// Attempts is the number of API calls made for the item in this run.
+	Attempts int `json:"-"`
+	// Resumed is set when the item completed in an earlier run and was read
+	// from the checkpoint instead of being sent again.
+	Resumed bool  `json:"-"`
+	Err     error `json:"-"`
+}
+
+// BatchProgress is reported after every completed item.
+type BatchProgress struct {
+	// Total is the number of items in the batch, or 0 when the items come
+	// from a stream of unknown length.
+	Total     int
+	Done      int
+	Succeeded int
+	Failed    int
+	Resumed   int
+	Last      BatchResult
+}
+
+// BatchReport summarises a batch run.
+type BatchReport struct {
+	BatchID   string
+	Operation BatchOperation
+	// Results holds one entry per item that was processed, in input order.
+	Results   []BatchResult
+	Succeeded int
+	Failed    int
+	Resumed   int
+	Elapsed   time.Duration
+}
+
+// Failures returns the results of the items that failed.
+func (r *BatchReport) Failures() []BatchResult {
+	var failures []BatchResult
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// BatchOptions configures a Batch.
+type BatchOptions struct {
+	// BatchID scopes the idempotency keys of the batch. Reuse it to resume a
+	// batch: rows with the same Key then send the same idempotency key, so a
+	// request that reached UQPAY before a crash is not applied twice. It is
+	// required with Checkpoint; otherwise empty generates a random ID.
+	BatchID string
+	// Concurrency is the number of requests in flight at once. Zero uses 8.
+	Concurrency int
+	// RatePerSecond limits how many requests, including retries, are started
+	// per second. Zero means no limit.
+	RatePerSecond float64
+	// MaxAttempts is the number of calls made per item before a retryable
+	// error (network failure, 429 or 5xx) is reported. Zero uses 3.
+	MaxAttempts int
+	// Backoff is the delay between attempts. The zero value uses
+	// DefaultBackoff.
+	Backoff Backoff
+	// Checkpoint is the path of a file recording completed items, one JSON
+	// object per line. Items recorded as succeeded are skipped when the batch
+	// is run again; failed items are retried.
+	Checkpoint string
+	// OnProgress is called after every item. Calls do not overlap.
+	OnProgress func(BatchProgress)
+	// RequestOptions is applied to every call. Its IdempotencyKey is replaced
+	// by the key derived for each item.
+	RequestOptions *common.RequestOptions
+}
+
+// Batch runs a card operation over many items with bounded concurrency,
+// rate limiting, retries and derived idempotency keys.
+type Batch struct {
+	cards *CardsClient
+	opts  BatchOptions
+}
+
+// NewBatch creates a batch runner that sends requests through cards.
+func NewBatch(cards *CardsClient, opts BatchOptions) *Batch {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultBatchConcurrency
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultBatchAttempts
+	}
+	if opts.Backoff == (Backoff{}) {
+		opts.Backoff = DefaultBackoff
+	}
+	return &Batch{cards: cards, opts: opts}
+}
+
+// CreateCards creates a card for every request.
+func (b *Batch) CreateCards(ctx context.Context, reqs []*CreateCardRequest) (*BatchReport, error) {
+	items := make([]BatchItem, len(reqs))
+	for i, req := range reqs {
+		items[i] = BatchItem{Create: req}
+	}
+	return b.RunItems(ctx, BatchCreate, items)
+}
+
+// UpdateStatus applies the same status change, e.g. FROZEN, to every card.
+// Card IDs are used as item keys.
+func (b *Batch) UpdateStatus(ctx context.Context, cardIDs []string, req *UpdateCardStatusRequest) (*BatchReport, error) {
+	items := make([]BatchItem, len(cardIDs))
+	for i, cardID := range cardIDs {
+		items[i] = BatchItem{Key: cardID, CardID: cardID, Status: req}
+	}
+	return b.RunItems(ctx, BatchUpdateStatus, items)
+}
+
+// RunItems applies op to every item.
+func (b *Batch) RunItems(ctx context.Context, op BatchOperation, items []BatchItem) (*BatchReport, error) {
+	feedCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream := make(chan BatchItem)
+	go func() {
+		defer close(stream)
+		for _, item := range items {
+			select {
+			case stream <- item:
+			case <-feedCtx.Done():
+				return
+			}
+		}
+	}()
+	return b.run(ctx, op, stream, len(items))
+}
+
+// Run applies op to every item received from items until the channel is
+// closed. It returns when all received items have finished. If ctx is
+// cancelled, items not yet started are left out of the report and ctx.Err()
+// is returned with it; run the batch again with the same BatchID and
+// Checkpoint to continue.
+func (b *Batch) Run(ctx context.Context, op BatchOperation, items <-chan BatchItem) (*BatchReport, error) {
+	return b.run(ctx, op, items, 0)
+}
+
+type indexedItem struct {
+	index int
+	item  BatchItem
+}
+
+func (b *Batch) run(ctx context.Context, op BatchOperation, items <-chan BatchItem, total int) (*BatchReport, error) {
+	started := time.Now()
+	switch op {
+	case BatchCreate, BatchUpdate, BatchUpdateStatus, BatchRecharge, BatchWithdraw:
+	default:
+		return nil, fmt.Errorf("unknown batch operation %q", op)
+	}
+	batchID := b.opts.BatchID
+	if batchID == "" {
+		if b.opts.Checkpoint != "" {
+			return nil, errors.New("a batch ID is required to use a checkpoint")
+		}
+		batchID = uuid.NewString()
+	}
+
+	var checkpoint *batchCheckpoint
+	if b.opts.Checkpoint != "" {
+		var err error
+		checkpoint, err = openBatchCheckpoint(b.opts.Checkpoint, batchID, op)
+		if err != nil {
+			return nil, err
+		}
+		defer checkpoint.Close()
+	}
+
+	report := &BatchReport{BatchID: batchID, Operation: op}
+	limiter := newRateLimiter(b.opts.RatePerSecond)
+	var (
+		mu       sync.Mutex
+		progress = BatchProgress{Total: total}
+		results  []BatchResult
+		writeErr error
+	)
+	finish := func(result BatchResult, record bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if checkpoint != nil && record && writeErr == nil {
+			writeErr = checkpoint.Record(result)
+		}
+		results = append(results, result)
+		progress.Done++
+		switch {
+		case result.Resumed:
+			progress.Resumed++
+		case result.Err != nil:
+			progress.Failed++
+		default:
+			progress.Succeeded++
+		}
+		progress.Last = result
+		if b.opts.OnProgress != nil {
+			b.opts.OnProgress(progress)
+		}
+	}
+
+	work := make(chan indexedItem)
+	var wg sync.WaitGroup
+	for i := 0; i < b.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for next := range work {
+				finish(b.process(ctx, op, batchID, limiter, next), true)
+			}
+		}()
+	}
+
+	seen := map[string]bool{}
+	index := 0
+feed:
+	for {
+		var item BatchItem
+		var ok bool
+		select {
+		case item, ok = <-items:
+			if !ok {
+				break feed
+			}
+		case <-ctx.Done():
+			break feed
+		}
+		key := item.Key
+		if key == "" {
+			key = strconv.Itoa(index)
+		}
+		item.Key = key
+		next := indexedItem{index: index, item: item}
+		index++
+
+		switch {
+		case seen[key]:
+			finish(BatchResult{Index: next.index, Key: key, Err: fmt.Errorf("batch item %d: duplicate key %q", next.index, key)}, false)
+			continue
+		case checkpoint != nil && checkpoint.Done(key):
+			result := checkpoint.Result(key)
+			result.Index = next.index
+			result.Resumed = true
+			seen[key] = true
+			finish(result, false)
+			continue
+		}
+		seen[key] = true
+		select {
+		case work <- next:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	report.Results = results
+	report.Succeeded = progress.Succeeded
+	report.Failed = progress.Failed
+	report.Resumed = progress.Resumed
+	report.Elapsed = time.Since(started)
+	if writeErr != nil {
+		return report, fmt.Errorf("failed to write batch checkpoint: %w", writeErr)
+	}
+	return report, ctx.Err()
+}
+
+// process sends one item, retrying transient errors with the same
+// idempotency key.
+func (b *Batch) process(ctx context.Context, op BatchOperation, batchID string, limiter *rateLimiter, next indexedItem) BatchResult {
+	result := BatchResult{Index: next.index, Key: next.item.Key}
+	call, err := b.call(op, next.item)
+	if err != nil {
+		result.Err = fmt.Errorf("batch item %d: %w", next.index, err)
+		return result
+	}
+
+	opts := common.RequestOptions{}
+	if b.opts.RequestOptions != nil {
+		opts = *b.opts.RequestOptions
+	}
+	opts.IdempotencyKey = uuid.NewSHA1(batchNamespace, []byte(batchID+"\x00"+string(op)+"\x00"+next.item.Key)).String()
+
+	for attempt := 0; ; attempt++ {
+		if err := limiter.wait(ctx); err != nil {
+			result.Err = err
+			return result
+		}
+		result.Attempts++
+		err := call(ctx, &opts, &result)
+		if err == nil {
+			result.Err = nil
+			return result
+		}
+		result.Err = err
+		if !retryable(err) || result.Attempts >= b.opts.MaxAttempts {
+			return result
+		}
+		timer := time.NewTimer(b.opts.Backoff.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result
+		case <-timer.C:
+		}
+	}
+}
+
+type batchCall func(ctx context.Context, opts *common.RequestOptions, result *BatchResult) error
+
+// call validates item for op and returns the API call that applies it.
+func (b *Batch) call(op BatchOperation, item BatchItem) (batchCall, error) {
+	if op != BatchCreate && item.CardID == "" {
+		return nil, errors.New("card ID is required")
+	}
+	switch op {
+	case BatchCreate:
+		if item.Create == nil {
+			return nil, errors.New("create request is required")
+		}
+		return func(ctx context.Context, opts *common.RequestOptions, result *BatchResult) error {
+			resp, err := b.cards.Create(ctx, item.Create, opts)
+			if err != nil {
+				return err
+			}
+			result.CardID, result.CardOrderID = resp.CardID, resp.CardOrderID
+			result.CardStatus, result.OrderStatus = resp.CardStatus, resp.OrderStatus
+			return nil
+		}, nil
+	case BatchUpdate:
+		if item.Update == nil {
+			return nil, errors.New("update request is required")
+		}
+		return func(ctx context.Context, opts *common.RequestOptions, result *BatchResult) error {
+			resp, err := b.cards.Update(ctx, item.CardID, item.Update, opts)
+			if err != nil {
+				return err
+			}
+			result.CardID, result.CardOrderID = resp.CardID, resp.CardOrderID
+			result.CardStatus, result.OrderStatus = resp.CardStatus, resp.OrderStatus
+			return nil
+		}, nil
+	case BatchUpdateStatus:
+		if item.Status == nil {
+			return nil, errors.New("status request is required")
+		}
+		return func(ctx context.Context, opts *common.RequestOptions, result *BatchResult) error {
+			resp, err := b.cards.UpdateStatus(ctx, item.CardID, item.Status, opts)
+			if err != nil {
+				return err
+			}
+			result.CardID, result.CardOrderID = resp.CardID, resp.CardOrderID
+			result.CardStatus, result.OrderStatus = item.Status.CardStatus, resp.OrderStatus
+			return nil
+		}, nil
+	default:
+		if item.Order == nil {
+			return nil, errors.New("order request is required")
+		}
+		send := b.cards.Recharge
+		if op == BatchWithdraw {
+			send = b.cards.Withdraw
+		}
+		return func(ctx context.Context, opts *common.RequestOptions, result *BatchResult) error {
+			resp, err := send(ctx, item.CardID, item.Order, opts)
+			if err != nil {
+				return err
+			}
+			result.CardID, result.CardOrderID, result.OrderStatus = resp.CardID, resp.CardOrderID, resp.OrderStatus
+			return nil
+		}, nil
+	}
+}
+
+// checkpointEntry is one line of a checkpoint file.
+type checkpointEntry struct {
+	BatchID   string         `json:"batch_id"`
+	Operation BatchOperation `json:"operation"`
+	BatchResult
+	Error string `json:"error,omitempty"`
+}
+
+// batchCheckpoint records completed items in an append-only JSON lines file.
+type batchCheckpoint struct {
+	file      *os.File
+	batchID   string
+	op        BatchOperation
+	completed map[string]BatchResult
+}
+
+func openBatchCheckpoint(path, batchID string, op BatchOperation) (*batchCheckpoint, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch checkpoint: %w", err)
+	}
+	checkpoint := &batchCheckpoint{file: file, batchID: batchID, op: op, completed: map[string]BatchResult{}}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	var pending error
+	for scanner.Scan() {
+		line++
+		if pending != nil {
+			// Only the last line may be damaged, by a crash mid-write.
+			file.Close()
+			return nil, pending
+		}
+		var entry checkpointEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			pending = fmt.Errorf("invalid batch checkpoint line %d: %w", line, err)
+			continue
+		}
+		if entry.BatchID != batchID || entry.Operation != op {
+			file.Close()
+			return nil, fmt.Errorf("batch checkpoint %s belongs to batch %s (%s), not %s (%s)", path, entry.BatchID, entry.Operation, batchID, op)
+		}
+		if entry.Error == "" {
+			checkpoint.completed[entry.Key] = entry.BatchResult
+		} else {
+			delete(checkpoint.completed, entry.Key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read batch checkpoint: %w", err)
+	}
+	if pending != nil {
+		// Start the next entry on a fresh line after a torn write.
+		if _, err := file.Write([]byte("\n")); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to write batch checkpoint: %w", err)
+		}
+	}
+	return checkpoint, nil
+}
+
+// Done reports whether key completed successfully in an earlier run.
+func (c *batchCheckpoint) Done(key string) bool {
+	_, ok := c.completed[key]
+	return ok
+}
+
+// Result returns the recorded result for key.
+func (c *batchCheckpoint) Result(key string) BatchResult {
+	return c.completed[key]
+}
+
+// Record appends result to the file.
+func (c *batchCheckpoint) Record(result BatchResult) error {
+	entry := checkpointEntry{BatchID: c.batchID, Operation: c.op, BatchResult: result}
+	if result.Err != nil {
+		entry.Error = result.Err.Error()
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = c.file.Write(append(data, '\n'))
+	return err
+}
+
+func (c *batchCheckpoint) Close() error {
+	return c.file.Close()
+}
+
+// rateLimiter spaces calls evenly at a fixed rate.
+type rateLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+func newRateLimiter(perSecond float64) *rateLimiter {
+	if perSecond <= 0 {
+		return &rateLimiter{}
+	}
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait blocks until the caller may start its next call.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
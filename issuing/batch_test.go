@@ -0,0 +1,151 @@
+package issuing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeCardIssuer struct {
+	mu       sync.Mutex
+	keys     map[string][]string // cardholder ID -> idempotency keys seen
+	failures map[string]int      // cardholder ID -> 503s still to return
+	rejected map[string]bool
+	frozen   []string
+}
+
+func newBatchTestClient(t *testing.T, issuer *fakeCardIssuer) *CardsClient {
+	t.Helper()
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/status") {
+			issuer.frozen = append(issuer.frozen, strings.Split(r.URL.Path, "/")[4])
+			_, _ = w.Write([]byte(`{"card_id":"` + strings.Split(r.URL.Path, "/")[4] + `","order_status":"PROCESSING"}`))
+			return
+		}
+		var req CreateCardRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		issuer.keys[req.CardholderID] = append(issuer.keys[req.CardholderID], r.Header.Get("x-idempotency-key"))
+		switch {
+		case issuer.failures[req.CardholderID] > 0:
+			issuer.failures[req.CardholderID]--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"type":"server_error","message":"unavailable"}`))
+		case issuer.rejected[req.CardholderID]:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"type":"invalid_request","message":"cardholder not verified"}`))
+		default:
+			_, _ = w.Write([]byte(`{"card_id":"card-` + req.CardholderID + `","card_order_id":"order-` + req.CardholderID + `","card_status":"PENDING","order_status":"PROCESSING"}`))
+		}
+	}).Cards
+}
+
+func TestBatchCreateCardsRetriesAndResumesFromCheckpoint(t *testing.T) {
+	issuer := &fakeCardIssuer{
+		keys:     map[string][]string{},
+		failures: map[string]int{"ch-2": 1},
+		rejected: map[string]bool{"ch-3": true},
+	}
+	cards := newBatchTestClient(t, issuer)
+	reqs := []*CreateCardRequest{
+		{CardholderID: "ch-1", CardCurrency: "USD", CardProductID: "prod"},
+		{CardholderID: "ch-2", CardCurrency: "USD", CardProductID: "prod"},
+		{CardholderID: "ch-3", CardCurrency: "USD", CardProductID: "prod"},
+		{CardholderID: "ch-4", CardCurrency: "USD", CardProductID: "prod"},
+	}
+	var progress []BatchProgress
+	opts := BatchOptions{
+		BatchID:       "expense-program",
+		Concurrency:   2,
+		RatePerSecond: 1000,
+		Backoff:       Backoff{Initial: time.Millisecond},
+		Checkpoint:    filepath.Join(t.TempDir(), "batch.jsonl"),
+		OnProgress:    func(p BatchProgress) { progress = append(progress, p) },
+	}
+
+	report, err := NewBatch(cards, opts).CreateCards(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("CreateCards: %v", err)
+	}
+	if report.Succeeded != 3 || report.Failed != 1 || len(report.Results) != 4 {
+		t.Fatalf("report = %+v", report)
+	}
+	for i, result := range report.Results {
+		if result.Index != i {
+			t.Fatalf("results out of order: %+v", report.Results)
+		}
+	}
+	if got := report.Results[1]; got.CardID != "card-ch-2" || got.Attempts != 2 || got.Err != nil {
+		t.Fatalf("retried item = %+v", got)
+	}
+	if keys := issuer.keys["ch-2"]; len(keys) != 2 || keys[0] != keys[1] {
+		t.Fatalf("retry idempotency keys = %v, want the same key twice", keys)
+	}
+	if issuer.keys["ch-1"][0] == issuer.keys["ch-4"][0] {
+		t.Fatal("items share an idempotency key")
+	}
+	failures := report.Failures()
+	if len(failures) != 1 || failures[0].Key != "2" || !strings.Contains(failures[0].Err.Error(), "cardholder not verified") {
+		t.Fatalf("failures = %+v", failures)
+	}
+	if len(progress) != 4 || progress[3].Done != 4 || progress[3].Total != 4 {
+		t.Fatalf("progress = %+v", progress)
+	}
+
+	// A second run skips the cards already issued and retries the failure
+	// with the same idempotency key.
+	firstKey := issuer.keys["ch-3"][0]
+	issuer.rejected["ch-3"] = false
+	progress = nil
+	report, err = NewBatch(cards, opts).CreateCards(context.Background(), reqs)
+	if err != nil {
+		t.Fatalf("resumed CreateCards: %v", err)
+	}
+	if report.Resumed != 3 || report.Succeeded != 1 || report.Failed != 0 {
+		t.Fatalf("resumed report = %+v", report)
+	}
+	if got := report.Results[0]; !got.Resumed || got.CardID != "card-ch-1" || got.CardOrderID != "order-ch-1" {
+		t.Fatalf("resumed item = %+v", got)
+	}
+	if len(issuer.keys["ch-1"]) != 1 || len(issuer.keys["ch-3"]) != 2 || issuer.keys["ch-3"][1] != firstKey {
+		t.Fatalf("calls after resume = %v", issuer.keys)
+	}
+
+	opts.BatchID = "another-program"
+	if _, err := NewBatch(cards, opts).CreateCards(context.Background(), reqs); err == nil {
+		t.Fatal("expected an error for a checkpoint of another batch")
+	}
+}
+
+func TestBatchUpdateStatusValidatesItems(t *testing.T) {
+	issuer := &fakeCardIssuer{keys: map[string][]string{}}
+	cards := newBatchTestClient(t, issuer)
+
+	report, err := NewBatch(cards, BatchOptions{}).UpdateStatus(context.Background(),
+		[]string{"card-1", "", "card-2", "card-1"},
+		&UpdateCardStatusRequest{CardStatus: CardStatusFrozen})
+	if err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	if report.Succeeded != 2 || report.Failed != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if got := report.Results[0]; got.CardStatus != CardStatusFrozen || got.OrderStatus != OrderStatusProcessing {
+		t.Fatalf("result = %+v", got)
+	}
+	if len(issuer.frozen) != 2 {
+		t.Fatalf("frozen = %v", issuer.frozen)
+	}
+	if _, err := NewBatch(cards, BatchOptions{Checkpoint: "batch.jsonl"}).UpdateStatus(context.Background(), nil, nil); err == nil {
+		t.Fatal("expected an error for a checkpoint without a batch ID")
+	}
+}
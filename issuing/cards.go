@@ -52,13 +52,31 @@ type SpendingControl struct {
 	Interval string `json:"interval"` // PER_TRANSACTION
 }
 
-// RiskControls represents user-customized risk control settings
+// RiskControls represents user-customized risk control settings.
+// A nil MCC list is left out of requests; an empty, non-nil list is sent as
+// [] and clears the codes on the card.
 type RiskControls struct {
 	Allow3DSTransactions *string  `json:"allow_3ds_transactions,omitempty"` // Y or N
 	AllowedMCC           []string `json:"allowed_mcc,omitempty"`
 	BlockedMCC           []string `json:"blocked_mcc,omitempty"`
 }
 
+// MarshalJSON encodes r, sending empty non-nil MCC lists as [].
+func (r RiskControls) MarshalJSON() ([]byte, error) {
+	wire := struct {
+		Allow3DSTransactions *string   `json:"allow_3ds_transactions,omitempty"`
+		AllowedMCC           *[]string `json:"allowed_mcc,omitempty"`
+		BlockedMCC           *[]string `json:"blocked_mcc,omitempty"`
+	}{Allow3DSTransactions: r.Allow3DSTransactions}
+	if r.AllowedMCC != nil {
+		wire.AllowedMCC = &r.AllowedMCC
+	}
+	if r.BlockedMCC != nil {
+		wire.BlockedMCC = &r.BlockedMCC
+	}
+	return json.Marshal(wire)
+}
+
 // CardUpdateRequest represents a card update request
 type CardUpdateRequest struct {
 	CardLimit          *float64          `json:"card_limit,omitempty"`
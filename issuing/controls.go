@@ -0,0 +1,421 @@
+package issuing
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SpendingIntervalPerTransaction limits the amount of each transaction.
+const SpendingIntervalPerTransaction = "PER_TRANSACTION"
+
+// Values of RiskControls.Allow3DSTransactions.
+const (
+	Allow3DSYes = "Y"
+	Allow3DSNo  = "N"
+)
+
+// Controls are the spending and risk controls of a card, as sent in
+// CreateCardRequest and CardUpdateRequest.
+type Controls struct {
+	SpendingControls []SpendingControl
+	RiskControls     *RiskControls
+}
+
+// ControlsOf returns the controls currently set on card.
+func ControlsOf(card *RetrieveCardResponse) Controls {
+	if card == nil {
+		return Controls{}
+	}
+	return Controls{SpendingControls: card.SpendingControls, RiskControls: card.RiskControls}
+}
+
+// ApplyTo sets the controls on a card creation request.
+func (c Controls) ApplyTo(req *CreateCardRequest) {
+	req.SpendingControls = c.SpendingControls
+	req.RiskControls = c.RiskControls
+}
+
+// UpdateRequest returns a card update request that sets the controls.
+func (c Controls) UpdateRequest() *CardUpdateRequest {
+	return &CardUpdateRequest{SpendingControls: c.SpendingControls, RiskControls: c.RiskControls}
+}
+
+// Conflicts describes every inconsistency in the controls: invalid or
+// duplicated limits, malformed MCCs and MCCs that are both allowed and
+// blocked.
+func (c Controls) Conflicts() []string {
+	var conflicts []string
+	intervals := map[string]bool{}
+	for _, control := range c.SpendingControls {
+		if control.Interval == "" {
+			conflicts = append(conflicts, "spending control has no interval")
+		} else if intervals[control.Interval] {
+			conflicts = append(conflicts, fmt.Sprintf("spending control %s is set more than once", control.Interval))
+		}
+		intervals[control.Interval] = true
+		if amount, ok := parseControlAmount(control.Amount); !ok || amount.Sign() <= 0 {
+			conflicts = append(conflicts, fmt.Sprintf("spending control %s has invalid amount %q", control.Interval, control.Amount))
+		}
+	}
+	if c.RiskControls == nil {
+		return conflicts
+	}
+	risk := c.RiskControls
+	if risk.Allow3DSTransactions != nil && *risk.Allow3DSTransactions != Allow3DSYes && *risk.Allow3DSTransactions != Allow3DSNo {
+		conflicts = append(conflicts, fmt.Sprintf("allow_3ds_transactions must be %s or %s, got %q", Allow3DSYes, Allow3DSNo, *risk.Allow3DSTransactions))
+	}
+	for _, code := range append(append([]string(nil), risk.AllowedMCC...), risk.BlockedMCC...) {
+		if !isMCC(code) {
+			conflicts = append(conflicts, fmt.Sprintf("invalid merchant category code %q", code))
+		}
+	}
+	blocked := map[string]bool{}
+	for _, code := range risk.BlockedMCC {
+		blocked[code] = true
+	}
+	for _, code := range uniqueSorted(risk.AllowedMCC) {
+		if blocked[code] {
+			conflicts = append(conflicts, fmt.Sprintf("merchant category code %s%s is both allowed and blocked", code, mccLabel(code)))
+		}
+	}
+	return conflicts
+}
+
+// ControlsConflictError lists the conflicts that made controls invalid.
+type ControlsConflictError struct {
+	Conflicts []string
+}
+
+func (e *ControlsConflictError) Error() string {
+	return "invalid card controls: " + strings.Join(e.Conflicts, "; ")
+}
+
+// Validate returns a *ControlsConflictError if the controls have conflicts.
+func (c Controls) Validate() error {
+	if conflicts := c.Conflicts(); len(conflicts) > 0 {
+		return &ControlsConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// ControlsBuilder builds Controls step by step. Methods record invalid input
+// and Build reports it, so calls can be chained:
+//
+//	controls, err := issuing.NewControlsBuilder().
+//	    PerTransactionLimit(500).
+//	    BlockCategories(issuing.MCCCategoryGambling, issuing.MCCCategoryCrypto).
+//	    Allow3DS(true).
+//	    Build()
+type ControlsBuilder struct {
+	spending []SpendingControl
+	allowed  []string
+	blocked  []string
+	allow3DS *string
+	errs     []string
+}
+
+// NewControlsBuilder creates an empty builder.
+func NewControlsBuilder() *ControlsBuilder {
+	return &ControlsBuilder{}
+}
+
+// PerTransactionLimit caps the amount of each transaction, in the card
+// currency.
+func (b *ControlsBuilder) PerTransactionLimit(amount float64) *ControlsBuilder {
+	return b.Limit(SpendingIntervalPerTransaction, amount)
+}
+
+// Limit adds a spending limit for interval.
+func (b *ControlsBuilder) Limit(interval string, amount float64) *ControlsBuilder {
+	if amount <= 0 {
+		b.errs = append(b.errs, fmt.Sprintf("spending control %s must have a positive amount", interval))
+		return b
+	}
+	b.spending = append(b.spending, SpendingControl{
+		Amount:   formatAmount(amount),
+		Interval: interval,
+	})
+	return b
+}
+
+// AllowMCCs restricts the card to the given merchant category codes, together
+// with any other allowed codes.
+func (b *ControlsBuilder) AllowMCCs(codes ...string) *ControlsBuilder {
+	b.allowed = append(b.allowed, codes...)
+	return b
+}
+
+// BlockMCCs declines the given merchant category codes.
+func (b *ControlsBuilder) BlockMCCs(codes ...string) *ControlsBuilder {
+	b.blocked = append(b.blocked, codes...)
+	return b
+}
+
+// ClearAllowedMCCs discards the allowed codes added so far and makes the
+// controls remove every allowed code from the card, unless codes are allowed
+// again afterwards.
+func (b *ControlsBuilder) ClearAllowedMCCs() *ControlsBuilder {
+	b.allowed = []string{}
+	return b
+}
+
+// ClearBlockedMCCs discards the blocked codes added so far and makes the
+// controls remove every blocked code from the card, unless codes are blocked
+// again afterwards.
+func (b *ControlsBuilder) ClearBlockedMCCs() *ControlsBuilder {
+	b.blocked = []string{}
+	return b
+}
+
+// AllowCategories allows every catalog code in categories.
+func (b *ControlsBuilder) AllowCategories(categories ...MCCCategory) *ControlsBuilder {
+	return b.AllowMCCs(b.categoryCodes(categories)...)
+}
+
+// BlockCategories blocks every catalog code in categories.
+func (b *ControlsBuilder) BlockCategories(categories ...MCCCategory) *ControlsBuilder {
+	return b.BlockMCCs(b.categoryCodes(categories)...)
+}
+
+func (b *ControlsBuilder) categoryCodes(categories []MCCCategory) []string {
+	var codes []string
+	for _, category := range categories {
+		inCategory := MCCsIn(category)
+		if len(inCategory) == 0 {
+			b.errs = append(b.errs, fmt.Sprintf("unknown merchant category %q", category))
+		}
+		codes = append(codes, inCategory...)
+	}
+	return codes
+}
+
+// Allow3DS sets whether 3-D Secure transactions are allowed.
+func (b *ControlsBuilder) Allow3DS(allow bool) *ControlsBuilder {
+	value := Allow3DSNo
+	if allow {
+		value = Allow3DSYes
+	}
+	b.allow3DS = &value
+	return b
+}
+
+// Build returns the controls, or a *ControlsConflictError describing invalid
+// input and conflicts. MCC lists are de-duplicated and sorted; a cleared list
+// is empty rather than nil.
+func (b *ControlsBuilder) Build() (Controls, error) {
+	controls := Controls{SpendingControls: b.spending}
+	if b.allowed != nil || b.blocked != nil || b.allow3DS != nil {
+		controls.RiskControls = &RiskControls{
+			Allow3DSTransactions: b.allow3DS,
+			AllowedMCC:           codeList(b.allowed),
+			BlockedMCC:           codeList(b.blocked),
+		}
+	}
+	conflicts := append(append([]string(nil), b.errs...), controls.Conflicts()...)
+	if len(conflicts) > 0 {
+		return controls, &ControlsConflictError{Conflicts: conflicts}
+	}
+	return controls, nil
+}
+
+// ControlChange is one difference between a card's current and desired
+// settings. For lists, Added and Removed hold the changed entries; for single
+// values, From and To hold the old and new value, with "" meaning unset.
+type ControlChange struct {
+	Field   string
+	From    string
+	To      string
+	Added   []string
+	Removed []string
+}
+
+func (c ControlChange) String() string {
+	if c.Added != nil || c.Removed != nil {
+		var parts []string
+		if len(c.Added) > 0 {
+			parts = append(parts, "+"+strings.Join(c.Added, ",+"))
+		}
+		if len(c.Removed) > 0 {
+			parts = append(parts, "-"+strings.Join(c.Removed, ",-"))
+		}
+		return c.Field + ": " + strings.Join(parts, " ")
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Field, orUnset(c.From), orUnset(c.To))
+}
+
+func orUnset(value string) string {
+	if value == "" {
+		return "(unset)"
+	}
+	return value
+}
+
+// DiffControls returns the changes needed to turn current into desired. Only
+// what desired sets is compared: nil SpendingControls, nil RiskControls, a nil
+// Allow3DSTransactions and nil MCC lists leave the current value alone, while
+// an empty, non-nil MCC list removes every current code. Amounts are compared
+// numerically.
+func DiffControls(current, desired Controls) []ControlChange {
+	var changes []ControlChange
+	if desired.SpendingControls != nil {
+		changes = append(changes, diffSpending(current.SpendingControls, desired.SpendingControls)...)
+	}
+	if desired.RiskControls == nil {
+		return changes
+	}
+	have := current.RiskControls
+	if have == nil {
+		have = &RiskControls{}
+	}
+	want := desired.RiskControls
+	if want.Allow3DSTransactions != nil && stringValue(have.Allow3DSTransactions) != *want.Allow3DSTransactions {
+		changes = append(changes, ControlChange{
+			Field: "risk_controls.allow_3ds_transactions",
+			From:  stringValue(have.Allow3DSTransactions),
+			To:    *want.Allow3DSTransactions,
+		})
+	}
+	if want.AllowedMCC != nil {
+		if change, ok := diffCodes("risk_controls.allowed_mcc", have.AllowedMCC, want.AllowedMCC); ok {
+			changes = append(changes, change)
+		}
+	}
+	if want.BlockedMCC != nil {
+		if change, ok := diffCodes("risk_controls.blocked_mcc", have.BlockedMCC, want.BlockedMCC); ok {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func diffSpending(current, desired []SpendingControl) []ControlChange {
+	have := map[string]string{}
+	for _, control := range current {
+		have[control.Interval] = control.Amount
+	}
+	want := map[string]string{}
+	var intervals []string
+	for _, control := range desired {
+		want[control.Interval] = control.Amount
+		intervals = append(intervals, control.Interval)
+	}
+	for interval := range have {
+		if _, ok := want[interval]; !ok {
+			intervals = append(intervals, interval)
+		}
+	}
+	sort.Strings(intervals)
+
+	var changes []ControlChange
+	for _, interval := range intervals {
+		from, to := have[interval], want[interval]
+		if amountsEqual(from, to) {
+			continue
+		}
+		changes = append(changes, ControlChange{Field: "spending_controls." + interval, From: from, To: to})
+	}
+	return changes
+}
+
+func diffCodes(field string, current, desired []string) (ControlChange, bool) {
+	have := map[string]bool{}
+	for _, code := range current {
+		have[code] = true
+	}
+	want := map[string]bool{}
+	change := ControlChange{Field: field, Added: []string{}, Removed: []string{}}
+	for _, code := range uniqueSorted(desired) {
+		want[code] = true
+		if !have[code] {
+			change.Added = append(change.Added, code)
+		}
+	}
+	for _, code := range uniqueSorted(current) {
+		if !want[code] {
+			change.Removed = append(change.Removed, code)
+		}
+	}
+	return change, len(change.Added) > 0 || len(change.Removed) > 0
+}
+
+func amountsEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	x, okX := parseControlAmount(a)
+	y, okY := parseControlAmount(b)
+	return okX && okY && x.Cmp(y) == 0
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+func parseControlAmount(amount string) (*big.Rat, bool) {
+	amount = strings.TrimSpace(amount)
+	if amount == "" || strings.ContainsAny(amount, "/eE") {
+		return nil, false
+	}
+	return new(big.Rat).SetString(amount)
+}
+
+// codeList is uniqueSorted that keeps a non-nil empty list empty.
+func codeList(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	if codes := uniqueSorted(values); codes != nil {
+		return codes
+	}
+	return []string{}
+}
+
+func uniqueSorted(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	var unique []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+func mccLabel(code string) string {
+	if mcc, ok := LookupMCC(code); ok {
+		return " (" + mcc.Description + ")"
+	}
+	return ""
+}
+
+func isMCC(code string) bool {
+	if len(code) != 4 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
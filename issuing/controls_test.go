@@ -0,0 +1,155 @@
+package issuing
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestControlsBuilderBlocksCategories(t *testing.T) {
+	controls, err := NewControlsBuilder().
+		PerTransactionLimit(250.5).
+		BlockCategories(MCCCategoryGambling, MCCCategoryCrypto).
+		BlockMCCs("7995").
+		Allow3DS(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if want := []SpendingControl{{Amount: "250.5", Interval: SpendingIntervalPerTransaction}}; !reflect.DeepEqual(controls.SpendingControls, want) {
+		t.Fatalf("SpendingControls = %+v", controls.SpendingControls)
+	}
+	if want := []string{"6051", "7800", "7801", "7802", "7995", "9406"}; !reflect.DeepEqual(controls.RiskControls.BlockedMCC, want) {
+		t.Fatalf("BlockedMCC = %v, want %v", controls.RiskControls.BlockedMCC, want)
+	}
+	if got := stringValue(controls.RiskControls.Allow3DSTransactions); got != Allow3DSYes {
+		t.Fatalf("Allow3DSTransactions = %q", got)
+	}
+
+	var req CreateCardRequest
+	controls.ApplyTo(&req)
+	if req.RiskControls != controls.RiskControls || len(req.SpendingControls) != 1 {
+		t.Fatalf("ApplyTo = %+v", req)
+	}
+}
+
+func TestControlsBuilderReportsConflicts(t *testing.T) {
+	_, err := NewControlsBuilder().
+		PerTransactionLimit(100).
+		PerTransactionLimit(-1).
+		Limit(SpendingIntervalPerTransaction, 200).
+		AllowCategories(MCCCategoryTravel).
+		BlockMCCs("4511", "45X1").
+		BlockCategories("spaceflight").
+		Build()
+	var conflictErr *ControlsConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Build error = %v", err)
+	}
+	want := []string{
+		"must have a positive amount",
+		`unknown merchant category "spaceflight"`,
+		"PER_TRANSACTION is set more than once",
+		`invalid merchant category code "45X1"`,
+		"4511 (Airlines and air carriers) is both allowed and blocked",
+	}
+	if len(conflictErr.Conflicts) != len(want) {
+		t.Fatalf("conflicts = %q", conflictErr.Conflicts)
+	}
+	for i, fragment := range want {
+		if !strings.Contains(conflictErr.Conflicts[i], fragment) {
+			t.Errorf("conflict %d = %q, want it to contain %q", i, conflictErr.Conflicts[i], fragment)
+		}
+	}
+}
+
+func TestDiffControls(t *testing.T) {
+	yes := Allow3DSYes
+	card := &RetrieveCardResponse{
+		SpendingControls: []SpendingControl{{Amount: "100.00", Interval: SpendingIntervalPerTransaction}},
+		RiskControls:     &RiskControls{Allow3DSTransactions: &yes, BlockedMCC: []string{"7995", "6011"}},
+	}
+	desired, err := NewControlsBuilder().
+		PerTransactionLimit(100).
+		BlockCategories(MCCCategoryGambling).
+		Allow3DS(false).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	changes := DiffControls(ControlsOf(card), desired)
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	want := []string{
+		"risk_controls.allow_3ds_transactions: Y -> N",
+		"risk_controls.blocked_mcc: +7800,+7801,+7802,+9406 -6011",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("changes = %q, want %q", got, want)
+	}
+
+	if changes := DiffControls(ControlsOf(card), Controls{}); len(changes) != 0 {
+		t.Fatalf("empty desired controls produced changes: %v", changes)
+	}
+	changes = DiffControls(Controls{}, Controls{SpendingControls: card.SpendingControls})
+	if len(changes) != 1 || changes[0].String() != "spending_controls.PER_TRANSACTION: (unset) -> 100.00" {
+		t.Fatalf("changes = %v", changes)
+	}
+}
+
+func TestControlsClearMCCs(t *testing.T) {
+	card := &RetrieveCardResponse{
+		RiskControls: &RiskControls{AllowedMCC: []string{"5812"}, BlockedMCC: []string{"7995", "6011"}},
+	}
+	desired, err := NewControlsBuilder().BlockMCCs("7995").ClearBlockedMCCs().Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	changes := DiffControls(ControlsOf(card), desired)
+	if len(changes) != 1 || changes[0].String() != "risk_controls.blocked_mcc: -6011,-7995" {
+		t.Fatalf("changes = %v", changes)
+	}
+
+	merged := MergeControls(ControlsOf(card), desired)
+	if merged.RiskControls.BlockedMCC == nil || len(merged.RiskControls.BlockedMCC) != 0 || !reflect.DeepEqual(merged.RiskControls.AllowedMCC, []string{"5812"}) {
+		t.Fatalf("merged = %+v", merged.RiskControls)
+	}
+	body, err := json.Marshal(desired.UpdateRequest())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(body); !strings.Contains(got, `"risk_controls":{"blocked_mcc":[]}`) {
+		t.Fatalf("update request = %s", got)
+	}
+	if changes := DiffControls(ControlsOf(&RetrieveCardResponse{}), desired); len(changes) != 0 {
+		t.Fatalf("clearing an empty list produced changes: %v", changes)
+	}
+}
+
+func TestMCCCatalog(t *testing.T) {
+	mcc, ok := LookupMCC("6011")
+	if !ok || !mcc.InCategory(MCCCategoryCash) {
+		t.Fatalf("LookupMCC(6011) = %+v, %v", mcc, ok)
+	}
+	seen := map[string]bool{}
+	previous := ""
+	for _, mcc := range MCCs() {
+		if !isMCC(mcc.Code) || mcc.Code <= previous || mcc.Description == "" || len(mcc.Categories) == 0 {
+			t.Errorf("bad or unsorted catalog entry %+v", mcc)
+		}
+		previous = mcc.Code
+		for _, category := range mcc.Categories {
+			seen[string(category)] = true
+		}
+	}
+	for _, category := range MCCCategories() {
+		if !seen[string(category)] || len(MCCsIn(category)) == 0 {
+			t.Errorf("category %s has no codes", category)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package issuing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+)
+
+// Cardholder statuses.
+const (
+	CardholderStatusPending    = "PENDING"
+	CardholderStatusIncomplete = "INCOMPLETE"
+	CardholderStatusSuccess    = "SUCCESS"
+	CardholderStatusFailed     = "FAILED"
+)
+
+// KYCField is a cardholder field that card products may require.
+type KYCField string
+
+const (
+	KYCFieldGender             KYCField = "gender"
+	KYCFieldNationality        KYCField = "nationality"
+	KYCFieldDateOfBirth        KYCField = "date_of_birth"
+	KYCFieldResidentialAddress KYCField = "residential_address"
+	KYCFieldIdentity           KYCField = "identity"
+	KYCFieldKYCVerification    KYCField = "kyc_verification"
+)
+
+// kycFields lists every KYCField in report order.
+var kycFields = []KYCField{
+	KYCFieldGender,
+	KYCFieldNationality,
+	KYCFieldDateOfBirth,
+	KYCFieldResidentialAddress,
+	KYCFieldIdentity,
+	KYCFieldKYCVerification,
+}
+
+// KYCRule requires Fields for the card products and cardholder countries it
+// selects. Empty selectors match everything.
+type KYCRule struct {
+	ProductID  string `json:"product_id,omitempty"`
+	CardScheme string `json:"card_scheme,omitempty"`
+	ModeType   string `json:"mode_type,omitempty"`
+	// Countries are cardholder country codes.
+	Countries []string   `json:"countries,omitempty"`
+	Fields    []KYCField `json:"fields"`
+}
+
+func (r KYCRule) matches(product *CardProduct, country string) bool {
+	if product != nil {
+		if r.ProductID != "" && r.ProductID != product.ProductID ||
+			r.CardScheme != "" && !strings.EqualFold(r.CardScheme, product.CardScheme) ||
+			r.ModeType != "" && !strings.EqualFold(r.ModeType, product.ModeType) {
+			return false
+		}
+	} else if r.ProductID != "" || r.CardScheme != "" || r.ModeType != "" {
+		return false
+	}
+	if len(r.Countries) == 0 {
+		return true
+	}
+	for _, c := range r.Countries {
+		if strings.EqualFold(c, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// KYCMatrix maps card products and cardholder countries to the KYC fields
+// they require. A field is required when any matching rule lists it.
+type KYCMatrix []KYCRule
+
+// kycPersonalFields are the personal details every card product asks for.
+var kycPersonalFields = []KYCField{KYCFieldGender, KYCFieldNationality, KYCFieldDateOfBirth, KYCFieldResidentialAddress}
+
+// DefaultKYCMatrix is the maintained requirement matrix used when no matrix
+// is given. Every product asks for the personal details; products that share
+// a balance across cards (mode type MULTI) and cardholders in countries whose
+// rules require documentary checks also need identity documents, and EEA, UK
+// and Swiss cardholders need a KYC verification as well. Update it as program
+// requirements change; a program with its own onboarding rules can extend it
+// or load a matrix with ParseKYCMatrix.
+var DefaultKYCMatrix = KYCMatrix{
+	{Fields: kycPersonalFields},
+	{ModeType: "MULTI", Fields: []KYCField{KYCFieldIdentity}},
+	{
+		Countries: []string{"AU", "CA", "HK", "JP", "SG", "US"},
+		Fields:    []KYCField{KYCFieldIdentity},
+	},
+	{
+		Countries: []string{
+			"AT", "BE", "BG", "CH", "CY", "CZ", "DE", "DK", "EE", "ES", "FI",
+			"FR", "GB", "GR", "HR", "HU", "IE", "IS", "IT", "LI", "LT", "LU",
+			"LV", "MT", "NL", "NO", "PL", "PT", "RO", "SE", "SI", "SK",
+		},
+		Fields: []KYCField{KYCFieldIdentity, KYCFieldKYCVerification},
+	},
+}
+
+// ParseKYCMatrix decodes a JSON array of KYCRule and checks the field names.
+func ParseKYCMatrix(data []byte) (KYCMatrix, error) {
+	var matrix KYCMatrix
+	if err := json.Unmarshal(data, &matrix); err != nil {
+		return nil, fmt.Errorf("failed to parse KYC matrix: %w", err)
+	}
+	for i, rule := range matrix {
+		if len(rule.Fields) == 0 {
+			return nil, fmt.Errorf("KYC matrix rule %d: fields are required", i)
+		}
+		for _, field := range rule.Fields {
+			if !isKYCField(field) {
+				return nil, fmt.Errorf("KYC matrix rule %d: unknown field %q", i, field)
+			}
+		}
+	}
+	return matrix, nil
+}
+
+// Required returns the fields required for product and a cardholder in
+// country, in a fixed order. product may be nil to apply only the rules that
+// do not select a product.
+func (m KYCMatrix) Required(product *CardProduct, country string) []KYCField {
+	required := map[KYCField]bool{}
+	for _, rule := range m {
+		if rule.matches(product, country) {
+			for _, field := range rule.Fields {
+				required[field] = true
+			}
+		}
+	}
+	var fields []KYCField
+	for _, field := range kycFields {
+		if required[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// KYCIssue is a required field that is missing or malformed.
+type KYCIssue struct {
+	Field KYCField
+	// Missing is set when the field has no value; otherwise Detail says why
+	// the value is malformed.
+	Missing bool
+	Detail  string
+}
+
+func (i KYCIssue) String() string {
+	if i.Missing {
+		return string(i.Field) + ": missing"
+	}
+	return string(i.Field) + ": " + i.Detail
+}
+
+// KYCReadiness reports whether a cardholder can be issued a card without KYC
+// follow-up.
+type KYCReadiness struct {
+	Required []KYCField
+	Issues   []KYCIssue
+	// RequiredFields is the minimal set of fields to send with
+	// CreateCardRequest.CardholderRequiredFields, taken from the known data
+	// passed to Check. It is nil when known data fixes no issue.
+	RequiredFields *CardholderRequiredFields
+	// Unresolved lists fields with issues the known data does not fix.
+	Unresolved []KYCField
+}
+
+// Ready reports whether the cardholder satisfies every requirement as is.
+func (r *KYCReadiness) Ready() bool {
+	return len(r.Issues) == 0
+}
+
+// ReadyWithFields reports whether sending RequiredFields resolves every issue.
+func (r *KYCReadiness) ReadyWithFields() bool {
+	return len(r.Unresolved) == 0
+}
+
+// UpdateRequest returns the minimal cardholder update carrying
+// RequiredFields, or nil when there is nothing to send.
+func (r *KYCReadiness) UpdateRequest() *UpdateCardholderRequest {
+	fields := r.RequiredFields
+	if fields == nil {
+		return nil
+	}
+	return &UpdateCardholderRequest{
+		Gender:             fields.Gender,
+		Nationality:        fields.Nationality,
+		DateOfBirth:        fields.DateOfBirth,
+		ResidentialAddress: fields.ResidentialAddress,
+		Identity:           fields.Identity,
+		KycVerification:    fields.KycVerification,
+	}
+}
+
+// Check reports which fields required for product are missing or malformed
+// on cardholder. Identity documents and KYC verification are not returned by
+// the cardholder API, so they count as present once the cardholder status is
+// SUCCESS and as missing otherwise. known holds data the caller already has,
+// such as an HR record; valid known values fill RequiredFields for the
+// fields with issues. known may be nil. An empty matrix checks against
+// DefaultKYCMatrix.
+func (m KYCMatrix) Check(cardholder *Cardholder, product *CardProduct, known *CardholderRequiredFields) (*KYCReadiness, error) {
+	if cardholder == nil {
+		return nil, errors.New("cardholder is required")
+	}
+	if len(m) == 0 {
+		m = DefaultKYCMatrix
+	}
+	if known == nil {
+		known = &CardholderRequiredFields{}
+	}
+	readiness := &KYCReadiness{Required: m.Required(product, cardholder.CountryCode)}
+	patch := CardholderRequiredFields{}
+	patched := false
+	for _, field := range readiness.Required {
+		issue, ok := cardholderKYCIssue(cardholder, field)
+		if ok {
+			continue
+		}
+		readiness.Issues = append(readiness.Issues, issue)
+		if !knownKYCFieldValid(known, field) {
+			readiness.Unresolved = append(readiness.Unresolved, field)
+			continue
+		}
+		copyKYCField(&patch, known, field)
+		patched = true
+	}
+	if patched {
+		readiness.RequiredFields = &patch
+	}
+	return readiness, nil
+}
+
+// CheckReadiness fetches the cardholder and checks it against matrix for
+// product. A nil matrix uses DefaultKYCMatrix.
+func (c *CardholdersClient) CheckReadiness(ctx context.Context, cardholderID string, product *CardProduct, matrix KYCMatrix, known *CardholderRequiredFields, opts ...*common.RequestOptions) (*KYCReadiness, error) {
+	cardholder, err := c.Get(ctx, cardholderID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return matrix.Check(cardholder, product, known)
+}
+
+// cardholderKYCIssue checks field on cardholder; ok is true when it is valid.
+func cardholderKYCIssue(cardholder *Cardholder, field KYCField) (KYCIssue, bool) {
+	switch field {
+	case KYCFieldGender:
+		return checkGender(cardholder.Gender)
+	case KYCFieldNationality:
+		return checkCountry(field, cardholder.Nationality)
+	case KYCFieldDateOfBirth:
+		return checkDateOfBirth(cardholder.DateOfBirth)
+	case KYCFieldResidentialAddress:
+		return checkAddress(cardholder.ResidentialAddress)
+	default:
+		if cardholder.CardholderStatus == CardholderStatusSuccess {
+			return KYCIssue{}, true
+		}
+		return KYCIssue{Field: field, Missing: true}, false
+	}
+}
+
+// knownKYCFieldValid reports whether known has a valid value for field.
+func knownKYCFieldValid(known *CardholderRequiredFields, field KYCField) bool {
+	var ok bool
+	switch field {
+	case KYCFieldGender:
+		_, ok = checkGender(known.Gender)
+	case KYCFieldNationality:
+		_, ok = checkCountry(field, known.Nationality)
+	case KYCFieldDateOfBirth:
+		_, ok = checkDateOfBirth(known.DateOfBirth)
+	case KYCFieldResidentialAddress:
+		_, ok = checkAddress(known.ResidentialAddress)
+	case KYCFieldIdentity:
+		_, ok = checkIdentity(known.Identity)
+	case KYCFieldKYCVerification:
+		_, ok = checkKYCVerification(known.KycVerification)
+	}
+	return ok
+}
+
+func copyKYCField(dst, src *CardholderRequiredFields, field KYCField) {
+	switch field {
+	case KYCFieldGender:
+		dst.Gender = src.Gender
+	case KYCFieldNationality:
+		dst.Nationality = src.Nationality
+	case KYCFieldDateOfBirth:
+		dst.DateOfBirth = src.DateOfBirth
+	case KYCFieldResidentialAddress:
+		dst.ResidentialAddress = src.ResidentialAddress
+	case KYCFieldIdentity:
+		dst.Identity = src.Identity
+	case KYCFieldKYCVerification:
+		dst.KycVerification = src.KycVerification
+	}
+}
+
+func checkGender(gender *string) (KYCIssue, bool) {
+	switch {
+	case gender == nil || *gender == "":
+		return KYCIssue{Field: KYCFieldGender, Missing: true}, false
+	case *gender != "MALE" && *gender != "FEMALE":
+		return KYCIssue{Field: KYCFieldGender, Detail: fmt.Sprintf("must be MALE or FEMALE, got %q", *gender)}, false
+	}
+	return KYCIssue{}, true
+}
+
+func checkCountry(field KYCField, country *string) (KYCIssue, bool) {
+	switch {
+	case country == nil || *country == "":
+		return KYCIssue{Field: field, Missing: true}, false
+	case !isCountryCode(*country):
+		return KYCIssue{Field: field, Detail: fmt.Sprintf("must be a two-letter ISO 3166 country code, got %q", *country)}, false
+	}
+	return KYCIssue{}, true
+}
+
+func checkDateOfBirth(date *string) (KYCIssue, bool) {
+	if date == nil || *date == "" {
+		return KYCIssue{Field: KYCFieldDateOfBirth, Missing: true}, false
+	}
+	born, err := time.Parse("2006-01-02", *date)
+	if err != nil {
+		return KYCIssue{Field: KYCFieldDateOfBirth, Detail: fmt.Sprintf("must be YYYY-MM-DD, got %q", *date)}, false
+	}
+	if born.After(time.Now()) {
+		return KYCIssue{Field: KYCFieldDateOfBirth, Detail: "is in the future"}, false
+	}
+	return KYCIssue{}, true
+}
+
+func checkAddress(address *ResidentialAddress) (KYCIssue, bool) {
+	if address == nil {
+		return KYCIssue{Field: KYCFieldResidentialAddress, Missing: true}, false
+	}
+	var problems []string
+	if !isCountryCode(address.Country) {
+		problems = append(problems, "country must be a two-letter ISO 3166 country code")
+	}
+	if strings.TrimSpace(address.City) == "" {
+		problems = append(problems, "city is required")
+	}
+	if strings.TrimSpace(address.Line1) == "" {
+		problems = append(problems, "line1 is required")
+	}
+	if len(problems) > 0 {
+		return KYCIssue{Field: KYCFieldResidentialAddress, Detail: strings.Join(problems, "; ")}, false
+	}
+	return KYCIssue{}, true
+}
+
+func checkIdentity(identity *Identity) (KYCIssue, bool) {
+	if identity == nil {
+		return KYCIssue{Field: KYCFieldIdentity, Missing: true}, false
+	}
+	var problems []string
+	switch identity.Type {
+	case "ID_CARD":
+		if identity.BackFile == nil || *identity.BackFile == "" {
+			problems = append(problems, "back_file is required for ID_CARD")
+		}
+	case "PASSPORT":
+	default:
+		problems = append(problems, fmt.Sprintf("type must be ID_CARD or PASSPORT, got %q", identity.Type))
+	}
+	if strings.TrimSpace(identity.Number) == "" {
+		problems = append(problems, "number is required")
+	}
+	if identity.FrontFile == "" {
+		problems = append(problems, "front_file is required")
+	}
+	if len(problems) > 0 {
+		return KYCIssue{Field: KYCFieldIdentity, Detail: strings.Join(problems, "; ")}, false
+	}
+	return KYCIssue{}, true
+}
+
+func checkKYCVerification(verification *KycVerification) (KYCIssue, bool) {
+	if verification == nil {
+		return KYCIssue{Field: KYCFieldKYCVerification, Missing: true}, false
+	}
+	switch verification.Method {
+	case "THIRD_PARTY":
+		if verification.KycProof == nil || verification.KycProof.Provider == "" || verification.KycProof.ReferenceID == "" {
+			return KYCIssue{Field: KYCFieldKYCVerification, Detail: "THIRD_PARTY verification needs a kyc_proof with provider and reference_id"}, false
+		}
+	case "SUMSUB_REDIRECT":
+	default:
+		return KYCIssue{Field: KYCFieldKYCVerification, Detail: fmt.Sprintf("method must be THIRD_PARTY or SUMSUB_REDIRECT, got %q", verification.Method)}, false
+	}
+	return KYCIssue{}, true
+}
+
+func isKYCField(field KYCField) bool {
+	for _, f := range kycFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isCountryCode(code string) bool {
+	return len(code) == 2 && code[0] >= 'A' && code[0] <= 'Z' && code[1] >= 'A' && code[1] <= 'Z'
+}
@@ -0,0 +1,126 @@
+package issuing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKYCMatrixCheck(t *testing.T) {
+	matrix, err := ParseKYCMatrix([]byte(`[
+		{"fields": ["gender", "nationality", "date_of_birth", "residential_address"]},
+		{"card_scheme": "VISA", "countries": ["SG"], "fields": ["identity"]},
+		{"product_id": "prod-premium", "fields": ["kyc_verification"]}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseKYCMatrix: %v", err)
+	}
+	product := &CardProduct{ProductID: "prod-standard", CardScheme: "VISA"}
+	cardholder := &Cardholder{
+		CardholderID:     "ch-1",
+		CountryCode:      "SG",
+		CardholderStatus: CardholderStatusIncomplete,
+		Gender:           stringPtr("M"),
+		Nationality:      stringPtr("SG"),
+		ResidentialAddress: &ResidentialAddress{
+			Country: "SG",
+			Line1:   "1 Raffles Place",
+		},
+	}
+	known := &CardholderRequiredFields{
+		Gender:      stringPtr("MALE"),
+		DateOfBirth: stringPtr("1990-04-01"),
+		Identity:    &Identity{Type: "ID_CARD", Number: "S1234567D", FrontFile: "file-front"},
+	}
+
+	readiness, err := matrix.Check(cardholder, product, known)
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	wantRequired := []KYCField{KYCFieldGender, KYCFieldNationality, KYCFieldDateOfBirth, KYCFieldResidentialAddress, KYCFieldIdentity}
+	if !reflect.DeepEqual(readiness.Required, wantRequired) {
+		t.Fatalf("Required = %v", readiness.Required)
+	}
+	var issues []string
+	for _, issue := range readiness.Issues {
+		issues = append(issues, issue.String())
+	}
+	wantIssues := []string{
+		`gender: must be MALE or FEMALE, got "M"`,
+		"date_of_birth: missing",
+		"residential_address: city is required",
+		"identity: missing",
+	}
+	if !reflect.DeepEqual(issues, wantIssues) {
+		t.Fatalf("Issues = %q", issues)
+	}
+	if readiness.Ready() || readiness.ReadyWithFields() {
+		t.Fatal("cardholder should not be ready")
+	}
+	// The known ID card has no back image, so identity stays unresolved.
+	if want := []KYCField{KYCFieldResidentialAddress, KYCFieldIdentity}; !reflect.DeepEqual(readiness.Unresolved, want) {
+		t.Fatalf("Unresolved = %v", readiness.Unresolved)
+	}
+	want := &CardholderRequiredFields{Gender: known.Gender, DateOfBirth: known.DateOfBirth}
+	if !reflect.DeepEqual(readiness.RequiredFields, want) {
+		t.Fatalf("RequiredFields = %+v", readiness.RequiredFields)
+	}
+	if update := readiness.UpdateRequest(); update == nil || *update.Gender != "MALE" || update.Nationality != nil {
+		t.Fatalf("UpdateRequest = %+v", update)
+	}
+
+	premium := &CardProduct{ProductID: "prod-premium", CardScheme: "MASTERCARD"}
+	verified := &Cardholder{
+		CountryCode:        "US",
+		CardholderStatus:   CardholderStatusSuccess,
+		Gender:             stringPtr("FEMALE"),
+		Nationality:        stringPtr("US"),
+		DateOfBirth:        stringPtr("1985-12-31"),
+		ResidentialAddress: &ResidentialAddress{Country: "US", City: "Austin", Line1: "1 Main St"},
+	}
+	readiness, err = matrix.Check(verified, premium, nil)
+	if err != nil || !readiness.Ready() || readiness.RequiredFields != nil || readiness.UpdateRequest() != nil {
+		t.Fatalf("verified cardholder readiness = %+v", readiness)
+	}
+}
+
+func TestKYCMatrixCheckRejectsNilCardholders(t *testing.T) {
+	if _, err := DefaultKYCMatrix.Check(nil, nil, nil); err == nil {
+		t.Fatal("nil cardholder accepted")
+	}
+}
+
+func TestDefaultKYCMatrixRequirements(t *testing.T) {
+	personal := []KYCField{KYCFieldGender, KYCFieldNationality, KYCFieldDateOfBirth, KYCFieldResidentialAddress}
+	single := &CardProduct{ProductID: "prod-single", ModeType: "SINGLE", CardScheme: "VISA"}
+	multi := &CardProduct{ProductID: "prod-multi", ModeType: "MULTI", CardScheme: "VISA"}
+	for _, tc := range []struct {
+		product *CardProduct
+		country string
+		want    []KYCField
+	}{
+		{single, "BR", personal},
+		{nil, "BR", personal},
+		{multi, "BR", append(personal[:4:4], KYCFieldIdentity)},
+		{single, "SG", append(personal[:4:4], KYCFieldIdentity)},
+		{single, "DE", append(personal[:4:4], KYCFieldIdentity, KYCFieldKYCVerification)},
+		{multi, "GB", append(personal[:4:4], KYCFieldIdentity, KYCFieldKYCVerification)},
+	} {
+		if got := DefaultKYCMatrix.Required(tc.product, tc.country); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Required(%+v, %s) = %v, want %v", tc.product, tc.country, got, tc.want)
+		}
+	}
+
+	// An empty matrix checks against the default one.
+	readiness, err := KYCMatrix(nil).Check(&Cardholder{CountryCode: "DE"}, single, nil)
+	if err != nil || len(readiness.Required) != 6 || readiness.Ready() {
+		t.Fatalf("readiness = %+v, %v", readiness, err)
+	}
+}
+
+func TestParseKYCMatrixRejectsUnknownFields(t *testing.T) {
+	for _, data := range []string{`[{"fields": ["passport"]}]`, `[{"countries": ["US"]}]`, `{}`} {
+		if _, err := ParseKYCMatrix([]byte(data)); err == nil {
+			t.Errorf("ParseKYCMatrix(%s) succeeded", data)
+		}
+	}
+}
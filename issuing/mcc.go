@@ -0,0 +1,180 @@
+package issuing
+
+import (
+	"sort"
+)
+
+// MCCCategory groups merchant category codes for spending and risk controls.
+type MCCCategory string
+
+const (
+	MCCCategoryTravel        MCCCategory = "travel"
+	MCCCategoryTransport     MCCCategory = "transport"
+	MCCCategoryFuel          MCCCategory = "fuel"
+	MCCCategoryDining        MCCCategory = "dining"
+	MCCCategoryGroceries     MCCCategory = "groceries"
+	MCCCategoryAlcohol       MCCCategory = "alcohol"
+	MCCCategoryTobacco       MCCCategory = "tobacco"
+	MCCCategoryGambling      MCCCategory = "gambling"
+	MCCCategoryCrypto        MCCCategory = "crypto"
+	MCCCategoryCash          MCCCategory = "cash"
+	MCCCategoryQuasiCash     MCCCategory = "quasi_cash"
+	MCCCategoryMoneyTransfer MCCCategory = "money_transfer"
+	MCCCategoryInvestment    MCCCategory = "investment"
+	MCCCategorySoftware      MCCCategory = "software"
+	MCCCategoryDigitalGoods  MCCCategory = "digital_goods"
+	MCCCategoryAdvertising   MCCCategory = "advertising"
+	MCCCategoryTelecom       MCCCategory = "telecom"
+	MCCCategoryUtilities     MCCCategory = "utilities"
+	MCCCategoryOffice        MCCCategory = "office"
+	MCCCategoryProfessional  MCCCategory = "professional_services"
+	MCCCategoryHealthcare    MCCCategory = "healthcare"
+	MCCCategoryEntertainment MCCCategory = "entertainment"
+	MCCCategoryAdult         MCCCategory = "adult"
+	MCCCategoryGovernment    MCCCategory = "government"
+	MCCCategoryCharity       MCCCategory = "charity"
+	MCCCategoryEducation     MCCCategory = "education"
+	MCCCategoryRetail        MCCCategory = "retail"
+)
+
+// MCC describes a merchant category code in the bundled catalog.
+type MCC struct {
+	Code        string
+	Description string
+	Categories  []MCCCategory
+}
+
+// InCategory reports whether the code belongs to category.
+func (m MCC) InCategory(category MCCCategory) bool {
+	for _, c := range m.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// mccCatalog lists the codes most relevant to corporate card programs. Branded
+// airline, car rental and hotel codes (3000-3999) are not listed; block the
+// generic codes and add branded ones explicitly when needed.
+var mccCatalog = []MCC{
+	{"4111", "Local and suburban commuter transport", []MCCCategory{MCCCategoryTransport}},
+	{"4112", "Passenger railways", []MCCCategory{MCCCategoryTravel, MCCCategoryTransport}},
+	{"4121", "Taxicabs and limousines", []MCCCategory{MCCCategoryTransport}},
+	{"4131", "Bus lines", []MCCCategory{MCCCategoryTravel, MCCCategoryTransport}},
+	{"4411", "Steamship and cruise lines", []MCCCategory{MCCCategoryTravel}},
+	{"4511", "Airlines and air carriers", []MCCCategory{MCCCategoryTravel}},
+	{"4722", "Travel agencies and tour operators", []MCCCategory{MCCCategoryTravel}},
+	{"4784", "Tolls and bridge fees", []MCCCategory{MCCCategoryTransport}},
+	{"4789", "Transportation services", []MCCCategory{MCCCategoryTransport}},
+	{"4814", "Telecommunication services", []MCCCategory{MCCCategoryTelecom}},
+	{"4816", "Computer network and information services", []MCCCategory{MCCCategoryTelecom, MCCCategorySoftware}},
+	{"4829", "Wire transfers and money orders", []MCCCategory{MCCCategoryMoneyTransfer, MCCCategoryQuasiCash}},
+	{"4900", "Utilities", []MCCCategory{MCCCategoryUtilities}},
+	{"5045", "Computers and peripheral equipment", []MCCCategory{MCCCategoryOffice}},
+	{"5111", "Stationery and office supplies", []MCCCategory{MCCCategoryOffice}},
+	{"5311", "Department stores", []MCCCategory{MCCCategoryRetail}},
+	{"5411", "Grocery stores and supermarkets", []MCCCategory{MCCCategoryGroceries}},
+	{"5499", "Miscellaneous food stores", []MCCCategory{MCCCategoryGroceries}},
+	{"5541", "Service stations", []MCCCategory{MCCCategoryFuel}},
+	{"5542", "Automated fuel dispensers", []MCCCategory{MCCCategoryFuel}},
+	{"5734", "Computer software stores", []MCCCategory{MCCCategorySoftware}},
+	{"5811", "Caterers", []MCCCategory{MCCCategoryDining}},
+	{"5812", "Eating places and restaurants", []MCCCategory{MCCCategoryDining}},
+	{"5813", "Drinking places (bars, taverns, nightclubs)", []MCCCategory{MCCCategoryDining, MCCCategoryAlcohol}},
+	{"5814", "Fast food restaurants", []MCCCategory{MCCCategoryDining}},
+	{"5815", "Digital goods: media", []MCCCategory{MCCCategoryDigitalGoods}},
+	{"5816", "Digital goods: games", []MCCCategory{MCCCategoryDigitalGoods}},
+	{"5817", "Digital goods: applications", []MCCCategory{MCCCategoryDigitalGoods, MCCCategorySoftware}},
+	{"5818", "Digital goods: large digital goods merchant", []MCCCategory{MCCCategoryDigitalGoods}},
+	{"5912", "Drug stores and pharmacies", []MCCCategory{MCCCategoryHealthcare}},
+	{"5921", "Package stores: beer, wine and liquor", []MCCCategory{MCCCategoryAlcohol}},
+	{"5943", "Stationery stores", []MCCCategory{MCCCategoryOffice}},
+	{"5967", "Direct marketing: inbound teleservices", []MCCCategory{MCCCategoryAdult}},
+	{"5993", "Cigar stores and stands", []MCCCategory{MCCCategoryTobacco}},
+	{"5999", "Miscellaneous and specialty retail", []MCCCategory{MCCCategoryRetail}},
+	{"6010", "Manual cash disbursements", []MCCCategory{MCCCategoryCash}},
+	{"6011", "Automated cash disbursements (ATM)", []MCCCategory{MCCCategoryCash}},
+	{"6050", "Quasi cash: financial institutions", []MCCCategory{MCCCategoryQuasiCash}},
+	{"6051", "Quasi cash: non-financial institutions, including cryptocurrency", []MCCCategory{MCCCategoryQuasiCash, MCCCategoryCrypto}},
+	{"6211", "Security brokers and dealers", []MCCCategory{MCCCategoryInvestment}},
+	{"6540", "Stored value card purchase and load", []MCCCategory{MCCCategoryMoneyTransfer, MCCCategoryQuasiCash}},
+	{"7011", "Hotels, motels and resorts", []MCCCategory{MCCCategoryTravel}},
+	{"7273", "Dating and escort services", []MCCCategory{MCCCategoryAdult}},
+	{"7311", "Advertising services", []MCCCategory{MCCCategoryAdvertising}},
+	{"7372", "Computer programming and data processing", []MCCCategory{MCCCategorySoftware}},
+	{"7512", "Car rental agencies", []MCCCategory{MCCCategoryTravel}},
+	{"7523", "Parking lots and garages", []MCCCategory{MCCCategoryTransport}},
+	{"7800", "Government-owned lotteries (US)", []MCCCategory{MCCCategoryGambling}},
+	{"7801", "Government-licensed online casinos", []MCCCategory{MCCCategoryGambling}},
+	{"7802", "Government-licensed horse and dog racing", []MCCCategory{MCCCategoryGambling}},
+	{"7832", "Motion picture theaters", []MCCCategory{MCCCategoryEntertainment}},
+	{"7922", "Theatrical producers and ticket agencies", []MCCCategory{MCCCategoryEntertainment}},
+	{"7941", "Sports clubs and promoters", []MCCCategory{MCCCategoryEntertainment}},
+	{"7995", "Betting, including lottery tickets and casino chips", []MCCCategory{MCCCategoryGambling}},
+	{"7996", "Amusement parks and carnivals", []MCCCategory{MCCCategoryEntertainment}},
+	{"8011", "Doctors", []MCCCategory{MCCCategoryHealthcare}},
+	{"8021", "Dentists and orthodontists", []MCCCategory{MCCCategoryHealthcare}},
+	{"8062", "Hospitals", []MCCCategory{MCCCategoryHealthcare}},
+	{"8099", "Medical services", []MCCCategory{MCCCategoryHealthcare}},
+	{"8111", "Legal services and attorneys", []MCCCategory{MCCCategoryProfessional}},
+	{"8220", "Colleges and universities", []MCCCategory{MCCCategoryEducation}},
+	{"8299", "Schools and educational services", []MCCCategory{MCCCategoryEducation}},
+	{"8398", "Charitable and social service organizations", []MCCCategory{MCCCategoryCharity}},
+	{"8931", "Accounting, auditing and bookkeeping services", []MCCCategory{MCCCategoryProfessional}},
+	{"8999", "Professional services", []MCCCategory{MCCCategoryProfessional}},
+	{"9211", "Court costs", []MCCCategory{MCCCategoryGovernment}},
+	{"9222", "Fines", []MCCCategory{MCCCategoryGovernment}},
+	{"9311", "Tax payments", []MCCCategory{MCCCategoryGovernment}},
+	{"9399", "Government services", []MCCCategory{MCCCategoryGovernment}},
+	{"9406", "Government-owned lotteries (non-US)", []MCCCategory{MCCCategoryGambling, MCCCategoryGovernment}},
+}
+
+var mccsByCode = func() map[string]MCC {
+	byCode := make(map[string]MCC, len(mccCatalog))
+	for _, mcc := range mccCatalog {
+		byCode[mcc.Code] = mcc
+	}
+	return byCode
+}()
+
+// LookupMCC returns the catalog entry for code.
+func LookupMCC(code string) (MCC, bool) {
+	mcc, ok := mccsByCode[code]
+	return mcc, ok
+}
+
+// MCCs returns the catalog sorted by code.
+func MCCs() []MCC {
+	return append([]MCC(nil), mccCatalog...)
+}
+
+// MCCCategories returns every category used in the catalog, sorted.
+func MCCCategories() []MCCCategory {
+	seen := map[MCCCategory]bool{}
+	var categories []MCCCategory
+	for _, mcc := range mccCatalog {
+		for _, category := range mcc.Categories {
+			if !seen[category] {
+				seen[category] = true
+				categories = append(categories, category)
+			}
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i] < categories[j] })
+	return categories
+}
+
+// MCCsIn returns the sorted codes that belong to any of categories.
+func MCCsIn(categories ...MCCCategory) []string {
+	var codes []string
+	for _, mcc := range mccCatalog {
+		for _, category := range categories {
+			if mcc.InCategory(category) {
+				codes = append(codes, mcc.Code)
+				break
+			}
+		}
+	}
+	return codes
+}
@@ -0,0 +1,374 @@
+package issuing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+)
+
+// Card usage types.
+const (
+	UsageTypeNormal  = "NORMAL"
+	UsageTypeOneTime = "ONE_TIME"
+)
+
+// Auto-cancel triggers of one-time cards.
+const (
+	AutoCancelOnAuth    = "ON_AUTH"
+	AutoCancelOnCapture = "ON_CAPTURE"
+)
+
+const (
+	defaultOneTimeExpiry = 24 * time.Hour
+	defaultExpiryGrace   = time.Minute
+)
+
+// oneTimeNamespace scopes the idempotency keys derived from one-time card
+// references.
+var oneTimeNamespace = uuid.MustParse("3f8a2c71-6b0d-4e95-a1c4-7d2e9b5f0a86")
+
+// OneTimeCardRequest describes a single-use card for one payment.
+type OneTimeCardRequest struct {
+	CardholderID  string
+	CardProductID string
+	CardCurrency  string
+	// Amount is the exact amount the card is funded with. It is also the card
+	// limit and, unless Controls sets spending controls, the per-transaction
+	// limit.
+	Amount float64
+	// Controls restricts where the card can be used, e.g. built with
+	// NewControlsBuilder().AllowMCCs(...). The card API restricts merchants
+	// by MCC; there is no merchant ID control.
+	Controls Controls
+	// ExpiresIn is how long the card stays usable. Zero uses 24 hours.
+	ExpiresIn time.Duration
+	// AutoCancelTrigger is AutoCancelOnAuth or AutoCancelOnCapture. Empty uses
+	// AutoCancelOnCapture, so a declined or reversed authorization does not
+	// burn the card.
+	AutoCancelTrigger string
+	// Reference identifies the payment, e.g. an invoice number. It derives
+	// the idempotency keys of the create and funding requests, so issuing the
+	// same reference again does not create or fund a second card.
+	Reference string
+	Metadata  map[string]string
+}
+
+// OneTimeCard is an issued, funded single-use card.
+type OneTimeCard struct {
+	CardID    string
+	Reference string
+	Amount    float64
+	Currency  string
+	ExpiresAt time.Time
+	// FundingOrder is the recharge that funded the card. It is nil when the
+	// card was funded at creation.
+	FundingOrder *CardOrder
+	// Embed is set when OneTimeOptions.Display is, and PANToken otherwise.
+	// Use CardsClient.WithSecureCardInfo to read the details server side.
+	Embed    *CardEmbed
+	PANToken *PANTokenResponse
+}
+
+// OneTimeSettlement is the end of a one-time card's life.
+type OneTimeSettlement struct {
+	CardID string
+	// Status is the card's final status.
+	Status string
+	// Expired is set when the card reached its expiry without being
+	// auto-cancelled and was cancelled by Track.
+	Expired bool
+	// Swept is the unspent balance withdrawn back to the issuing balance.
+	Swept      float64
+	SweepOrder *CardOrder
+}
+
+// OneTimeOptions configures OneTimeCards.
+type OneTimeOptions struct {
+	// Waiter tracks card orders and statuses. Nil creates one with default
+	// options; pass a Waiter fed with webhooks to react to events.
+	Waiter *Waiter
+	// Display, when set, turns issued cards into embeds for the hosted
+	// card-detail display instead of returning a bare PAN token.
+	Display *SecureDisplay
+	// ExpiryGrace is how long Track waits past the expiry for UQPAY to expire
+	// the card before cancelling it. Zero uses one minute.
+	ExpiryGrace time.Duration
+	// RequestOptions is passed to every API call, e.g. OnBehalfOf.
+	// Idempotent calls use derived idempotency keys instead of its
+	// IdempotencyKey.
+	RequestOptions *common.RequestOptions
+	// Now returns the current time. Nil uses time.Now.
+	Now func() time.Time
+}
+
+// OneTimeCards issues single-use cards for individual payments, tracks them
+// until they are cancelled and sweeps their unspent balance back.
+type OneTimeCards struct {
+	cards  *CardsClient
+	waiter *Waiter
+	opts   OneTimeOptions
+
+	mu sync.Mutex
+	// sweeps are withdrawals that did not complete, by card.
+	sweeps map[string]oneTimeSweep
+}
+
+// oneTimeSweep is a withdrawal sent for a card with its idempotency key.
+type oneTimeSweep struct {
+	amount float64
+	key    string
+}
+
+// NewOneTimeCards creates a one-time card helper.
+func NewOneTimeCards(client *Client, opts OneTimeOptions) *OneTimeCards {
+	if opts.Waiter == nil {
+		opts.Waiter = NewWaiter(client.Cards, WaiterOptions{RequestOptions: opts.RequestOptions})
+	}
+	if opts.ExpiryGrace <= 0 {
+		opts.ExpiryGrace = defaultExpiryGrace
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &OneTimeCards{cards: client.Cards, waiter: opts.Waiter, opts: opts, sweeps: map[string]oneTimeSweep{}}
+}
+
+// Issue creates a one-time card, waits for it to become active, funds it with
+// exactly req.Amount and returns it with an embed or PAN token.
+func (o *OneTimeCards) Issue(ctx context.Context, req *OneTimeCardRequest) (*OneTimeCard, error) {
+	if err := validateOneTimeRequest(req); err != nil {
+		return nil, err
+	}
+	expiresIn := req.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = defaultOneTimeExpiry
+	}
+	trigger := req.AutoCancelTrigger
+	if trigger == "" {
+		trigger = AutoCancelOnCapture
+	}
+	expiresAt := o.opts.Now().Add(expiresIn).UTC().Truncate(time.Second)
+
+	controls := req.Controls
+	if controls.SpendingControls == nil {
+		controls.SpendingControls = []SpendingControl{{Amount: formatAmount(req.Amount), Interval: SpendingIntervalPerTransaction}}
+	}
+	create := &CreateCardRequest{
+		CardLimit:         &req.Amount,
+		CardCurrency:      req.CardCurrency,
+		CardholderID:      req.CardholderID,
+		CardProductID:     req.CardProductID,
+		Metadata:          req.Metadata,
+		UsageType:         stringPtr(UsageTypeOneTime),
+		AutoCancelTrigger: &trigger,
+		ExpiryAt:          stringPtr(expiresAt.Format(time.RFC3339)),
+	}
+	controls.ApplyTo(create)
+
+	created, err := o.cards.Create(ctx, create, o.requestOptions(req.Reference, "create"))
+	if err != nil {
+		return nil, err
+	}
+	if created.CardOrderID != "" {
+		if _, err := o.waiter.WaitForOrder(ctx, created.CardOrderID); err != nil {
+			return nil, err
+		}
+	}
+	card, err := o.waiter.WaitForCardStatus(ctx, created.CardID, CardStatusActive)
+	if err != nil {
+		return nil, err
+	}
+
+	issued := &OneTimeCard{
+		CardID:    created.CardID,
+		Reference: req.Reference,
+		Amount:    req.Amount,
+		Currency:  req.CardCurrency,
+		ExpiresAt: expiresAt,
+	}
+	balance, err := parseBalance(card.AvailableBalance)
+	if err != nil {
+		return nil, fmt.Errorf("card %s: %w", card.CardID, err)
+	}
+	if shortfall := roundUpCents(req.Amount - balance); shortfall > 0 {
+		order, err := o.cards.Recharge(ctx, card.CardID, &CardOrderRequest{Amount: shortfall}, o.requestOptions(req.Reference, "fund"))
+		if err != nil {
+			return nil, err
+		}
+		if issued.FundingOrder, err = o.waiter.WaitForOrder(ctx, order.CardOrderID); err != nil {
+			return nil, err
+		}
+	}
+
+	if o.opts.Display != nil {
+		issued.Embed, err = o.opts.Display.Embed(ctx, card.CardID)
+	} else {
+		issued.PANToken, err = o.cards.CreatePANToken(ctx, card.CardID, o.opts.RequestOptions)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// Track waits until the card is auto-cancelled or closed, or its expiry
+// passes, and then sweeps its unspent balance back with Withdraw. A card
+// still usable ExpiryGrace after its expiry is cancelled by Track before the
+// sweep, so it cannot be spent even if the sweep fails. The card's
+// ExpiresAt is required, as the card API does not return the expiry; keep the
+// value returned by Issue.
+func (o *OneTimeCards) Track(ctx context.Context, card *OneTimeCard) (*OneTimeSettlement, error) {
+	if card == nil || card.CardID == "" {
+		return nil, errors.New("one-time card is required")
+	}
+	if card.ExpiresAt.IsZero() {
+		return nil, fmt.Errorf("one-time card %s has no expiry", card.CardID)
+	}
+	deadline := card.ExpiresAt.Add(o.opts.ExpiryGrace)
+	waitCtx, cancel := context.WithTimeout(ctx, deadline.Sub(o.opts.Now()))
+	defer cancel()
+
+	settlement := &OneTimeSettlement{CardID: card.CardID}
+	latest, err := o.waiter.WaitForCardStatus(waitCtx, card.CardID, CardStatusCancelled)
+	var statusErr *CardStatusError
+	switch {
+	case err == nil:
+	case errors.As(err, &statusErr):
+		// The card was closed instead, which also ends it.
+	case ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded):
+		settlement.Expired = true
+	default:
+		return nil, err
+	}
+
+	if settlement.Expired {
+		if _, err := o.cards.UpdateStatus(ctx, card.CardID, &UpdateCardStatusRequest{
+			CardStatus:   CardStatusCancelled,
+			UpdateReason: stringPtr("one-time card expired"),
+		}, o.opts.RequestOptions); err != nil {
+			return settlement, err
+		}
+	}
+	if err := o.sweep(ctx, card, settlement); err != nil {
+		return settlement, err
+	}
+	if settlement.Expired {
+		settlement.Status = CardStatusCancelled
+	} else {
+		settlement.Status = latest.CardStatus
+	}
+	return settlement, nil
+}
+
+// Sweep withdraws the card's unspent balance back to the issuing balance.
+// Every withdrawal gets a new idempotency key. A sweep retried after a
+// transient error resends the same amount with the same key while the card
+// still holds it, so it is not applied twice.
+func (o *OneTimeCards) Sweep(ctx context.Context, card *OneTimeCard) (*OneTimeSettlement, error) {
+	if card == nil || card.CardID == "" {
+		return nil, errors.New("one-time card is required")
+	}
+	settlement := &OneTimeSettlement{CardID: card.CardID}
+	err := o.sweep(ctx, card, settlement)
+	return settlement, err
+}
+
+func (o *OneTimeCards) sweep(ctx context.Context, card *OneTimeCard, settlement *OneTimeSettlement) error {
+	latest, err := o.cards.Get(ctx, card.CardID, o.opts.RequestOptions)
+	if err != nil {
+		return err
+	}
+	settlement.Status = latest.CardStatus
+	balance, err := parseBalance(latest.AvailableBalance)
+	if err != nil {
+		return fmt.Errorf("card %s: %w", card.CardID, err)
+	}
+	balance = math.Floor(balance*100+1e-6) / 100
+	if balance <= 0 {
+		o.mu.Lock()
+		delete(o.sweeps, card.CardID)
+		o.mu.Unlock()
+		return nil
+	}
+	o.mu.Lock()
+	attempt, ok := o.sweeps[card.CardID]
+	if !ok || attempt.amount != balance {
+		attempt = oneTimeSweep{amount: balance, key: uuid.NewString()}
+		o.sweeps[card.CardID] = attempt
+	}
+	o.mu.Unlock()
+	opts := o.requestOptions("", "")
+	opts.IdempotencyKey = attempt.key
+	order, err := o.cards.Withdraw(ctx, card.CardID, &CardOrderRequest{Amount: balance}, opts)
+	if err == nil {
+		settlement.SweepOrder, err = o.waiter.WaitForOrder(ctx, order.CardOrderID)
+	}
+	var failed *OrderFailedError
+	if err == nil || errors.As(err, &failed) || !retryable(err) && ctx.Err() == nil {
+		// The withdrawal completed or was refused; the next sweep is a new one.
+		o.mu.Lock()
+		delete(o.sweeps, card.CardID)
+		o.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	settlement.Swept = balance
+	return nil
+}
+
+// requestOptions returns the configured request options with an idempotency
+// key derived from reference and step, or a random key when reference is
+// empty.
+func (o *OneTimeCards) requestOptions(reference, step string) *common.RequestOptions {
+	opts := &common.RequestOptions{}
+	if o.opts.RequestOptions != nil {
+		*opts = *o.opts.RequestOptions
+	}
+	opts.IdempotencyKey = ""
+	if reference != "" {
+		opts.IdempotencyKey = uuid.NewSHA1(oneTimeNamespace, []byte(reference+"\x00"+step)).String()
+	}
+	return opts
+}
+
+func validateOneTimeRequest(req *OneTimeCardRequest) error {
+	if req == nil {
+		return errors.New("one-time card request is required")
+	}
+	if req.CardholderID == "" || req.CardProductID == "" || req.CardCurrency == "" {
+		return errors.New("cardholder ID, card product ID and card currency are required")
+	}
+	if req.Amount <= 0 || math.IsInf(req.Amount, 0) || math.IsNaN(req.Amount) {
+		return errors.New("one-time card amount must be positive")
+	}
+	if req.ExpiresIn < 0 {
+		return errors.New("one-time card expiry cannot be negative")
+	}
+	if req.AutoCancelTrigger != "" && req.AutoCancelTrigger != AutoCancelOnAuth && req.AutoCancelTrigger != AutoCancelOnCapture {
+		return fmt.Errorf("unknown auto-cancel trigger %q", req.AutoCancelTrigger)
+	}
+	return req.Controls.Validate()
+}
+
+// parseBalance parses an available balance. Empty means zero.
+func parseBalance(balance string) (float64, error) {
+	balance = strings.TrimSpace(balance)
+	if balance == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(balance, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid available balance %q", balance)
+	}
+	return value, nil
+}
@@ -0,0 +1,249 @@
+package issuing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeOneTimeCard serves a single card through creation, funding, spending
+// and cancellation. A withdrawal resent with a key seen before is not applied
+// again.
+type fakeOneTimeCard struct {
+	mu       sync.Mutex
+	create   *CreateCardRequest
+	keys     map[string]string
+	status   string
+	balance  float64
+	withdraw []float64
+	// calls lists the withdraw and status requests in order.
+	calls []string
+	// withdrawKeys are the idempotency keys of applied withdrawals.
+	withdrawKeys map[string]bool
+	// withdrawFailures fails that many withdrawals with 503 without
+	// applying them.
+	withdrawFailures int
+}
+
+func newOneTimeTestClient(t *testing.T, card *fakeOneTimeCard) *Client {
+	t.Helper()
+	card.keys = map[string]string{}
+	card.withdrawKeys = map[string]bool{}
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		card.mu.Lock()
+		defer card.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.Path, "/v1/issuing/cards")
+		switch {
+		case r.Method == http.MethodPost && path == "":
+			card.create = &CreateCardRequest{}
+			_ = json.NewDecoder(r.Body).Decode(card.create)
+			card.keys["create"] = r.Header.Get("x-idempotency-key")
+			card.status = CardStatusActive
+			_, _ = w.Write([]byte(`{"card_id":"card-1","card_order_id":"order-create","card_status":"PENDING","order_status":"PROCESSING"}`))
+		case strings.HasSuffix(path, "/order"):
+			_, _ = w.Write([]byte(`{"card_order_id":"` + strings.Split(path, "/")[1] + `","card_id":"card-1","order_status":"SUCCESS"}`))
+		case path == "/card-1/recharge" || path == "/card-1/withdraw":
+			var req CardOrderRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			op := strings.TrimPrefix(path, "/card-1/")
+			key := r.Header.Get("x-idempotency-key")
+			card.keys[op] = key
+			switch {
+			case op == "recharge":
+				card.balance += req.Amount
+			case card.withdrawFailures > 0:
+				card.withdrawFailures--
+				card.calls = append(card.calls, op)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"type":"server_error","message":"unavailable"}`))
+				return
+			case !card.withdrawKeys[key]:
+				card.withdrawKeys[key] = true
+				card.balance -= req.Amount
+				card.withdraw = append(card.withdraw, req.Amount)
+				card.calls = append(card.calls, op)
+			}
+			_, _ = w.Write([]byte(`{"card_id":"card-1","card_order_id":"order-` + op + `","order_status":"PROCESSING"}`))
+		case path == "/card-1/token":
+			_, _ = w.Write([]byte(`{"token":"pan-token","expires_in":60}`))
+		case path == "/card-1/status":
+			var req UpdateCardStatusRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			card.status = req.CardStatus
+			card.calls = append(card.calls, "status")
+			_, _ = w.Write([]byte(`{"card_id":"card-1","order_status":"SUCCESS"}`))
+		case path == "/card-1":
+			_ = json.NewEncoder(w).Encode(map[string]string{"card_id": "card-1", "card_status": card.status, "available_balance": formatAmount(card.balance)})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func TestOneTimeCardsIssueTrackAndSweep(t *testing.T) {
+	fake := &fakeOneTimeCard{}
+	client := newOneTimeTestClient(t, fake)
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	oneTime := NewOneTimeCards(client, OneTimeOptions{
+		Waiter: NewWaiter(client.Cards, WaiterOptions{Backoff: fastBackoff}),
+		Now:    func() time.Time { return now },
+	})
+
+	controls, err := NewControlsBuilder().AllowMCCs("4722").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := oneTime.Issue(context.Background(), &OneTimeCardRequest{
+		CardholderID:  "holder-1",
+		CardProductID: "product-1",
+		CardCurrency:  "USD",
+		Amount:        249.99,
+		Controls:      controls,
+		ExpiresIn:     time.Hour,
+		Reference:     "invoice-42",
+	})
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if card.CardID != "card-1" || card.PANToken == nil || card.PANToken.Token != "pan-token" || card.FundingOrder == nil {
+		t.Errorf("card = %+v", card)
+	}
+	if !card.ExpiresAt.Equal(now.Add(time.Hour)) || fake.balance != 249.99 {
+		t.Errorf("expires at %v, balance %v", card.ExpiresAt, fake.balance)
+	}
+
+	req := fake.create
+	if stringValue(req.UsageType) != UsageTypeOneTime || stringValue(req.AutoCancelTrigger) != AutoCancelOnCapture || stringValue(req.ExpiryAt) != "2024-06-01T11:00:00Z" {
+		t.Errorf("create request = %+v", req)
+	}
+	if req.CardLimit == nil || *req.CardLimit != 249.99 || len(req.SpendingControls) != 1 || req.SpendingControls[0].Amount != "249.99" {
+		t.Errorf("limits = %v, %+v", req.CardLimit, req.SpendingControls)
+	}
+	if req.RiskControls == nil || len(req.RiskControls.AllowedMCC) != 1 || req.RiskControls.AllowedMCC[0] != "4722" {
+		t.Errorf("risk controls = %+v", req.RiskControls)
+	}
+	if fake.keys["create"] == "" || fake.keys["create"] != oneTime.requestOptions("invoice-42", "create").IdempotencyKey {
+		t.Errorf("create key = %q", fake.keys["create"])
+	}
+
+	// The payment captures less than the card was funded with and UQPAY
+	// auto-cancels the card.
+	fake.mu.Lock()
+	fake.balance = 10.01
+	fake.status = CardStatusCancelled
+	fake.mu.Unlock()
+
+	settlement, err := oneTime.Track(context.Background(), card)
+	if err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	if settlement.Status != CardStatusCancelled || settlement.Expired || settlement.Swept != 10.01 || settlement.SweepOrder == nil {
+		t.Errorf("settlement = %+v", settlement)
+	}
+	if len(fake.withdraw) != 1 || fake.balance > 0.001 {
+		t.Errorf("withdrawals = %v, balance = %v", fake.withdraw, fake.balance)
+	}
+}
+
+func TestOneTimeCardsTrackCancelsExpiredCard(t *testing.T) {
+	fake := &fakeOneTimeCard{status: CardStatusActive, balance: 30}
+	client := newOneTimeTestClient(t, fake)
+	oneTime := NewOneTimeCards(client, OneTimeOptions{
+		Waiter:      NewWaiter(client.Cards, WaiterOptions{Backoff: fastBackoff}),
+		ExpiryGrace: 20 * time.Millisecond,
+	})
+
+	card := &OneTimeCard{CardID: "card-1", ExpiresAt: time.Now()}
+	settlement, err := oneTime.Track(context.Background(), card)
+	if err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	if !settlement.Expired || settlement.Status != CardStatusCancelled || settlement.Swept != 30 {
+		t.Errorf("settlement = %+v", settlement)
+	}
+	if fake.status != CardStatusCancelled {
+		t.Errorf("card status = %s", fake.status)
+	}
+	if len(fake.calls) != 2 || fake.calls[0] != "status" || fake.calls[1] != "withdraw" {
+		t.Errorf("calls = %v, want the card cancelled before the sweep", fake.calls)
+	}
+}
+
+func TestOneTimeCardsTrackCancelsExpiredCardEvenIfSweepFails(t *testing.T) {
+	fake := &fakeOneTimeCard{status: CardStatusActive, balance: 30, withdrawFailures: 3}
+	client := newOneTimeTestClient(t, fake)
+	oneTime := NewOneTimeCards(client, OneTimeOptions{
+		Waiter:      NewWaiter(client.Cards, WaiterOptions{Backoff: fastBackoff}),
+		ExpiryGrace: 20 * time.Millisecond,
+	})
+
+	if _, err := oneTime.Track(context.Background(), &OneTimeCard{CardID: "card-1", ExpiresAt: time.Now()}); err == nil {
+		t.Fatal("expected the sweep to fail")
+	}
+	if fake.status != CardStatusCancelled {
+		t.Errorf("card status = %s, want the expired card cancelled", fake.status)
+	}
+}
+
+func TestOneTimeCardsSweepKeys(t *testing.T) {
+	fake := &fakeOneTimeCard{status: CardStatusCancelled, balance: 30, withdrawFailures: 1}
+	client := newOneTimeTestClient(t, fake)
+	oneTime := NewOneTimeCards(client, OneTimeOptions{Waiter: NewWaiter(client.Cards, WaiterOptions{Backoff: fastBackoff})})
+	card := &OneTimeCard{CardID: "card-1"}
+
+	// A transient failure is retried with the same key.
+	if _, err := oneTime.Sweep(context.Background(), card); err == nil {
+		t.Fatal("expected the transient failure")
+	}
+	failedKey := fake.keys["withdraw"]
+	if settlement, err := oneTime.Sweep(context.Background(), card); err != nil || settlement.Swept != 30 {
+		t.Fatalf("retry = %+v, %v", settlement, err)
+	}
+	if fake.keys["withdraw"] != failedKey {
+		t.Errorf("retry key = %q, want %q", fake.keys["withdraw"], failedKey)
+	}
+
+	// A later sweep of the same amount is a new withdrawal with a new key.
+	fake.mu.Lock()
+	fake.balance = 30
+	fake.mu.Unlock()
+	if settlement, err := oneTime.Sweep(context.Background(), card); err != nil || settlement.Swept != 30 || fake.balance > 0.001 {
+		t.Fatalf("second sweep = %+v, %v, balance = %v", settlement, err, fake.balance)
+	}
+	if fake.keys["withdraw"] == failedKey || len(fake.withdraw) != 2 {
+		t.Errorf("keys reused: %v, withdrawals = %v", fake.keys, fake.withdraw)
+	}
+}
+
+func TestOneTimeCardsTrackRequiresExpiry(t *testing.T) {
+	fake := &fakeOneTimeCard{status: CardStatusActive, balance: 30}
+	client := newOneTimeTestClient(t, fake)
+	oneTime := NewOneTimeCards(client, OneTimeOptions{Waiter: NewWaiter(client.Cards, WaiterOptions{Backoff: fastBackoff})})
+
+	if _, err := oneTime.Track(context.Background(), &OneTimeCard{CardID: "card-1"}); err == nil {
+		t.Fatal("Track accepted a card without an expiry")
+	}
+	if fake.status != CardStatusActive || len(fake.withdraw) != 0 {
+		t.Errorf("card status = %s, withdrawals = %v", fake.status, fake.withdraw)
+	}
+}
+
+func TestOneTimeCardsIssueValidates(t *testing.T) {
+	oneTime := NewOneTimeCards(NewClient(nil), OneTimeOptions{})
+	for _, req := range []*OneTimeCardRequest{
+		nil,
+		{CardholderID: "h", CardProductID: "p", CardCurrency: "USD"},
+		{CardholderID: "h", CardProductID: "p", CardCurrency: "USD", Amount: 10, AutoCancelTrigger: "ON_REFUND"},
+		{CardProductID: "p", CardCurrency: "USD", Amount: 10},
+	} {
+		if _, err := oneTime.Issue(context.Background(), req); err == nil {
+			t.Errorf("expected an error for %+v", req)
+		}
+	}
+}
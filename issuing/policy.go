@@ -0,0 +1,346 @@
+package issuing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+)
+
+const defaultPolicyPageSize = 100
+
+// PolicyTemplate is the desired configuration of a group of cards. Nil and
+// empty fields leave the card's current value alone, except MCC lists in
+// Controls.RiskControls: an empty, non-nil list removes every code from the
+// card (see ControlsBuilder.ClearBlockedMCCs).
+type PolicyTemplate struct {
+	Name               string
+	CardLimit          *float64
+	NoPINPaymentAmount *float64
+	Controls           Controls
+	// Metadata tags are set on every bound card. Other metadata keys on the
+	// card are kept.
+	Metadata map[string]string
+}
+
+// PolicyBinding applies a template to every card whose metadata contains all
+// key/value pairs of Selector.
+type PolicyBinding struct {
+	Template string
+	Selector map[string]string
+}
+
+// matches reports whether metadata contains the selector.
+func (b PolicyBinding) matches(metadata map[string]string) bool {
+	for key, value := range b.Selector {
+		if actual, ok := metadata[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
+
+// PolicyOptions configures a PolicyReconciler.
+type PolicyOptions struct {
+	// PageSize is the List page size used to plan. Zero uses 100.
+	PageSize int
+	// SkipStatuses are card statuses left out of plans. Nil uses
+	// DefaultTerminalCardStatuses.
+	SkipStatuses []string
+	// Batch configures how Apply sends updates.
+	Batch BatchOptions
+	// RequestOptions is passed to the List calls made while planning, e.g.
+	// OnBehalfOf. Set Batch.RequestOptions for Apply.
+	RequestOptions *common.RequestOptions
+}
+
+// PolicyReconciler brings live cards in line with policy templates using a
+// plan/apply workflow.
+type PolicyReconciler struct {
+	cards     *CardsClient
+	templates map[string]PolicyTemplate
+	bindings  []PolicyBinding
+	opts      PolicyOptions
+}
+
+// NewPolicyReconciler validates templates and bindings and creates a
+// reconciler. Every binding must name a template and have a selector.
+func NewPolicyReconciler(cards *CardsClient, templates []PolicyTemplate, bindings []PolicyBinding, opts PolicyOptions) (*PolicyReconciler, error) {
+	byName := make(map[string]PolicyTemplate, len(templates))
+	for i, template := range templates {
+		if template.Name == "" {
+			return nil, fmt.Errorf("policy template %d: name is required", i)
+		}
+		if _, ok := byName[template.Name]; ok {
+			return nil, fmt.Errorf("policy template %q is defined twice", template.Name)
+		}
+		if err := template.Controls.Validate(); err != nil {
+			return nil, fmt.Errorf("policy template %q: %w", template.Name, err)
+		}
+		if template.CardLimit != nil && *template.CardLimit < 0 {
+			return nil, fmt.Errorf("policy template %q: card limit cannot be negative", template.Name)
+		}
+		if template.NoPINPaymentAmount != nil && *template.NoPINPaymentAmount < 0 {
+			return nil, fmt.Errorf("policy template %q: no-PIN payment amount cannot be negative", template.Name)
+		}
+		byName[template.Name] = template
+	}
+	for i, binding := range bindings {
+		if _, ok := byName[binding.Template]; !ok {
+			return nil, fmt.Errorf("policy binding %d: unknown template %q", i, binding.Template)
+		}
+		if len(binding.Selector) == 0 {
+			return nil, fmt.Errorf("policy binding %d: selector is required", i)
+		}
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPolicyPageSize
+	}
+	if opts.SkipStatuses == nil {
+		opts.SkipStatuses = DefaultTerminalCardStatuses
+	}
+	return &PolicyReconciler{
+		cards:     cards,
+		templates: byName,
+		bindings:  append([]PolicyBinding(nil), bindings...),
+		opts:      opts,
+	}, nil
+}
+
+// PolicyChange is the planned update of one card.
+type PolicyChange struct {
+	CardID   string
+	Template string
+	Changes  []ControlChange
+	// Update is the request Apply sends. It is nil when the card already
+	// matches its template or Err is set.
+	Update *CardUpdateRequest
+	// Err is set when the card matches bindings for more than one template.
+	// Such cards are not updated.
+	Err error
+}
+
+// PolicyPlan is the result of comparing live cards with their templates.
+type PolicyPlan struct {
+	// Changes holds one entry per bound card that needs an update or could
+	// not be planned, sorted by card ID.
+	Changes []PolicyChange
+	// InSync counts bound cards that already match their template.
+	InSync int
+	// Unbound counts cards no binding selected.
+	Unbound int
+	// Skipped counts cards left out because of their status.
+	Skipped int
+}
+
+// Updates returns the number of cards Apply would update.
+func (p *PolicyPlan) Updates() int {
+	n := 0
+	for _, change := range p.Changes {
+		if change.Update != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// String renders the plan for dry runs.
+func (p *PolicyPlan) String() string {
+	var b strings.Builder
+	for _, change := range p.Changes {
+		if change.Err != nil {
+			fmt.Fprintf(&b, "! card %s: %v\n", change.CardID, change.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "~ card %s (%s)\n", change.CardID, change.Template)
+		for _, c := range change.Changes {
+			fmt.Fprintf(&b, "    %s\n", c)
+		}
+	}
+	fmt.Fprintf(&b, "Plan: %d to update, %d in sync, %d unbound, %d skipped.\n", p.Updates(), p.InSync, p.Unbound, p.Skipped)
+	return b.String()
+}
+
+// Plan lists every card and computes the updates needed to match the bound
+// templates. Nothing is changed.
+func (r *PolicyReconciler) Plan(ctx context.Context) (*PolicyPlan, error) {
+	plan := &PolicyPlan{}
+	for page := 1; ; page++ {
+		resp, err := r.cards.List(ctx, &ListCardsRequest{PageSize: r.opts.PageSize, PageNumber: page}, r.opts.RequestOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to plan card policies: %w", err)
+		}
+		for i := range resp.Data {
+			r.planCard(plan, &resp.Data[i])
+		}
+		if page >= resp.TotalPages || len(resp.Data) == 0 {
+			break
+		}
+	}
+	sort.Slice(plan.Changes, func(i, j int) bool { return plan.Changes[i].CardID < plan.Changes[j].CardID })
+	return plan, nil
+}
+
+// PlanCard computes the update needed for a single card.
+func (r *PolicyReconciler) PlanCard(card *RetrieveCardResponse) *PolicyChange {
+	plan := &PolicyPlan{}
+	r.planCard(plan, card)
+	if len(plan.Changes) == 0 {
+		return nil
+	}
+	return &plan.Changes[0]
+}
+
+func (r *PolicyReconciler) planCard(plan *PolicyPlan, card *RetrieveCardResponse) {
+	for _, status := range r.opts.SkipStatuses {
+		if card.CardStatus == status {
+			plan.Skipped++
+			return
+		}
+	}
+	var matched []string
+	for _, binding := range r.bindings {
+		if binding.matches(card.Metadata) && !containsString(matched, binding.Template) {
+			matched = append(matched, binding.Template)
+		}
+	}
+	switch len(matched) {
+	case 0:
+		plan.Unbound++
+		return
+	case 1:
+	default:
+		plan.Changes = append(plan.Changes, PolicyChange{
+			CardID: card.CardID,
+			Err:    fmt.Errorf("card matches templates %s", strings.Join(matched, ", ")),
+		})
+		return
+	}
+
+	template := r.templates[matched[0]]
+	change := PolicyChange{CardID: card.CardID, Template: template.Name}
+	update := &CardUpdateRequest{}
+	if template.CardLimit != nil && !amountsEqual(card.CardLimit.String(), formatAmount(*template.CardLimit)) {
+		change.Changes = append(change.Changes, ControlChange{Field: "card_limit", From: card.CardLimit.String(), To: formatAmount(*template.CardLimit)})
+		update.CardLimit = template.CardLimit
+	}
+	if template.NoPINPaymentAmount != nil && !amountsEqual(card.NoPINPaymentAmount, formatAmount(*template.NoPINPaymentAmount)) {
+		change.Changes = append(change.Changes, ControlChange{Field: "no_pin_payment_amount", From: card.NoPINPaymentAmount, To: formatAmount(*template.NoPINPaymentAmount)})
+		update.NoPINPaymentAmount = template.NoPINPaymentAmount
+	}
+	current := ControlsOf(card)
+	controlChanges := DiffControls(current, template.Controls)
+	if len(controlChanges) > 0 {
+		change.Changes = append(change.Changes, controlChanges...)
+		merged := MergeControls(current, template.Controls)
+		update.SpendingControls = merged.SpendingControls
+		update.RiskControls = merged.RiskControls
+	}
+	if metadataChanges := diffMetadata(card.Metadata, template.Metadata); len(metadataChanges) > 0 {
+		change.Changes = append(change.Changes, metadataChanges...)
+		update.Metadata = make(map[string]string, len(card.Metadata)+len(template.Metadata))
+		for key, value := range card.Metadata {
+			update.Metadata[key] = value
+		}
+		for key, value := range template.Metadata {
+			update.Metadata[key] = value
+		}
+	}
+	if len(change.Changes) == 0 {
+		plan.InSync++
+		return
+	}
+	change.Update = update
+	plan.Changes = append(plan.Changes, change)
+}
+
+// Apply sends the updates in plan as a batch. Rows are keyed by card ID and a
+// hash of the planned update, so with Batch.BatchID or Batch.Checkpoint set,
+// applying the same plan again resumes it while a different plan for the
+// same cards is sent in full. Cards that could not be planned are not
+// touched.
+func (r *PolicyReconciler) Apply(ctx context.Context, plan *PolicyPlan) (*BatchReport, error) {
+	if plan == nil {
+		return nil, errors.New("policy plan is required")
+	}
+	var items []BatchItem
+	for _, change := range plan.Changes {
+		if change.Update == nil {
+			continue
+		}
+		key, err := policyRowKey(change.CardID, change.Update)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, BatchItem{Key: key, CardID: change.CardID, Update: change.Update})
+	}
+	return NewBatch(r.cards, r.opts.Batch).RunItems(ctx, BatchUpdate, items)
+}
+
+// policyRowKey returns the batch row key of update for cardID.
+func policyRowKey(cardID string, update *CardUpdateRequest) (string, error) {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash update for card %s: %w", cardID, err)
+	}
+	sum := sha256.Sum256(body)
+	return cardID + "/" + hex.EncodeToString(sum[:8]), nil
+}
+
+// MergeControls returns current with everything desired sets applied, using
+// the same rules as DiffControls: nil desired values keep the current ones
+// and an empty, non-nil MCC list clears the current codes.
+func MergeControls(current, desired Controls) Controls {
+	merged := current
+	if desired.SpendingControls != nil {
+		merged.SpendingControls = desired.SpendingControls
+	}
+	if desired.RiskControls == nil {
+		return merged
+	}
+	risk := RiskControls{}
+	if current.RiskControls != nil {
+		risk = *current.RiskControls
+	}
+	if desired.RiskControls.Allow3DSTransactions != nil {
+		risk.Allow3DSTransactions = desired.RiskControls.Allow3DSTransactions
+	}
+	if desired.RiskControls.AllowedMCC != nil {
+		risk.AllowedMCC = desired.RiskControls.AllowedMCC
+	}
+	if desired.RiskControls.BlockedMCC != nil {
+		risk.BlockedMCC = desired.RiskControls.BlockedMCC
+	}
+	merged.RiskControls = &risk
+	return merged
+}
+
+func diffMetadata(current, desired map[string]string) []ControlChange {
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var changes []ControlChange
+	for _, key := range keys {
+		if current[key] != desired[key] {
+			changes = append(changes, ControlChange{Field: "metadata." + key, From: current[key], To: desired[key]})
+		}
+	}
+	return changes
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package issuing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const policyTestCards = `[
+	{"card_id":"card-1","card_status":"ACTIVE","card_limit":"1000","no_pin_payment_amount":"50",
+	 "spending_controls":[{"amount":"200","interval":"PER_TRANSACTION"}],
+	 "risk_controls":{"allow_3ds_transactions":"Y","blocked_mcc":["7995"]},
+	 "metadata":{"department":"sales","cost_center":"cc-1"}},
+	{"card_id":"card-2","card_status":"ACTIVE","card_limit":"1000.00","no_pin_payment_amount":"50",
+	 "spending_controls":[{"amount":"500.00","interval":"PER_TRANSACTION"}],
+	 "risk_controls":{"blocked_mcc":["6051","7800","7801","7802","7995","9406"]},
+	 "metadata":{"department":"sales","policy":"sales-v2"}},
+	{"card_id":"card-3","card_status":"CANCELLED","metadata":{"department":"sales"}},
+	{"card_id":"card-4","card_status":"ACTIVE","metadata":{"department":"engineering"}},
+	{"card_id":"card-5","card_status":"ACTIVE","metadata":{"department":"sales","team":"eng"}}
+]`
+
+func TestPolicyReconcilerPlanAndApply(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		updates = map[string]CardUpdateRequest{}
+	)
+	cards := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodGet {
+			var cards []json.RawMessage
+			if err := json.Unmarshal([]byte(policyTestCards), &cards); err != nil {
+				t.Fatalf("fixture: %v", err)
+			}
+			page := cards[:3]
+			if r.URL.Query().Get("page_number") == "2" {
+				page = cards[3:]
+			}
+			body, _ := json.Marshal(map[string]interface{}{"total_pages": 2, "total_items": len(cards), "data": page})
+			_, _ = w.Write(body)
+			return
+		}
+		var req CardUpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode update: %v", err)
+		}
+		cardID := strings.TrimPrefix(r.URL.Path, "/v1/issuing/cards/")
+		mu.Lock()
+		updates[cardID] = req
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"card_id":"` + cardID + `","order_status":"SUCCESS"}`))
+	}).Cards
+
+	controls, err := NewControlsBuilder().PerTransactionLimit(500).BlockCategories(MCCCategoryGambling, MCCCategoryCrypto).Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	limit := 1000.0
+	templates := []PolicyTemplate{
+		{Name: "sales", CardLimit: &limit, Controls: controls, Metadata: map[string]string{"policy": "sales-v2"}},
+		{Name: "engineering-team", Metadata: map[string]string{"policy": "eng-v1"}},
+	}
+	bindings := []PolicyBinding{
+		{Template: "sales", Selector: map[string]string{"department": "sales"}},
+		{Template: "engineering-team", Selector: map[string]string{"team": "eng"}},
+	}
+	reconciler, err := NewPolicyReconciler(cards, templates, bindings, PolicyOptions{})
+	if err != nil {
+		t.Fatalf("NewPolicyReconciler: %v", err)
+	}
+
+	plan, err := reconciler.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if plan.InSync != 1 || plan.Unbound != 1 || plan.Skipped != 1 || len(plan.Changes) != 2 || plan.Updates() != 1 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if plan.Changes[1].CardID != "card-5" || plan.Changes[1].Err == nil {
+		t.Fatalf("ambiguous card = %+v", plan.Changes[1])
+	}
+	want := `~ card card-1 (sales)
+    spending_controls.PER_TRANSACTION: 200 -> 500
+    risk_controls.blocked_mcc: +6051,+7800,+7801,+7802,+9406
+    metadata.policy: (unset) -> sales-v2
+! card card-5: card matches templates sales, engineering-team
+Plan: 1 to update, 1 in sync, 1 unbound, 1 skipped.
+`
+	if got := plan.String(); got != want {
+		t.Fatalf("plan output:\n%s\nwant:\n%s", got, want)
+	}
+
+	report, err := reconciler.Apply(context.Background(), plan)
+	if err != nil || report.Succeeded != 1 {
+		t.Fatalf("Apply = %+v, %v", report, err)
+	}
+	update, ok := updates["card-1"]
+	if !ok || len(updates) != 1 {
+		t.Fatalf("updates = %+v", updates)
+	}
+	if update.CardLimit != nil || update.SpendingControls[0].Amount != "500" {
+		t.Fatalf("update = %+v", update)
+	}
+	if stringValue(update.RiskControls.Allow3DSTransactions) != Allow3DSYes || len(update.RiskControls.BlockedMCC) != 6 {
+		t.Fatalf("risk controls = %+v", update.RiskControls)
+	}
+	if update.Metadata["cost_center"] != "cc-1" || update.Metadata["policy"] != "sales-v2" {
+		t.Fatalf("metadata = %v", update.Metadata)
+	}
+}
+
+func TestPolicyReconcilerApplyKeysRowsByPlannedUpdate(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		keys   []string
+		limits []string
+	)
+	cards := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req CardUpdateRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		keys = append(keys, r.Header.Get("x-idempotency-key"))
+		limits = append(limits, formatAmount(*req.CardLimit))
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"card_id":"card-1","order_status":"SUCCESS"}`))
+	}).Cards
+	reconciler, err := NewPolicyReconciler(cards, nil, nil, PolicyOptions{
+		Batch: BatchOptions{BatchID: "policy", Checkpoint: t.TempDir() + "/policy.jsonl"},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicyReconciler: %v", err)
+	}
+	planFor := func(limit float64) *PolicyPlan {
+		return &PolicyPlan{Changes: []PolicyChange{{CardID: "card-1", Update: &CardUpdateRequest{CardLimit: &limit}}}}
+	}
+
+	// The second plan is sent even though card-1 completed in the first; the
+	// third repeats the first plan, which the checkpoint resumes.
+	for i, limit := range []float64{500, 800, 500} {
+		report, err := reconciler.Apply(context.Background(), planFor(limit))
+		if err != nil || report.Succeeded+report.Resumed != 1 || (report.Resumed == 1) != (i == 2) {
+			t.Fatalf("Apply(%v) = %+v, %v", limit, report, err)
+		}
+	}
+	if len(limits) != 2 || limits[0] != "500" || limits[1] != "800" || keys[0] == keys[1] {
+		t.Fatalf("limits = %v, keys = %v", limits, keys)
+	}
+}
+
+func TestNewPolicyReconcilerValidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates []PolicyTemplate
+		bindings  []PolicyBinding
+	}{
+		{"unnamed template", []PolicyTemplate{{}}, nil},
+		{"duplicate template", []PolicyTemplate{{Name: "a"}, {Name: "a"}}, nil},
+		{"conflicting controls", []PolicyTemplate{{Name: "a", Controls: Controls{RiskControls: &RiskControls{AllowedMCC: []string{"5812"}, BlockedMCC: []string{"5812"}}}}}, nil},
+		{"unknown template", []PolicyTemplate{{Name: "a"}}, []PolicyBinding{{Template: "b", Selector: map[string]string{"k": "v"}}}},
+		{"empty selector", []PolicyTemplate{{Name: "a"}}, []PolicyBinding{{Template: "a"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewPolicyReconciler(nil, test.templates, test.bindings, PolicyOptions{}); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
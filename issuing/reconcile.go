@@ -0,0 +1,337 @@
+package issuing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+)
+
+const defaultReconcilePageSize = 100
+
+// MatchField names the identifier a report row was matched on.
+type MatchField string
+
+// Identifiers tried when matching, in order.
+const (
+	MatchTransactionID         MatchField = "transaction_id"
+	MatchShortTransactionID    MatchField = "short_transaction_id"
+	MatchAuthorizationCode     MatchField = "authorization_code"
+	MatchOriginalTransactionID MatchField = "original_transaction_id"
+)
+
+// ReconcileEntry is the part of a report row that reconciliation compares.
+// SettlementRow.Entry and LedgerRow.Entry build one; other sources can fill
+// it directly.
+type ReconcileEntry struct {
+	Line                  int
+	TransactionID         string
+	ShortTransactionID    string
+	OriginalTransactionID string
+	AuthorizationCode     string
+	CardID                string
+	// Amount and Currency are compared with the transaction's billing amount
+	// and currency. Signs are ignored, as reports and the API disagree on them.
+	Amount   string
+	Currency string
+	// Fee is compared with the transaction fee. Empty is treated as zero.
+	Fee string
+	// Row is the *SettlementRow or *LedgerRow the entry came from.
+	Row interface{}
+}
+
+// Entry returns the reconciliation view of a settlement row. The billing
+// amount is used, falling back to the settlement amount.
+func (r *SettlementRow) Entry() ReconcileEntry {
+	amount, currency := r.BillingAmount, r.BillingCurrency
+	if amount == "" {
+		amount, currency = r.SettlementAmount, r.SettlementCurrency
+	}
+	return ReconcileEntry{
+		Line:                  r.Line,
+		TransactionID:         r.TransactionID,
+		ShortTransactionID:    r.ShortTransactionID,
+		OriginalTransactionID: r.OriginalTransactionID,
+		AuthorizationCode:     r.AuthorizationCode,
+		CardID:                r.CardID,
+		Amount:                amount,
+		Currency:              currency,
+		Fee:                   r.Fee,
+		Row:                   r,
+	}
+}
+
+// Entry returns the reconciliation view of a ledger row.
+func (r *LedgerRow) Entry() ReconcileEntry {
+	return ReconcileEntry{
+		Line:                  r.Line,
+		TransactionID:         r.TransactionID,
+		ShortTransactionID:    r.ShortTransactionID,
+		OriginalTransactionID: r.OriginalTransactionID,
+		AuthorizationCode:     r.AuthorizationCode,
+		CardID:                r.CardID,
+		Amount:                r.Amount,
+		Currency:              r.Currency,
+		Fee:                   r.Fee,
+		Row:                   r,
+	}
+}
+
+// ReconciledPair is a report row and the transaction it was matched to.
+type ReconciledPair struct {
+	Entry       ReconcileEntry
+	Transaction Transaction
+	MatchedBy   MatchField
+	// Detail describes the difference for mismatches.
+	Detail string
+}
+
+// Reconciliation sorts report rows and transactions into buckets.
+type Reconciliation struct {
+	// Matched rows agree with their transaction on amount, currency and fee.
+	Matched []ReconciledPair
+	// AmountMismatches disagree on amount or currency.
+	AmountMismatches []ReconciledPair
+	// FeeMismatches agree on amount but not on fee.
+	FeeMismatches []ReconciledPair
+	// UnmatchedRows are report rows no transaction was found for.
+	UnmatchedRows []ReconcileEntry
+	// UnmatchedTransactions are transactions no report row was found for.
+	// Declined transactions never settle and are not listed.
+	UnmatchedTransactions []Transaction
+}
+
+// Balanced reports whether every row and transaction matched exactly.
+func (r *Reconciliation) Balanced() bool {
+	return len(r.AmountMismatches) == 0 && len(r.FeeMismatches) == 0 &&
+		len(r.UnmatchedRows) == 0 && len(r.UnmatchedTransactions) == 0
+}
+
+// String summarises the bucket sizes.
+func (r *Reconciliation) String() string {
+	return fmt.Sprintf("%d matched, %d amount mismatches, %d fee mismatches, %d unmatched rows, %d unmatched transactions",
+		len(r.Matched), len(r.AmountMismatches), len(r.FeeMismatches), len(r.UnmatchedRows), len(r.UnmatchedTransactions))
+}
+
+// Reconcile matches report entries against transactions. Each entry is
+// matched on the first identifier that finds an unmatched transaction:
+// transaction ID, short transaction ID, authorization code and finally
+// original transaction ID. Authorization codes are short and reused across
+// cards, so they are only tried for rows that name a card and only match
+// transactions on that card. Each transaction is matched at most once.
+func Reconcile(entries []ReconcileEntry, transactions []Transaction) *Reconciliation {
+	index := map[MatchField]map[string][]int{
+		MatchTransactionID:         {},
+		MatchShortTransactionID:    {},
+		MatchAuthorizationCode:     {},
+		MatchOriginalTransactionID: {},
+	}
+	add := func(field MatchField, key string, i int) {
+		if key != "" {
+			index[field][key] = append(index[field][key], i)
+		}
+	}
+	for i, txn := range transactions {
+		add(MatchTransactionID, txn.TransactionID, i)
+		add(MatchShortTransactionID, txn.ShortTransactionID, i)
+		add(MatchAuthorizationCode, txn.AuthorizationCode, i)
+		add(MatchOriginalTransactionID, txn.OriginalTransactionID, i)
+	}
+
+	used := make([]bool, len(transactions))
+	find := func(field MatchField, key, cardID string) (int, bool) {
+		if key == "" {
+			return 0, false
+		}
+		for _, i := range index[field][key] {
+			if !used[i] && (cardID == "" || transactions[i].CardID == cardID) {
+				return i, true
+			}
+		}
+		return 0, false
+	}
+
+	result := &Reconciliation{}
+	for _, entry := range entries {
+		authorizationCode := entry.AuthorizationCode
+		if entry.CardID == "" {
+			authorizationCode = ""
+		}
+		i, field, ok := 0, MatchField(""), false
+		for _, try := range []struct {
+			field  MatchField
+			key    string
+			cardID string
+		}{
+			{MatchTransactionID, entry.TransactionID, ""},
+			{MatchShortTransactionID, entry.ShortTransactionID, ""},
+			{MatchAuthorizationCode, authorizationCode, entry.CardID},
+			{MatchOriginalTransactionID, entry.OriginalTransactionID, ""},
+		} {
+			if i, ok = find(try.field, try.key, try.cardID); ok {
+				field = try.field
+				break
+			}
+		}
+		if !ok && entry.OriginalTransactionID != "" {
+			// A clearing row may name the authorisation it settles.
+			if i, ok = find(MatchTransactionID, entry.OriginalTransactionID, ""); ok {
+				field = MatchOriginalTransactionID
+			}
+		}
+		if !ok {
+			result.UnmatchedRows = append(result.UnmatchedRows, entry)
+			continue
+		}
+		used[i] = true
+
+		pair := ReconciledPair{Entry: entry, Transaction: transactions[i], MatchedBy: field}
+		txn := transactions[i]
+		switch {
+		case !reportAmountsEqual(entry.Amount, txn.BillingAmount):
+			pair.Detail = fmt.Sprintf("amount %s, transaction %s", entry.Amount, txn.BillingAmount)
+			result.AmountMismatches = append(result.AmountMismatches, pair)
+		case entry.Currency != "" && txn.BillingCurrency != "" && !strings.EqualFold(entry.Currency, txn.BillingCurrency):
+			pair.Detail = fmt.Sprintf("currency %s, transaction %s", entry.Currency, txn.BillingCurrency)
+			result.AmountMismatches = append(result.AmountMismatches, pair)
+		case !reportAmountsEqual(zeroIfEmpty(entry.Fee), zeroIfEmpty(txn.TransactionFee)):
+			pair.Detail = fmt.Sprintf("fee %s, transaction %s", zeroIfEmpty(entry.Fee), zeroIfEmpty(txn.TransactionFee))
+			result.FeeMismatches = append(result.FeeMismatches, pair)
+		default:
+			result.Matched = append(result.Matched, pair)
+		}
+	}
+
+	for i, txn := range transactions {
+		if !used[i] && txn.TransactionStatus != "DECLINED" {
+			result.UnmatchedTransactions = append(result.UnmatchedTransactions, txn)
+		}
+	}
+	return result
+}
+
+// reportAmountsEqual compares amounts ignoring sign and thousands separators.
+func reportAmountsEqual(a, b string) bool {
+	normalize := func(amount string) string {
+		amount = strings.ReplaceAll(strings.TrimSpace(amount), ",", "")
+		return strings.TrimLeft(amount, "+-")
+	}
+	return amountsEqual(normalize(a), normalize(b))
+}
+
+func zeroIfEmpty(amount string) string {
+	if strings.TrimSpace(amount) == "" {
+		return "0"
+	}
+	return amount
+}
+
+// WaitForReport polls Download until the report is ready and returns its
+// content. Not-found responses and empty bodies are treated as the report
+// still being generated, as are transient errors. The zero Backoff uses
+// DefaultBackoff; bound the wait with ctx.
+func (c *DownloadCenterClient) WaitForReport(ctx context.Context, reportID string, backoff Backoff, opts ...*common.RequestOptions) (*DownloadReportResponse, error) {
+	if backoff == (Backoff{}) {
+		backoff = DefaultBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.Download(ctx, reportID, opts...)
+		if err == nil && len(resp.Data) > 0 {
+			return resp, nil
+		}
+		if err != nil && !reportPending(err) {
+			return nil, err
+		}
+		timer := time.NewTimer(backoff.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to wait for report %s: %w", reportID, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func reportPending(err error) bool {
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusNotFound || apiErr.StatusCode == http.StatusConflict) {
+		return true
+	}
+	return retryable(err)
+}
+
+// ReconcilerOptions configures a Reconciler.
+type ReconcilerOptions struct {
+	// Backoff is the schedule used while waiting for the report. The zero
+	// value uses DefaultBackoff.
+	Backoff Backoff
+	// PageSize is the transaction List page size. Zero uses 100.
+	PageSize int
+	// RequestOptions is passed to every API call, e.g. OnBehalfOf.
+	RequestOptions *common.RequestOptions
+}
+
+// Reconciler reconciles card transactions against a settlement or ledger
+// report for a period.
+type Reconciler struct {
+	client *Client
+	opts   ReconcilerOptions
+}
+
+// NewReconciler creates a reconciler.
+func NewReconciler(client *Client, opts ReconcilerOptions) *Reconciler {
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultReconcilePageSize
+	}
+	return &Reconciler{client: client, opts: opts}
+}
+
+// Run requests a report of reportType (ReportTypeSettlement or
+// ReportTypeLedger) for [start, end), waits for it, parses it and reconciles
+// it against the transactions listed for the same period.
+func (r *Reconciler) Run(ctx context.Context, reportType string, start, end time.Time) (*Reconciliation, error) {
+	reportOpts := ReportOptions{Backoff: r.opts.Backoff, RequestOptions: r.opts.RequestOptions}
+	var entries []ReconcileEntry
+	switch reportType {
+	case ReportTypeSettlement:
+		rows, err := r.client.SettlementReport(ctx, start, end, reportOpts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			entries = append(entries, rows[i].Entry())
+		}
+	case ReportTypeLedger:
+		rows, err := r.client.LedgerReport(ctx, start, end, reportOpts)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rows {
+			entries = append(entries, rows[i].Entry())
+		}
+	default:
+		return nil, fmt.Errorf("unsupported report type %q", reportType)
+	}
+
+	var transactions []Transaction
+	for page := 1; ; page++ {
+		resp, err := r.client.Transactions.List(ctx, &ListTransactionsRequest{
+			PageSize:   r.opts.PageSize,
+			PageNumber: page,
+			StartTime:  start.Format(time.RFC3339),
+			EndTime:    end.Format(time.RFC3339),
+		}, r.opts.RequestOptions)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, resp.Data...)
+		if page >= resp.TotalPages || len(resp.Data) == 0 {
+			break
+		}
+	}
+	return Reconcile(entries, transactions), nil
+}
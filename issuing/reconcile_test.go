@@ -0,0 +1,145 @@
+package issuing
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const settlementCSV = "\ufeffShort Transaction ID,Authorization Code,Original Transaction ID,Card ID,Billing Amount,Billing Currency,Transaction Fee,Some New Column\n" +
+	"S1,,,card-1,-10.00,USD,0.10,x\n" +
+	"S2,,,card-1,20,USD,0,x\n" +
+	",A3,,card-2,\"1,000.00\",USD,0.50,x\n" +
+	",,T4,card-2,5,USD,0,x\n" +
+	"\n" +
+	"S9,,,card-9,1,USD,0,x\n"
+
+func TestParseSettlementReportMapsHeaders(t *testing.T) {
+	rows, err := ParseSettlementReport([]byte(settlementCSV))
+	if err != nil {
+		t.Fatalf("ParseSettlementReport: %v", err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows, want 5", len(rows))
+	}
+	if rows[0].ShortTransactionID != "S1" || rows[0].BillingAmount != "-10.00" || rows[0].Fee != "0.10" || rows[0].Line != 2 {
+		t.Errorf("first row = %+v", rows[0])
+	}
+	if rows[2].BillingAmount != "1,000.00" || rows[4].Line != 7 {
+		t.Errorf("rows = %+v", rows)
+	}
+
+	if _, err := ParseSettlementReport([]byte("foo,bar\n1,2\n")); err == nil {
+		t.Error("expected an error for a header with no known columns")
+	}
+	if _, err := ParseLedgerReport(nil); err == nil {
+		t.Error("expected an error for an empty report")
+	}
+}
+
+func TestReconcileBuckets(t *testing.T) {
+	rows, err := ParseSettlementReport([]byte(settlementCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []ReconcileEntry
+	for i := range rows {
+		entries = append(entries, rows[i].Entry())
+	}
+	transactions := []Transaction{
+		{TransactionID: "T1", ShortTransactionID: "S1", CardID: "card-1", BillingAmount: "10", BillingCurrency: "USD", TransactionFee: "0.1", TransactionStatus: "APPROVED"},
+		{TransactionID: "T2", ShortTransactionID: "S2", CardID: "card-1", BillingAmount: "25", BillingCurrency: "USD", TransactionStatus: "APPROVED"},
+		{TransactionID: "T3", AuthorizationCode: "A3", CardID: "card-2", BillingAmount: "1000", BillingCurrency: "USD", TransactionFee: "0.25", TransactionStatus: "APPROVED"},
+		{TransactionID: "T4", CardID: "card-2", BillingAmount: "5", BillingCurrency: "USD", TransactionStatus: "APPROVED"},
+		{TransactionID: "T5", CardID: "card-3", BillingAmount: "7", TransactionStatus: "PENDING"},
+		{TransactionID: "T6", CardID: "card-3", BillingAmount: "8", TransactionStatus: "DECLINED"},
+	}
+
+	result := Reconcile(entries, transactions)
+	if len(result.Matched) != 2 || result.Matched[0].Transaction.TransactionID != "T1" || result.Matched[0].MatchedBy != MatchShortTransactionID {
+		t.Errorf("matched = %+v", result.Matched)
+	}
+	if result.Matched[1].Transaction.TransactionID != "T4" || result.Matched[1].MatchedBy != MatchOriginalTransactionID {
+		t.Errorf("matched by original = %+v", result.Matched[1])
+	}
+	if len(result.AmountMismatches) != 1 || result.AmountMismatches[0].Transaction.TransactionID != "T2" {
+		t.Errorf("amount mismatches = %+v", result.AmountMismatches)
+	}
+	if len(result.FeeMismatches) != 1 || result.FeeMismatches[0].MatchedBy != MatchAuthorizationCode {
+		t.Errorf("fee mismatches = %+v", result.FeeMismatches)
+	}
+	if len(result.UnmatchedRows) != 1 || result.UnmatchedRows[0].ShortTransactionID != "S9" {
+		t.Errorf("unmatched rows = %+v", result.UnmatchedRows)
+	}
+	if len(result.UnmatchedTransactions) != 1 || result.UnmatchedTransactions[0].TransactionID != "T5" {
+		t.Errorf("unmatched transactions = %+v", result.UnmatchedTransactions)
+	}
+	if result.Balanced() {
+		t.Error("Balanced() = true")
+	}
+}
+
+func TestReconcileMatchesAuthorizationCodesOnlyOnTheRowsCard(t *testing.T) {
+	transactions := []Transaction{
+		{TransactionID: "T1", AuthorizationCode: "123456", CardID: "card-1", BillingAmount: "10", TransactionStatus: "APPROVED"},
+	}
+	entries := []ReconcileEntry{
+		{AuthorizationCode: "123456", Amount: "10"},
+		{AuthorizationCode: "123456", CardID: "card-2", Amount: "10"},
+	}
+	result := Reconcile(entries, transactions)
+	if len(result.Matched) != 0 || len(result.UnmatchedRows) != 2 || len(result.UnmatchedTransactions) != 1 {
+		t.Fatalf("result = %+v", result)
+	}
+
+	entries = append(entries, ReconcileEntry{AuthorizationCode: "123456", CardID: "card-1", Amount: "10"})
+	result = Reconcile(entries, transactions)
+	if len(result.Matched) != 1 || result.Matched[0].Entry.CardID != "card-1" || result.Matched[0].MatchedBy != MatchAuthorizationCode {
+		t.Fatalf("matched = %+v", result.Matched)
+	}
+}
+
+func TestReconcilerRunWaitsForLedgerReport(t *testing.T) {
+	var downloads int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/issuing/reports":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"report_id":"rep-1"}`))
+		case r.URL.Path == "/v1/issuing/reports/rep-1":
+			if atomic.AddInt32(&downloads, 1) < 3 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"type":"not_found","message":"report is being generated"}`))
+				return
+			}
+			_, _ = w.Write([]byte("id,transaction_id,amount,currency,fee\nL1,T1,-10,USD,\n"))
+		case r.URL.Path == "/v1/issuing/transactions":
+			if r.URL.Query().Get("start_time") != "2024-03-21T00:00:00Z" {
+				t.Errorf("start_time = %q", r.URL.Query().Get("start_time"))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"total_pages":1,"data":[{"transaction_id":"T1","billing_amount":"10","billing_currency":"USD","transaction_status":"APPROVED"}]}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	start := time.Date(2024, 3, 21, 0, 0, 0, 0, time.UTC)
+	result, err := NewReconciler(client, ReconcilerOptions{Backoff: fastBackoff}).Run(context.Background(), ReportTypeLedger, start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !result.Balanced() || len(result.Matched) != 1 {
+		t.Errorf("result = %s", result)
+	}
+	if row, ok := result.Matched[0].Entry.Row.(*LedgerRow); !ok || row.EntryID != "L1" {
+		t.Errorf("row = %#v", result.Matched[0].Entry.Row)
+	}
+	if n := atomic.LoadInt32(&downloads); n != 3 {
+		t.Errorf("downloads = %d, want 3", n)
+	}
+}
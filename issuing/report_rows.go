@@ -0,0 +1,380 @@
+package issuing
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+)
+
+// Report types accepted by ReportsClient.Create.
+const (
+	ReportTypeSettlement = "SETTLEMENT"
+	ReportTypeLedger     = "LEDGER"
+)
+
+// SettlementRow is one row of a SETTLEMENT report. Columns are matched by
+// header name, case and punctuation insensitive, against the names in the
+// report tag; unknown columns are ignored and missing ones left empty.
+type SettlementRow struct {
+	// Line is the row's line number in the file, counting the header as 1.
+	Line int `json:"line"`
+
+	TransactionID         string `json:"transaction_id" report:"transaction_id,txn_id"`
+	ShortTransactionID    string `json:"short_transaction_id" report:"short_transaction_id,short_txn_id"`
+	OriginalTransactionID string `json:"original_transaction_id" report:"original_transaction_id,original_txn_id"`
+	AuthorizationCode     string `json:"authorization_code" report:"authorization_code,auth_code,approval_code"`
+	CardID                string `json:"card_id" report:"card_id"`
+	CardNumber            string `json:"card_number" report:"card_number,masked_card_number,pan"`
+	TransactionType       string `json:"transaction_type" report:"transaction_type,type"`
+	TransactionTime       string `json:"transaction_time" report:"transaction_time,transaction_date"`
+	SettlementTime        string `json:"settlement_time" report:"settlement_time,settlement_date,posted_time"`
+	TransactionAmount     string `json:"transaction_amount" report:"transaction_amount"`
+	TransactionCurrency   string `json:"transaction_currency" report:"transaction_currency"`
+	BillingAmount         string `json:"billing_amount" report:"billing_amount"`
+	BillingCurrency       string `json:"billing_currency" report:"billing_currency"`
+	SettlementAmount      string `json:"settlement_amount" report:"settlement_amount"`
+	SettlementCurrency    string `json:"settlement_currency" report:"settlement_currency"`
+	Fee                   string `json:"fee" report:"transaction_fee,fee,fee_amount"`
+	FeeCurrency           string `json:"fee_currency" report:"transaction_fee_currency,fee_currency"`
+	MerchantName          string `json:"merchant_name" report:"merchant_name"`
+	MerchantCategoryCode  string `json:"merchant_category_code" report:"merchant_category_code,mcc"`
+}
+
+// LedgerRow is one row of a LEDGER report, matched to columns like
+// SettlementRow.
+type LedgerRow struct {
+	// Line is the row's line number in the file, counting the header as 1.
+	Line int `json:"line"`
+
+	EntryID               string `json:"entry_id" report:"ledger_id,entry_id,id"`
+	TransactionID         string `json:"transaction_id" report:"transaction_id,txn_id"`
+	ShortTransactionID    string `json:"short_transaction_id" report:"short_transaction_id,short_txn_id"`
+	OriginalTransactionID string `json:"original_transaction_id" report:"original_transaction_id,original_txn_id"`
+	AuthorizationCode     string `json:"authorization_code" report:"authorization_code,auth_code,approval_code"`
+	CardID                string `json:"card_id" report:"card_id"`
+	EntryType             string `json:"entry_type" report:"entry_type,direction,debit_credit"`
+	TransactionType       string `json:"transaction_type" report:"transaction_type,type"`
+	Amount                string `json:"amount" report:"amount,transaction_amount"`
+	Currency              string `json:"currency" report:"currency,transaction_currency"`
+	Fee                   string `json:"fee" report:"transaction_fee,fee,fee_amount"`
+	FeeCurrency           string `json:"fee_currency" report:"transaction_fee_currency,fee_currency"`
+	BalanceAfter          string `json:"balance_after" report:"balance_after,balance,available_balance"`
+	CreateTime            string `json:"create_time" report:"create_time,transaction_time,created_at"`
+	Description           string `json:"description" report:"description,remark"`
+}
+
+// ReportRow is the set of typed report rows: SettlementRow for
+// ReportTypeSettlement and LedgerRow for ReportTypeLedger.
+type ReportRow interface {
+	SettlementRow | LedgerRow
+}
+
+// ErrUnsupportedReportFormat is returned for report files that are neither
+// CSV nor a gzip or zip archive of CSV, such as XLSX workbooks.
+var ErrUnsupportedReportFormat = errors.New("unsupported report format")
+
+// ReportReader streams typed rows from a report file. Plain CSV, gzip and zip
+// archives holding a CSV file are detected from the content. Zip archives are
+// read into memory, as the format requires random access.
+type ReportReader[T ReportRow] struct {
+	csv     *csv.Reader
+	columns [][]int
+	line    []int
+}
+
+// NewReportReader reads the header of the report in r.
+func NewReportReader[T ReportRow](r io.Reader) (*ReportReader[T], error) {
+	content, err := reportContent(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(content)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("report is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	rowType := reflect.TypeOf((*T)(nil)).Elem()
+	columns, err := reportColumns(rowType, header)
+	if err != nil {
+		return nil, err
+	}
+	lineField, _ := rowType.FieldByName("Line")
+	return &ReportReader[T]{csv: reader, columns: columns, line: lineField.Index}, nil
+}
+
+// Read returns the next row, or io.EOF after the last one. Blank lines are
+// skipped.
+func (r *ReportReader[T]) Read() (*T, error) {
+	for {
+		record, err := r.csv.Read()
+		if err != nil {
+			return nil, err
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		var row T
+		value := reflect.ValueOf(&row).Elem()
+		line, _ := r.csv.FieldPos(0)
+		value.FieldByIndex(r.line).SetInt(int64(line))
+		for i, field := range record {
+			if i < len(r.columns) && r.columns[i] != nil {
+				value.FieldByIndex(r.columns[i]).SetString(strings.TrimSpace(field))
+			}
+		}
+		return &row, nil
+	}
+}
+
+// ReadAll returns the remaining rows.
+func (r *ReportReader[T]) ReadAll() ([]T, error) {
+	var rows []T
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, *row)
+	}
+}
+
+// WriteJSONL streams the remaining rows to w as JSON Lines and returns the
+// number of rows written.
+func (r *ReportReader[T]) WriteJSONL(w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	n := 0
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := enc.Encode(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// WriteReportJSONL writes rows to w as JSON Lines.
+func WriteReportJSONL[T ReportRow](w io.Writer, rows []T) error {
+	enc := json.NewEncoder(w)
+	for i := range rows {
+		if err := enc.Encode(&rows[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSettlementReport parses a SETTLEMENT report held in memory.
+func ParseSettlementReport(data []byte) ([]SettlementRow, error) {
+	rows, err := parseReport[SettlementRow](data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse settlement report: %w", err)
+	}
+	return rows, nil
+}
+
+// ParseLedgerReport parses a LEDGER report held in memory.
+func ParseLedgerReport(data []byte) ([]LedgerRow, error) {
+	rows, err := parseReport[LedgerRow](data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ledger report: %w", err)
+	}
+	return rows, nil
+}
+
+// ReportOptions configures Client.FetchReport and the typed report helpers.
+type ReportOptions struct {
+	// Backoff is the schedule used while waiting for the report. The zero
+	// value uses DefaultBackoff.
+	Backoff Backoff
+	// RequestOptions is passed to every API call, e.g. OnBehalfOf.
+	RequestOptions *common.RequestOptions
+}
+
+// FetchReport creates a report of reportType for [start, end), waits until it
+// is ready and downloads it. Bound the wait with ctx.
+func (c *Client) FetchReport(ctx context.Context, reportType string, start, end time.Time, opts ReportOptions) (*DownloadReportResponse, error) {
+	if reportType != ReportTypeSettlement && reportType != ReportTypeLedger {
+		return nil, fmt.Errorf("unsupported report type %q", reportType)
+	}
+	if !start.Before(end) {
+		return nil, errors.New("report start must be before end")
+	}
+	report, err := c.Reports.Create(ctx, &CreateReportRequest{
+		ReportType: reportType,
+		StartTime:  start.Format(time.RFC3339),
+		EndTime:    end.Format(time.RFC3339),
+	}, opts.RequestOptions)
+	if err != nil {
+		return nil, err
+	}
+	return c.DownloadCenter.WaitForReport(ctx, report.ReportID, opts.Backoff, opts.RequestOptions)
+}
+
+// SettlementReport fetches and parses the SETTLEMENT report for [start, end).
+func (c *Client) SettlementReport(ctx context.Context, start, end time.Time, opts ReportOptions) ([]SettlementRow, error) {
+	download, err := c.FetchReport(ctx, ReportTypeSettlement, start, end, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSettlementReport(download.Data)
+}
+
+// LedgerReport fetches and parses the LEDGER report for [start, end).
+func (c *Client) LedgerReport(ctx context.Context, start, end time.Time, opts ReportOptions) ([]LedgerRow, error) {
+	download, err := c.FetchReport(ctx, ReportTypeLedger, start, end, opts)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLedgerReport(download.Data)
+}
+
+func parseReport[T ReportRow](data []byte) ([]T, error) {
+	reader, err := NewReportReader[T](bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return reader.ReadAll()
+}
+
+// reportContent returns the CSV content of a report file, decompressing
+// gzip and zip archives.
+func reportContent(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return gzip.NewReader(buffered)
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		data, err := io.ReadAll(buffered)
+		if err != nil {
+			return nil, err
+		}
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		return zipReportContent(archive)
+	}
+	return buffered, nil
+}
+
+// zipReportContent opens the CSV file in a zip archive: the first file named
+// *.csv, or the only file.
+func zipReportContent(archive *zip.Reader) (io.Reader, error) {
+	var files []*zip.File
+	for _, file := range archive.File {
+		if file.Name == "[Content_Types].xml" {
+			return nil, fmt.Errorf("%w: XLSX workbooks cannot be parsed, request the report as CSV", ErrUnsupportedReportFormat)
+		}
+		if !file.FileInfo().IsDir() {
+			files = append(files, file)
+		}
+	}
+	for _, file := range files {
+		if strings.EqualFold(path.Ext(file.Name), ".csv") {
+			return file.Open()
+		}
+	}
+	if len(files) == 1 {
+		return files[0].Open()
+	}
+	return nil, fmt.Errorf("%w: zip archive holds %d files and no CSV", ErrUnsupportedReportFormat, len(files))
+}
+
+// reportColumns maps each header column to the index of the row field it
+// fills, or nil for columns the row type does not know.
+func reportColumns(rowType reflect.Type, header []string) ([][]int, error) {
+	fields := map[string][]int{}
+	for i := 0; i < rowType.NumField(); i++ {
+		field := rowType.Field(i)
+		tag := field.Tag.Get("report")
+		if tag == "" {
+			continue
+		}
+		for _, name := range strings.Split(tag, ",") {
+			fields[name] = field.Index
+		}
+	}
+
+	columns := make([][]int, len(header))
+	seen := map[string]bool{}
+	known := 0
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		index, ok := fields[normalizeReportHeader(name)]
+		if !ok {
+			continue
+		}
+		key := fmt.Sprint(index)
+		if seen[key] {
+			// The first of several alias columns wins.
+			continue
+		}
+		seen[key] = true
+		columns[i] = index
+		known++
+	}
+	if known == 0 {
+		return nil, fmt.Errorf("no known columns in header %q", strings.Join(header, ","))
+	}
+	return columns, nil
+}
+
+// normalizeReportHeader turns "Short Transaction ID" and "short-transaction-id"
+// into "short_transaction_id".
+func normalizeReportHeader(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			underscore = false
+			b.WriteRune(r)
+			continue
+		}
+		underscore = true
+	}
+	return b.String()
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
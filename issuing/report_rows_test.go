@@ -0,0 +1,144 @@
+package issuing
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ledgerCSV = "Entry ID,Transaction ID,Amount,Currency,Balance After,Added Later\n" +
+	"L1,T1,-10.00,USD,90.00,x\n" +
+	"L2,T2,25.00,USD,115.00,y\n"
+
+func zipReport(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReportReaderDetectsCompression(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write([]byte(ledgerCSV))
+	_ = w.Close()
+
+	for name, data := range map[string][]byte{
+		"csv":  []byte(ledgerCSV),
+		"gzip": gz.Bytes(),
+		"zip":  zipReport(t, map[string]string{"readme.txt": "ignore", "ledger.csv": ledgerCSV}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			reader, err := NewReportReader[LedgerRow](bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("NewReportReader: %v", err)
+			}
+			first, err := reader.Read()
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if first.EntryID != "L1" || first.Amount != "-10.00" || first.BalanceAfter != "90.00" || first.Line != 2 {
+				t.Errorf("first row = %+v", first)
+			}
+			rest, err := reader.ReadAll()
+			if err != nil || len(rest) != 1 || rest[0].TransactionID != "T2" {
+				t.Errorf("ReadAll = %+v, %v", rest, err)
+			}
+			if _, err := reader.Read(); err != io.EOF {
+				t.Errorf("Read after end = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestReportReaderRejectsXLSX(t *testing.T) {
+	data := zipReport(t, map[string]string{"[Content_Types].xml": "<Types/>", "xl/workbook.xml": "<workbook/>"})
+	if _, err := NewReportReader[SettlementRow](bytes.NewReader(data)); !errors.Is(err, ErrUnsupportedReportFormat) {
+		t.Errorf("err = %v, want ErrUnsupportedReportFormat", err)
+	}
+}
+
+func TestReportJSONL(t *testing.T) {
+	reader, err := NewReportReader[LedgerRow](strings.NewReader(ledgerCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed bytes.Buffer
+	n, err := reader.WriteJSONL(&streamed)
+	if err != nil || n != 2 {
+		t.Fatalf("WriteJSONL = %d, %v", n, err)
+	}
+
+	rows, err := ParseLedgerReport([]byte(ledgerCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written bytes.Buffer
+	if err := WriteReportJSONL(&written, rows); err != nil {
+		t.Fatal(err)
+	}
+	if streamed.String() != written.String() {
+		t.Errorf("streamed %q, written %q", streamed.String(), written.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(written.String()), "\n")
+	var row LedgerRow
+	if err := json.Unmarshal([]byte(lines[1]), &row); err != nil || row.EntryID != "L2" || row.Line != 3 {
+		t.Errorf("line = %s, row = %+v, err = %v", lines[1], row, err)
+	}
+}
+
+func TestClientSettlementReport(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	_, _ = w.Write([]byte("transaction_id,billing_amount\nT1,10\n"))
+	_ = w.Close()
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/issuing/reports":
+			var req CreateReportRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if req.ReportType != ReportTypeSettlement || req.StartTime != "2024-03-21T00:00:00+08:00" {
+				t.Errorf("request = %+v", req)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"report_id":"rep-1"}`))
+		case "/v1/issuing/reports/rep-1":
+			_, _ = w.Write(gz.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	start := time.Date(2024, 3, 21, 0, 0, 0, 0, time.FixedZone("SGT", 8*3600))
+	rows, err := client.SettlementReport(context.Background(), start, start.AddDate(0, 0, 1), ReportOptions{Backoff: fastBackoff})
+	if err != nil {
+		t.Fatalf("SettlementReport: %v", err)
+	}
+	if len(rows) != 1 || rows[0].TransactionID != "T1" || rows[0].BillingAmount != "10" {
+		t.Errorf("rows = %+v", rows)
+	}
+
+	if _, err := client.FetchReport(context.Background(), "BALANCE", start, start.AddDate(0, 0, 1), ReportOptions{}); err == nil {
+		t.Error("expected an error for an unsupported report type")
+	}
+}
@@ -20,6 +20,19 @@ func (p *staticTokenProvider) GetToken() (string, error) {
 	return p.token, nil
 }
 
+// newTestClient returns a Client whose requests are served by handler. The
+// test server is closed when the test ends.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	config := &configuration.Configuration{
+		Environment: &configuration.Environment{BaseURL: server.URL},
+		HTTPClient:  server.Client(),
+	}
+	return NewClient(common.NewAPIClient(config, &staticTokenProvider{token: "token"}))
+}
+
 type capturedRequest struct {
 	method string
 	path   string
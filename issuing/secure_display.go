@@ -0,0 +1,294 @@
+package issuing
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+)
+
+// Placeholders replaced in SecureDisplayOptions.BaseURL.
+const (
+	SecureDisplayTokenPlaceholder    = "{token}"
+	SecureDisplayCardIDPlaceholder   = "{card_id}"
+	SecureDisplayLanguagePlaceholder = "{language}"
+)
+
+const (
+	// defaultPANTokenLifetime is used when a token response carries no expiry.
+	defaultPANTokenLifetime = 60 * time.Second
+	defaultExpiryMargin     = 5 * time.Second
+)
+
+// ErrPANTokenExpired is returned when a PAN token has expired, or would expire
+// within the configured margin, before it can be used.
+var ErrPANTokenExpired = errors.New("PAN token expired")
+
+// ErrInvalidEmbedSignature is returned by VerifyEmbed for embeds that were not
+// signed with the display's signing key or were modified.
+var ErrInvalidEmbedSignature = errors.New("invalid card embed signature")
+
+// SecureDisplayOptions configures a SecureDisplay.
+type SecureDisplayOptions struct {
+	// BaseURL is the hosted display page URL from your program
+	// configuration, as an absolute https URL template. It must contain
+	// {token}, and may contain {card_id} and {language}; each is replaced
+	// with the query-escaped value, e.g.
+	// "https://display.example.com/card?token={token}&lang={language}".
+	// It is required: the host and parameters differ by program.
+	BaseURL string
+	// Language replaces {language} in BaseURL, e.g. "en".
+	Language string
+	// SigningKey signs embeds with HMAC-SHA256 so a frontend proxy or a later
+	// request can check with VerifyEmbed that an embed was issued by this
+	// backend. It only protects embeds that round-trip through your own
+	// backend; the display page does not check it. Empty leaves embeds
+	// unsigned.
+	SigningKey []byte
+	// ExpiryMargin treats tokens as expired this long before their expiry, to
+	// leave time for the browser to load the page. Zero uses 5 seconds.
+	ExpiryMargin time.Duration
+	// RequestOptions is passed to every API call, e.g. OnBehalfOf.
+	RequestOptions *common.RequestOptions
+	// Now returns the current time. Nil uses time.Now.
+	Now func() time.Time
+}
+
+// SecureDisplay mints PAN tokens and turns them into embeds for the hosted
+// card-detail display, so card numbers and CVVs are shown to cardholders
+// without passing through the caller's servers.
+type SecureDisplay struct {
+	cards *CardsClient
+	opts  SecureDisplayOptions
+}
+
+// NewSecureDisplay creates a secure display helper. opts.BaseURL is required.
+func NewSecureDisplay(cards *CardsClient, opts SecureDisplayOptions) (*SecureDisplay, error) {
+	if opts.BaseURL == "" {
+		return nil, errors.New("secure display base URL is required")
+	}
+	base, err := url.Parse(opts.BaseURL)
+	if err != nil || base.Scheme != "https" || base.Host == "" {
+		return nil, fmt.Errorf("secure display base URL must be an absolute https URL, got %q", opts.BaseURL)
+	}
+	if !strings.Contains(opts.BaseURL, SecureDisplayTokenPlaceholder) {
+		return nil, fmt.Errorf("secure display base URL must contain %s, got %q", SecureDisplayTokenPlaceholder, opts.BaseURL)
+	}
+	if opts.ExpiryMargin <= 0 {
+		opts.ExpiryMargin = defaultExpiryMargin
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &SecureDisplay{cards: cards, opts: opts}, nil
+}
+
+// CardEmbed is what a frontend needs to show a card's details in the hosted
+// display. It is safe to send to the cardholder's browser: the token only
+// grants one view of one card and expires within a minute.
+type CardEmbed struct {
+	CardID    string    `json:"card_id"`
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Signature is the base64url HMAC-SHA256 of the other fields, set when
+	// the display has a signing key. Only VerifyEmbed checks it, so it only
+	// protects embeds sent back to your own backend.
+	Signature string `json:"signature,omitempty"`
+}
+
+// Expired reports whether the embed's token has expired at now.
+func (e *CardEmbed) Expired(now time.Time) bool {
+	return !now.Before(e.ExpiresAt)
+}
+
+// Embed mints a PAN token for cardID and returns the embed for it. The token
+// is checked before it is handed out, so a token that is already expired is
+// reported as ErrPANTokenExpired instead of failing in the browser.
+func (d *SecureDisplay) Embed(ctx context.Context, cardID string) (*CardEmbed, error) {
+	if cardID == "" {
+		return nil, errors.New("card ID is required")
+	}
+	token, err := d.cards.CreatePANToken(ctx, cardID, d.opts.RequestOptions)
+	if err != nil {
+		return nil, err
+	}
+	return d.EmbedToken(cardID, token)
+}
+
+// EmbedToken builds the embed for a PAN token minted elsewhere.
+func (d *SecureDisplay) EmbedToken(cardID string, token *PANTokenResponse) (*CardEmbed, error) {
+	if token == nil || token.Token == "" {
+		return nil, errors.New("PAN token is empty")
+	}
+	now := d.opts.Now()
+	expiresAt, err := panTokenExpiry(token, now)
+	if err != nil {
+		return nil, err
+	}
+	if !now.Add(d.opts.ExpiryMargin).Before(expiresAt) {
+		return nil, fmt.Errorf("%w at %s", ErrPANTokenExpired, expiresAt.Format(time.RFC3339))
+	}
+
+	embedURL := strings.NewReplacer(
+		SecureDisplayTokenPlaceholder, url.QueryEscape(token.Token),
+		SecureDisplayCardIDPlaceholder, url.QueryEscape(cardID),
+		SecureDisplayLanguagePlaceholder, url.QueryEscape(d.opts.Language),
+	).Replace(d.opts.BaseURL)
+
+	embed := &CardEmbed{CardID: cardID, Token: token.Token, URL: embedURL, ExpiresAt: expiresAt.UTC()}
+	if len(d.opts.SigningKey) > 0 {
+		embed.Signature = d.sign(embed)
+	}
+	return embed, nil
+}
+
+// VerifyEmbed checks the signature and expiry of an embed, e.g. one posted
+// back by a frontend. It fails for every embed when no signing key is set.
+// The signature says nothing about what the browser loads from the display
+// page; it only shows that this backend issued the embed.
+func (d *SecureDisplay) VerifyEmbed(embed *CardEmbed) error {
+	if len(d.opts.SigningKey) == 0 {
+		return errors.New("secure display has no signing key")
+	}
+	if embed == nil || !hmac.Equal([]byte(embed.Signature), []byte(d.sign(embed))) {
+		return ErrInvalidEmbedSignature
+	}
+	if embed.Expired(d.opts.Now()) {
+		return ErrPANTokenExpired
+	}
+	return nil
+}
+
+func (d *SecureDisplay) sign(embed *CardEmbed) string {
+	mac := hmac.New(sha256.New, d.opts.SigningKey)
+	for _, part := range []string{embed.CardID, embed.Token, embed.URL, strconv.FormatInt(embed.ExpiresAt.Unix(), 10)} {
+		mac.Write([]byte(part))
+		mac.Write([]byte{0})
+	}
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// panTokenExpiry returns when token expires, from ExpiresAt, then ExpiresIn,
+// then the documented 60 second lifetime.
+func panTokenExpiry(token *PANTokenResponse, now time.Time) (time.Time, error) {
+	if token.ExpiresAt != "" {
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02T15:04:05"} {
+			if t, err := time.Parse(layout, token.ExpiresAt); err == nil {
+				return t, nil
+			}
+		}
+		if seconds, err := strconv.ParseInt(token.ExpiresAt, 10, 64); err == nil {
+			return time.Unix(seconds, 0), nil
+		}
+		return time.Time{}, fmt.Errorf("invalid PAN token expiry %q", token.ExpiresAt)
+	}
+	if token.ExpiresIn > 0 {
+		return now.Add(time.Duration(token.ExpiresIn) * time.Second), nil
+	}
+	return now.Add(defaultPANTokenLifetime), nil
+}
+
+// SensitiveCardInfo holds a card's number, CVV and expiry date in byte slices
+// that Wipe overwrites. Formatting it with fmt prints a redacted value.
+type SensitiveCardInfo struct {
+	CardNumber []byte
+	CVV        []byte
+	ExpireDate []byte
+}
+
+// Wipe overwrites the card details with zeros.
+func (s *SensitiveCardInfo) Wipe() {
+	for _, b := range [][]byte{s.CardNumber, s.CVV, s.ExpireDate} {
+		for i := range b {
+			b[i] = 0
+		}
+	}
+}
+
+// LastFour returns the last four digits of the card number.
+func (s *SensitiveCardInfo) LastFour() string {
+	if len(s.CardNumber) < 4 {
+		return ""
+	}
+	return string(s.CardNumber[len(s.CardNumber)-4:])
+}
+
+// Format prints a redacted value for every verb, so card details never reach
+// logs through fmt.
+func (s SensitiveCardInfo) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, "SensitiveCardInfo{CardNumber:%s, CVV:[REDACTED], ExpireDate:[REDACTED]}", maskCardNumber(s.CardNumber))
+}
+
+// Format prints a redacted value for every verb, so card details never reach
+// logs through fmt.
+func (s SecureCardInfo) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, "SecureCardInfo{CardNumber:%s, CVV:[REDACTED], ExpireDate:[REDACTED]}", maskCardNumber([]byte(s.CardNumber)))
+}
+
+func maskCardNumber(number []byte) string {
+	if len(number) < 4 {
+		return "[REDACTED]"
+	}
+	return "****" + string(number[len(number)-4:])
+}
+
+// secretBytes decodes a JSON string into a byte slice without keeping a
+// string copy.
+type secretBytes []byte
+
+func (s *secretBytes) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = nil
+		return nil
+	}
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return errors.New("card detail must be a JSON string")
+	}
+	inner := data[1 : len(data)-1]
+	if bytes.IndexByte(inner, '\\') < 0 {
+		*s = append((*s)[:0], inner...)
+		return nil
+	}
+	// Escaped values, such as "12\/27", are rare enough to decode normally.
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*s = []byte(value)
+	return nil
+}
+
+// WithSecureCardInfo fetches the card's details, passes them to fn and wipes
+// them when fn returns, also on error or panic. The details must not be kept
+// or copied into strings beyond fn. Wiping is best effort: buffers owned by
+// the HTTP stack are not reachable. Prefer SecureDisplay, which keeps card
+// details off the caller's servers entirely.
+func (c *CardsClient) WithSecureCardInfo(ctx context.Context, cardID string, fn func(*SensitiveCardInfo) error, opts ...*common.RequestOptions) error {
+	var resp struct {
+		CVV        secretBytes `json:"cvv"`
+		ExpireDate secretBytes `json:"expire_date"`
+		CardNumber secretBytes `json:"card_number"`
+	}
+	info := &SensitiveCardInfo{}
+	defer func() {
+		info.CardNumber, info.CVV, info.ExpireDate = resp.CardNumber, resp.CVV, resp.ExpireDate
+		info.Wipe()
+	}()
+	path := fmt.Sprintf("/v1/issuing/cards/%s/secure", cardID)
+	if err := c.client.GetWithOptions(ctx, path, &resp, firstRequestOptions(opts)); err != nil {
+		return fmt.Errorf("failed to get secure card info: %w", err)
+	}
+	info.CardNumber, info.CVV, info.ExpireDate = resp.CardNumber, resp.CVV, resp.ExpireDate
+	return fn(info)
+}
@@ -0,0 +1,128 @@
+package issuing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecureDisplayEmbed(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(time.Minute).Format(time.RFC3339)
+	cards := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/issuing/cards/card-1/token" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"token":"pan-token-1","expires_in":60,"expires_at":"` + expiresAt + `"}`))
+	}).Cards
+	clock := now
+	display, err := NewSecureDisplay(cards, SecureDisplayOptions{
+		BaseURL:    "https://display.example.com/card?token={token}&cardId={card_id}&lang={language}",
+		Language:   "en",
+		SigningKey: []byte("signing-key"),
+		Now:        func() time.Time { return clock },
+	})
+	if err != nil {
+		t.Fatalf("NewSecureDisplay: %v", err)
+	}
+
+	embed, err := display.Embed(context.Background(), "card-1")
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	u, err := url.Parse(embed.URL)
+	if err != nil || !strings.HasPrefix(embed.URL, "https://display.example.com/card?") {
+		t.Fatalf("URL = %q", embed.URL)
+	}
+	if q := u.Query(); q.Get("token") != "pan-token-1" || q.Get("cardId") != "card-1" || q.Get("lang") != "en" {
+		t.Fatalf("query = %v", q)
+	}
+	if !embed.ExpiresAt.Equal(now.Add(time.Minute)) || embed.Signature == "" {
+		t.Fatalf("embed = %+v", embed)
+	}
+	if err := display.VerifyEmbed(embed); err != nil {
+		t.Fatalf("VerifyEmbed: %v", err)
+	}
+
+	tampered := *embed
+	tampered.CardID = "card-2"
+	if err := display.VerifyEmbed(&tampered); !errors.Is(err, ErrInvalidEmbedSignature) {
+		t.Fatalf("VerifyEmbed(tampered) = %v", err)
+	}
+	clock = now.Add(2 * time.Minute)
+	if err := display.VerifyEmbed(embed); !errors.Is(err, ErrPANTokenExpired) {
+		t.Fatalf("VerifyEmbed(expired) = %v", err)
+	}
+}
+
+func TestSecureDisplayRejectsExpiringTokens(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	display, err := NewSecureDisplay(nil, SecureDisplayOptions{BaseURL: "https://display.example.com/{card_id}?t={token}", Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("NewSecureDisplay: %v", err)
+	}
+	tokens := []*PANTokenResponse{
+		{Token: "t", ExpiresAt: "2024-06-01 12:00:03"},
+		{Token: "t", ExpiresAt: "2024-06-01T11:59:00Z"},
+		{Token: "t", ExpiresIn: 2},
+	}
+	for _, token := range tokens {
+		if _, err := display.EmbedToken("card-1", token); !errors.Is(err, ErrPANTokenExpired) {
+			t.Errorf("EmbedToken(%+v) error = %v", token, err)
+		}
+	}
+	embed, err := display.EmbedToken("card-1", &PANTokenResponse{Token: "t"})
+	if err != nil || !embed.ExpiresAt.Equal(now.Add(time.Minute)) || embed.Signature != "" {
+		t.Fatalf("EmbedToken without expiry = %+v, %v", embed, err)
+	}
+	if embed.URL != "https://display.example.com/card-1?t=t" {
+		t.Fatalf("URL = %q", embed.URL)
+	}
+	for _, baseURL := range []string{"", "http://example.com/card?token={token}", "https://example.com/card"} {
+		if _, err := NewSecureDisplay(nil, SecureDisplayOptions{BaseURL: baseURL}); err == nil {
+			t.Errorf("NewSecureDisplay(%q) succeeded", baseURL)
+		}
+	}
+}
+
+func TestWithSecureCardInfoWipesDetails(t *testing.T) {
+	cards := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"card_number":"4111111111111111","cvv":"123","expire_date":"12\/27"}`))
+	}).Cards
+
+	var kept *SensitiveCardInfo
+	err := cards.WithSecureCardInfo(context.Background(), "card-1", func(info *SensitiveCardInfo) error {
+		if string(info.CVV) != "123" || string(info.ExpireDate) != "12/27" || info.LastFour() != "1111" {
+			t.Errorf("info = %q %q %q", info.CardNumber, info.CVV, info.ExpireDate)
+		}
+		for _, verb := range []string{"%v", "%+v", "%#v", "%s"} {
+			if out := fmt.Sprintf(verb, info); strings.Contains(out, "411111") || strings.Contains(out, "123") {
+				t.Errorf("%s leaked card details: %s", verb, out)
+			}
+		}
+		kept = info
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithSecureCardInfo: %v", err)
+	}
+	for _, b := range [][]byte{kept.CardNumber, kept.CVV, kept.ExpireDate} {
+		for _, c := range b {
+			if c != 0 {
+				t.Fatalf("details not wiped: %q", b)
+			}
+		}
+	}
+
+	secure := SecureCardInfo{CardNumber: "4111111111111111", CVV: "123", ExpireDate: "12/27"}
+	if out := fmt.Sprintf("%+v", &secure); strings.Contains(out, "411111") || !strings.Contains(out, "****1111") {
+		t.Fatalf("SecureCardInfo formatted as %s", out)
+	}
+}
@@ -0,0 +1,652 @@
+package issuing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+	"github.com/uqpay/uqpay-sdk-go/v2/internal/ctxutil"
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+const defaultTopUpInterval = 5 * time.Minute
+
+// topUpNamespace scopes the idempotency keys derived for top-up orders.
+var topUpNamespace = uuid.MustParse("9d3c1f6e-2a47-4c1b-8e55-0f7b6a2d4e13")
+
+// TopUpRule is the funding policy of one card.
+type TopUpRule struct {
+	CardID string
+	// Threshold triggers a top-up when the card's available balance drops
+	// below it.
+	Threshold float64
+	// Target is the available balance a top-up restores.
+	Target float64
+	// DailyCap limits the amount recharged to the card per day. Zero means no
+	// limit.
+	DailyCap float64
+}
+
+// TopUpAlertKind classifies a TopUpAlert.
+type TopUpAlertKind string
+
+// Alerts raised by AutoTopUp.
+const (
+	// TopUpInsufficientBalance: the issuing balance cannot fund the top-up.
+	TopUpInsufficientBalance TopUpAlertKind = "insufficient_balance"
+	// TopUpDailyCapReached: the card's or the engine's daily cap is used up.
+	TopUpDailyCapReached TopUpAlertKind = "daily_cap_reached"
+	// TopUpFailed: checking or recharging the card failed.
+	TopUpFailed TopUpAlertKind = "failed"
+)
+
+// TopUpAlert reports a card that needed funds but could not be topped up.
+type TopUpAlert struct {
+	Kind     TopUpAlertKind
+	CardID   string
+	Currency string
+	// Needed is the amount the card needed to reach its target.
+	Needed float64
+	// Available is the issuing balance for insufficient-balance alerts and the
+	// remaining cap for daily-cap alerts.
+	Available float64
+	Err       error
+}
+
+// TopUpOptions configures an AutoTopUp.
+type TopUpOptions struct {
+	// Currency is the issuing balance cards are funded from. Recharges are in
+	// the card currency, so a card in another currency is not topped up and
+	// reported with a TopUpFailed alert. Empty uses each card's currency.
+	Currency string
+	// DailyCap limits the total recharged across all cards per day. Zero
+	// means no limit.
+	DailyCap float64
+	// Location sets the day boundary of the daily caps. Nil uses UTC.
+	Location *time.Location
+	// Store keeps the daily cap usage. Nil uses an in-memory store, whose
+	// usage starts from zero when the process restarts.
+	Store TopUpStore
+	// Interval is how often Run checks every card. Zero uses five minutes.
+	Interval time.Duration
+	// DryRun computes top-ups without recharging, reporting them through
+	// OnTopUp with TopUpResult.DryRun set.
+	DryRun bool
+	// OnTopUp is called for every recharge sent, or planned in dry-run mode.
+	OnTopUp func(TopUpResult)
+	// OnAlert is called for every card that could not be topped up.
+	OnAlert func(TopUpAlert)
+	// RequestOptions is passed to every API call, e.g. OnBehalfOf. Recharges
+	// use a derived idempotency key instead of its IdempotencyKey.
+	RequestOptions *common.RequestOptions
+	// Now returns the current time. Nil uses time.Now.
+	Now func() time.Time
+}
+
+// TopUpResult is the outcome of checking one card.
+type TopUpResult struct {
+	CardID string
+	// Balance is the card's available balance when it was checked.
+	Balance string
+	// Amount is the recharge sent, or planned in dry-run mode. Zero means the
+	// card was not topped up; Reason says why.
+	Amount float64
+	Reason string
+	DryRun bool
+	// Order is the recharge order. It is nil in dry-run mode.
+	Order *CardOrder
+	Err   error
+}
+
+// TopUpStore keeps the amounts recharged per card and day for the daily caps,
+// and each card's unfinished top-up with the idempotency key it is sent with.
+// A persistent store keeps both across restarts, so a recharge interrupted by
+// a crash is resent with the same key; one shared by several engines makes
+// them enforce the same caps. Implementations must be safe for concurrent
+// use.
+type TopUpStore interface {
+	// Add adds amount to the usage of cardID on day. Negative amounts
+	// release a top-up that was refused.
+	Add(ctx context.Context, day, cardID string, amount float64) error
+	// Usage returns the amount recharged to cardID and to all cards on day.
+	Usage(ctx context.Context, day, cardID string) (card, total float64, err error)
+	// NextSequence returns a number for a new top-up of cardID. The store
+	// never returns the same number twice for a card.
+	NextSequence(ctx context.Context, cardID string) (int64, error)
+	// Attempt returns the card's unfinished top-up, or nil.
+	Attempt(ctx context.Context, cardID string) (*TopUpAttempt, error)
+	// SetAttempt records the card's unfinished top-up. Nil clears it.
+	SetAttempt(ctx context.Context, cardID string, attempt *TopUpAttempt) error
+}
+
+// TopUpAttempt is a recharge sent for a card and counted against the caps
+// that has not completed yet.
+type TopUpAttempt struct {
+	// Day is the cap day the amount is counted on.
+	Day string
+	// Balance is the card's available balance the top-up was computed from.
+	Balance string
+	Amount  float64
+	// Key is the idempotency key the recharge is sent with.
+	Key string
+	// OrderID is the recharge order once UQPAY accepted it. Empty means the
+	// recharge may not have reached UQPAY.
+	OrderID string
+}
+
+// MemoryTopUpStore is an in-process TopUpStore. It only keeps the usage of
+// the most recent day.
+type MemoryTopUpStore struct {
+	mu        sync.Mutex
+	day       string
+	usage     map[string]float64
+	total     float64
+	sequences map[string]int64
+	attempts  map[string]TopUpAttempt
+}
+
+// NewMemoryTopUpStore creates an empty in-memory store.
+func NewMemoryTopUpStore() *MemoryTopUpStore {
+	return &MemoryTopUpStore{
+		usage:     map[string]float64{},
+		sequences: map[string]int64{},
+		attempts:  map[string]TopUpAttempt{},
+	}
+}
+
+// Add adds amount to the usage of cardID on day. Adding to a new day drops
+// the previous one.
+func (s *MemoryTopUpStore) Add(ctx context.Context, day, cardID string, amount float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if day != s.day {
+		s.day, s.usage, s.total = day, map[string]float64{}, 0
+	}
+	s.usage[cardID] += amount
+	s.total += amount
+	return nil
+}
+
+// Usage returns the amount recharged to cardID and to all cards on day.
+func (s *MemoryTopUpStore) Usage(ctx context.Context, day, cardID string) (float64, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if day != s.day {
+		return 0, 0, nil
+	}
+	return s.usage[cardID], s.total, nil
+}
+
+// NextSequence returns the next top-up number of cardID.
+func (s *MemoryTopUpStore) NextSequence(ctx context.Context, cardID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sequences[cardID]++
+	return s.sequences[cardID], nil
+}
+
+// Attempt returns a copy of the card's unfinished top-up, or nil.
+func (s *MemoryTopUpStore) Attempt(ctx context.Context, cardID string) (*TopUpAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempt, ok := s.attempts[cardID]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+// SetAttempt records the card's unfinished top-up. Nil clears it.
+func (s *MemoryTopUpStore) SetAttempt(ctx context.Context, cardID string, attempt *TopUpAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if attempt == nil {
+		delete(s.attempts, cardID)
+	} else {
+		s.attempts[cardID] = *attempt
+	}
+	return nil
+}
+
+// AutoTopUp keeps prepaid cards funded. Each card is checked on a schedule
+// and whenever a transaction or recharge webhook passed to HandleEvent names
+// it; a card whose available balance is below its threshold is recharged up
+// to its target, within the daily caps and the funds in the issuing balance.
+//
+// Every top-up gets its own idempotency key, derived from the card and a
+// sequence number from the TopUpStore, and is recorded in the store before
+// it is sent. After a transient error or a crash, the next check resends the
+// same amount with the same key while the card still has the balance the
+// top-up was computed from; once the balance has changed the top-up was
+// applied and is forgotten. A refused or failed recharge is released, and a
+// later top-up gets a new key. While a recharge order is pending, the card
+// is not topped up again.
+type AutoTopUp struct {
+	cards    *CardsClient
+	balances *BalancesClient
+	rules    map[string]TopUpRule
+	opts     TopUpOptions
+	queue    chan string
+
+	// capMu serializes cap checks with the reservations that follow them.
+	capMu sync.Mutex
+
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+// NewAutoTopUp validates rules and creates an auto top-up engine.
+func NewAutoTopUp(client *Client, rules []TopUpRule, opts TopUpOptions) (*AutoTopUp, error) {
+	byCard := make(map[string]TopUpRule, len(rules))
+	for i, rule := range rules {
+		if rule.CardID == "" {
+			return nil, fmt.Errorf("top-up rule %d: card ID is required", i)
+		}
+		if _, ok := byCard[rule.CardID]; ok {
+			return nil, fmt.Errorf("top-up rule for card %s is defined twice", rule.CardID)
+		}
+		if rule.Threshold < 0 || rule.Target <= rule.Threshold {
+			return nil, fmt.Errorf("top-up rule for card %s: target must be above a non-negative threshold", rule.CardID)
+		}
+		if rule.DailyCap < 0 {
+			return nil, fmt.Errorf("top-up rule for card %s: daily cap cannot be negative", rule.CardID)
+		}
+		byCard[rule.CardID] = rule
+	}
+	if opts.DailyCap < 0 {
+		return nil, errors.New("top-up daily cap cannot be negative")
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.Store == nil {
+		opts.Store = NewMemoryTopUpStore()
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultTopUpInterval
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &AutoTopUp{
+		cards:    client.Cards,
+		balances: client.Balances,
+		rules:    byCard,
+		opts:     opts,
+		queue:    make(chan string, 64),
+		busy:     map[string]bool{},
+	}, nil
+}
+
+// HandleEvent queues the card named by a card transaction or recharge webhook
+// for a check by Run. A card recharge event that completes the card's
+// pending recharge order releases the card, and a failed order no longer
+// counts toward the caps. Other events and cards without a rule are ignored,
+// and events are dropped while the queue is full, as the next sweep covers
+// them. Events whose data cannot be decoded return an error.
+func (a *AutoTopUp) HandleEvent(event *webhook.Event) error {
+	if event == nil {
+		return nil
+	}
+	payload, err := event.Payload()
+	if errors.Is(err, webhook.ErrUnregisteredEventType) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode webhook event %s: %w", event.EventID, err)
+	}
+	var cardID string
+	var recharge *webhook.CardRechargeData
+	switch data := payload.(type) {
+	case *webhook.CardRechargeData:
+		cardID, recharge = data.CardID, data
+	case *webhook.CardAuthorizationData:
+		cardID = data.CardID
+	case *webhook.CardReversalData:
+		cardID = data.CardID
+	case *webhook.CardClearingData:
+		cardID = data.CardID
+	case *webhook.CardRefundData:
+		cardID = data.CardID
+	case *webhook.CardTransactionData:
+		cardID = data.CardID
+	default:
+		return nil
+	}
+	if _, ok := a.rules[cardID]; !ok {
+		return nil
+	}
+	if recharge != nil {
+		status := recharge.OrderStatus
+		if status == "" && event.EventType == webhook.EventTypeCardRechargeFailed {
+			status = OrderStatusFailed
+		} else if status == "" {
+			status = OrderStatusSuccess
+		}
+		if err := a.settleOrder(context.Background(), cardID, recharge.CardOrderID, status); err != nil {
+			return err
+		}
+	}
+	select {
+	case a.queue <- cardID:
+	default:
+	}
+	return nil
+}
+
+// Run checks every card each Interval and queued cards as events arrive,
+// until ctx is done. Failures are reported to OnAlert.
+func (a *AutoTopUp) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.opts.Interval)
+	defer ticker.Stop()
+	a.CheckAll(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case cardID := <-a.queue:
+			_, _ = a.Check(ctx, cardID)
+		case <-ticker.C:
+			a.CheckAll(ctx)
+		}
+	}
+}
+
+// CheckAll checks every card with a rule, in card ID order.
+func (a *AutoTopUp) CheckAll(ctx context.Context) []TopUpResult {
+	cardIDs := make([]string, 0, len(a.rules))
+	for cardID := range a.rules {
+		cardIDs = append(cardIDs, cardID)
+	}
+	sort.Strings(cardIDs)
+	results := make([]TopUpResult, 0, len(cardIDs))
+	for _, cardID := range cardIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		result, _ := a.Check(ctx, cardID)
+		results = append(results, *result)
+	}
+	return results
+}
+
+// Check fetches one card and tops it up if its balance is below the
+// threshold. A card already being checked, or with a pending recharge order,
+// is skipped. Errors are also reported to OnAlert and recorded in the result.
+func (a *AutoTopUp) Check(ctx context.Context, cardID string) (*TopUpResult, error) {
+	result := &TopUpResult{CardID: cardID, DryRun: a.opts.DryRun}
+	rule, ok := a.rules[cardID]
+	if !ok {
+		result.Err = fmt.Errorf("no top-up rule for card %s", cardID)
+		return result, result.Err
+	}
+	if !a.acquire(cardID) {
+		result.Reason = "check already in progress"
+		return result, nil
+	}
+	defer a.release(cardID)
+
+	err := a.check(ctx, rule, result)
+	if err != nil {
+		result.Err = err
+		a.alert(TopUpAlert{Kind: TopUpFailed, CardID: cardID, Needed: result.Amount, Err: err})
+	}
+	return result, err
+}
+
+func (a *AutoTopUp) check(ctx context.Context, rule TopUpRule, result *TopUpResult) error {
+	attempt, err := a.opts.Store.Attempt(ctx, rule.CardID)
+	if err != nil {
+		return fmt.Errorf("failed to read top-up attempt: %w", err)
+	}
+	if attempt != nil && attempt.OrderID != "" {
+		pending, err := a.pendingOrder(ctx, rule.CardID, attempt)
+		if err != nil {
+			return err
+		}
+		if pending {
+			result.Reason = "recharge order pending"
+			return nil
+		}
+		attempt = nil
+	}
+	card, err := a.cards.Get(ctx, rule.CardID, a.opts.RequestOptions)
+	if err != nil {
+		return err
+	}
+	result.Balance = card.AvailableBalance
+	if attempt != nil && attempt.Balance != card.AvailableBalance {
+		// The balance moved since the top-up was sent, so it was applied.
+		if err := a.opts.Store.SetAttempt(ctx, rule.CardID, nil); err != nil {
+			return fmt.Errorf("failed to record top-up attempt: %w", err)
+		}
+		attempt = nil
+	}
+	if card.CardStatus != CardStatusActive {
+		result.Reason = "card is " + strings.ToLower(card.CardStatus)
+		return nil
+	}
+	if a.opts.Currency != "" && !strings.EqualFold(a.opts.Currency, card.CardCurrency) {
+		return fmt.Errorf("card %s is in %s, not the funding currency %s", rule.CardID, card.CardCurrency, a.opts.Currency)
+	}
+	balance, err := parseBalance(card.AvailableBalance)
+	if err != nil {
+		return fmt.Errorf("card %s: %w", rule.CardID, err)
+	}
+	if balance >= rule.Threshold {
+		result.Reason = "balance above threshold"
+		return nil
+	}
+
+	retry := attempt != nil
+	if !retry {
+		a.capMu.Lock()
+		defer a.capMu.Unlock()
+		day := a.day()
+		needed := roundUpCents(rule.Target - balance)
+		amount, remaining, err := a.allowance(ctx, day, rule, needed)
+		if err != nil {
+			return err
+		}
+		if amount <= 0 {
+			result.Reason = "daily cap reached"
+			a.alert(TopUpAlert{Kind: TopUpDailyCapReached, CardID: rule.CardID, Currency: card.CardCurrency, Needed: needed, Available: remaining})
+			return nil
+		}
+		attempt = &TopUpAttempt{Day: day, Balance: card.AvailableBalance, Amount: amount}
+	}
+
+	currency := card.CardCurrency
+	funding, err := a.balances.Retrieve(ctx, &RetrieveBalanceRequest{Currency: currency}, a.opts.RequestOptions)
+	if err != nil {
+		return err
+	}
+	available, err := parseBalance(funding.AvailableBalance)
+	if err != nil {
+		return fmt.Errorf("issuing balance %s: %w", currency, err)
+	}
+	if available < attempt.Amount {
+		result.Reason = "insufficient issuing balance"
+		a.alert(TopUpAlert{Kind: TopUpInsufficientBalance, CardID: rule.CardID, Currency: currency, Needed: attempt.Amount, Available: available})
+		return nil
+	}
+
+	result.Amount = attempt.Amount
+	if a.opts.DryRun {
+		result.Reason = "dry run"
+		a.notify(*result)
+		return nil
+	}
+	if !retry {
+		if err := a.reserve(ctx, rule.CardID, attempt); err != nil {
+			return err
+		}
+	}
+	opts := &common.RequestOptions{}
+	if a.opts.RequestOptions != nil {
+		*opts = *a.opts.RequestOptions
+	}
+	opts.IdempotencyKey = attempt.Key
+	order, err := a.cards.Recharge(ctx, rule.CardID, &CardOrderRequest{Amount: attempt.Amount}, opts)
+	switch {
+	case err != nil && retryable(err):
+		// The order may have been applied, so it stays counted and recorded,
+		// and the next check resends it with the same key.
+		return err
+	case err != nil:
+		// The recharge was refused, so it does not count toward the caps.
+		a.finish(ctx, rule.CardID, attempt, true)
+		return err
+	case order.OrderStatus == OrderStatusSuccess || order.OrderStatus == OrderStatusFailed:
+		if err := a.finish(ctx, rule.CardID, attempt, order.OrderStatus == OrderStatusFailed); err != nil {
+			return err
+		}
+	default:
+		attempt.OrderID = order.CardOrderID
+		if err := a.opts.Store.SetAttempt(ctx, rule.CardID, attempt); err != nil {
+			return fmt.Errorf("failed to record top-up attempt: %w", err)
+		}
+	}
+	result.Order = order
+	a.notify(*result)
+	return nil
+}
+
+// allowance returns how much of needed the daily caps allow, and the
+// smallest remaining cap.
+func (a *AutoTopUp) allowance(ctx context.Context, day string, rule TopUpRule, needed float64) (float64, float64, error) {
+	spent, total, err := a.opts.Store.Usage(ctx, day, rule.CardID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read top-up usage: %w", err)
+	}
+	remaining := math.Inf(1)
+	if rule.DailyCap > 0 {
+		remaining = rule.DailyCap - spent
+	}
+	if a.opts.DailyCap > 0 && a.opts.DailyCap-total < remaining {
+		remaining = a.opts.DailyCap - total
+	}
+	remaining = math.Floor(remaining*100+1e-6) / 100
+	if needed > remaining {
+		return remaining, remaining, nil
+	}
+	return needed, remaining, nil
+}
+
+// reserve counts a new top-up against the caps, gives it a key and records
+// it before it is sent.
+func (a *AutoTopUp) reserve(ctx context.Context, cardID string, attempt *TopUpAttempt) error {
+	sequence, err := a.opts.Store.NextSequence(ctx, cardID)
+	if err != nil {
+		return fmt.Errorf("failed to number top-up: %w", err)
+	}
+	attempt.Key = topUpKey(cardID, sequence)
+	if err := a.opts.Store.Add(ctx, attempt.Day, cardID, attempt.Amount); err != nil {
+		return fmt.Errorf("failed to reserve top-up: %w", err)
+	}
+	if err := a.opts.Store.SetAttempt(ctx, cardID, attempt); err != nil {
+		a.unreserve(ctx, cardID, attempt)
+		return fmt.Errorf("failed to record top-up attempt: %w", err)
+	}
+	return nil
+}
+
+// pendingOrder reports whether the card's recharge order is still pending.
+// An order that has completed since is settled.
+func (a *AutoTopUp) pendingOrder(ctx context.Context, cardID string, attempt *TopUpAttempt) (bool, error) {
+	order, err := a.cards.GetOrder(ctx, attempt.OrderID, a.opts.RequestOptions)
+	if err != nil {
+		return false, err
+	}
+	if order.OrderStatus != OrderStatusSuccess && order.OrderStatus != OrderStatusFailed {
+		return true, nil
+	}
+	return false, a.finish(ctx, cardID, attempt, order.OrderStatus == OrderStatusFailed)
+}
+
+// settleOrder finishes the card's pending recharge order once it reaches a
+// terminal status. orderID may be empty to match the card's order whatever
+// its ID.
+func (a *AutoTopUp) settleOrder(ctx context.Context, cardID, orderID, status string) error {
+	if status != OrderStatusSuccess && status != OrderStatusFailed {
+		return nil
+	}
+	attempt, err := a.opts.Store.Attempt(ctx, cardID)
+	if err != nil {
+		return fmt.Errorf("failed to read top-up attempt: %w", err)
+	}
+	if attempt == nil || attempt.OrderID == "" || (orderID != "" && orderID != attempt.OrderID) {
+		return nil
+	}
+	return a.finish(ctx, cardID, attempt, status == OrderStatusFailed)
+}
+
+// finish forgets the card's top-up, releasing its amount from the caps if it
+// was not applied.
+func (a *AutoTopUp) finish(ctx context.Context, cardID string, attempt *TopUpAttempt, failed bool) error {
+	if failed {
+		a.unreserve(ctx, cardID, attempt)
+	}
+	if err := a.opts.Store.SetAttempt(ctxutil.WithoutCancel(ctx), cardID, nil); err != nil {
+		return fmt.Errorf("failed to record top-up attempt: %w", err)
+	}
+	return nil
+}
+
+// unreserve releases a recharge that was not applied from the caps.
+func (a *AutoTopUp) unreserve(ctx context.Context, cardID string, attempt *TopUpAttempt) {
+	_ = a.opts.Store.Add(ctxutil.WithoutCancel(ctx), attempt.Day, cardID, -attempt.Amount)
+}
+
+// day returns the current cap day.
+func (a *AutoTopUp) day() string {
+	return a.opts.Now().In(a.opts.Location).Format("2006-01-02")
+}
+
+// topUpKey derives the idempotency key of the top-up of cardID numbered
+// sequence.
+func topUpKey(cardID string, sequence int64) string {
+	name := fmt.Sprintf("%s\x00%d", cardID, sequence)
+	return uuid.NewSHA1(topUpNamespace, []byte(name)).String()
+}
+
+func (a *AutoTopUp) acquire(cardID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.busy[cardID] {
+		return false
+	}
+	a.busy[cardID] = true
+	return true
+}
+
+func (a *AutoTopUp) release(cardID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.busy, cardID)
+}
+
+func (a *AutoTopUp) notify(result TopUpResult) {
+	if a.opts.OnTopUp != nil {
+		a.opts.OnTopUp(result)
+	}
+}
+
+func (a *AutoTopUp) alert(alert TopUpAlert) {
+	if a.opts.OnAlert != nil {
+		a.opts.OnAlert(alert)
+	}
+}
+
+// roundUpCents rounds amount up to the next cent.
+func roundUpCents(amount float64) float64 {
+	return math.Ceil(amount*100-1e-6) / 100
+}
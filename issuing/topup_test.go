@@ -0,0 +1,308 @@
+package issuing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+// fakeFunding serves card, issuing balance and recharge endpoints. Recharges
+// move funds from the issuing balance to the card, and a recharge resent with
+// an idempotency key seen before gets the first response replayed without
+// being applied again.
+type fakeFunding struct {
+	mu       sync.Mutex
+	cards    map[string]float64
+	status   map[string]string
+	issuing  float64
+	keys     []string
+	recharge []float64
+	// failures fails that many recharges with 503 without applying them.
+	failures int
+	// orderStatus is returned for recharges and order lookups. Empty uses
+	// SUCCESS.
+	orderStatus string
+	lookups     int
+	replies     map[string][]byte
+}
+
+func newTopUpTestClient(t *testing.T, funding *fakeFunding) *Client {
+	t.Helper()
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		funding.mu.Lock()
+		defer funding.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		orderStatus := funding.orderStatus
+		if orderStatus == "" {
+			orderStatus = OrderStatusSuccess
+		}
+		switch {
+		case r.URL.Path == "/v1/issuing/balances":
+			_ = json.NewEncoder(w).Encode(IssuingBalance{Currency: "USD", AvailableBalance: formatAmount(funding.issuing)})
+		case strings.HasSuffix(r.URL.Path, "/recharge"):
+			cardID := strings.Split(r.URL.Path, "/")[4]
+			var req CardOrderRequest
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			key := r.Header.Get("x-idempotency-key")
+			funding.keys = append(funding.keys, key)
+			funding.recharge = append(funding.recharge, req.Amount)
+			if reply, ok := funding.replies[key]; ok {
+				_, _ = w.Write(reply)
+				return
+			}
+			if funding.failures > 0 {
+				funding.failures--
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"type":"server_error","message":"unavailable"}`))
+				return
+			}
+			if orderStatus == OrderStatusSuccess {
+				funding.cards[cardID] += req.Amount
+				funding.issuing -= req.Amount
+			}
+			reply, _ := json.Marshal(map[string]interface{}{"card_id": cardID, "card_order_id": "order-" + cardID, "amount": req.Amount, "order_status": orderStatus})
+			if funding.replies == nil {
+				funding.replies = map[string][]byte{}
+			}
+			funding.replies[key] = reply
+			_, _ = w.Write(reply)
+		case strings.HasSuffix(r.URL.Path, "/order"):
+			funding.lookups++
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"card_order_id": strings.Split(r.URL.Path, "/")[4], "order_status": orderStatus})
+		default:
+			cardID := strings.TrimPrefix(r.URL.Path, "/v1/issuing/cards/")
+			status := funding.status[cardID]
+			if status == "" {
+				status = CardStatusActive
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"card_id": cardID, "card_currency": "USD", "card_status": status, "available_balance": formatAmount(funding.cards[cardID])})
+		}
+	})
+}
+
+func TestAutoTopUpRechargesToTargetWithinCaps(t *testing.T) {
+	funding := &fakeFunding{cards: map[string]float64{"card-1": 12.5, "card-2": 80}, issuing: 1000}
+	client := newTopUpTestClient(t, funding)
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	var topUps []TopUpResult
+	var alerts []TopUpAlert
+	engine, err := NewAutoTopUp(client, []TopUpRule{
+		{CardID: "card-1", Threshold: 20, Target: 100, DailyCap: 150},
+		{CardID: "card-2", Threshold: 20, Target: 100},
+	}, TopUpOptions{
+		Now:     func() time.Time { return now },
+		OnTopUp: func(r TopUpResult) { topUps = append(topUps, r) },
+		OnAlert: func(a TopUpAlert) { alerts = append(alerts, a) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results := engine.CheckAll(context.Background())
+	if len(results) != 2 || results[0].Amount != 87.5 || results[0].Order == nil || results[1].Amount != 0 {
+		t.Fatalf("results = %+v", results)
+	}
+	if len(topUps) != 1 || len(alerts) != 0 {
+		t.Errorf("topUps = %+v, alerts = %+v", topUps, alerts)
+	}
+
+	// Spending drops the card back to the balance of the first top-up; the
+	// cap leaves 62.5 of the 87.5 needed, sent with a new key.
+	funding.cards["card-1"] = 12.5
+	result, err := engine.Check(context.Background(), "card-1")
+	if err != nil || result.Amount != 62.5 || funding.cards["card-1"] != 75 {
+		t.Fatalf("second top-up = %+v, %v, balance = %v", result, err, funding.cards["card-1"])
+	}
+	if funding.keys[0] == funding.keys[1] {
+		t.Error("top-ups on the same day reused an idempotency key")
+	}
+
+	funding.cards["card-1"] = 5
+	result, _ = engine.Check(context.Background(), "card-1")
+	if result.Amount != 0 || len(alerts) != 1 || alerts[0].Kind != TopUpDailyCapReached {
+		t.Errorf("capped result = %+v, alerts = %+v", result, alerts)
+	}
+
+	// A new day resets the cap.
+	now = now.Add(24 * time.Hour)
+	if result, _ = engine.Check(context.Background(), "card-1"); result.Amount != 95 {
+		t.Errorf("next day result = %+v", result)
+	}
+}
+
+func TestAutoTopUpResendsUnfinishedTopUpsWithTheSameKey(t *testing.T) {
+	funding := &fakeFunding{cards: map[string]float64{"card-1": 0}, issuing: 1000, failures: 1}
+	client := newTopUpTestClient(t, funding)
+	store := NewMemoryTopUpStore()
+	rules := []TopUpRule{{CardID: "card-1", Threshold: 10, Target: 50, DailyCap: 200}}
+	engine, err := NewAutoTopUp(client, rules, TopUpOptions{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Check(context.Background(), "card-1"); err == nil {
+		t.Fatal("expected the transient failure")
+	}
+
+	// A restarted engine on the same store resends the top-up with the same
+	// key and amount, counted once against the cap.
+	engine, err = NewAutoTopUp(client, rules, TopUpOptions{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := engine.Check(context.Background(), "card-1")
+	if err != nil || result.Amount != 50 || result.Order == nil || funding.cards["card-1"] != 50 {
+		t.Fatalf("retry = %+v, %v, balance = %v", result, err, funding.cards["card-1"])
+	}
+	if len(funding.keys) != 2 || funding.keys[0] != funding.keys[1] {
+		t.Errorf("retry keys = %v", funding.keys)
+	}
+	if spent, _, _ := store.Usage(context.Background(), engine.day(), "card-1"); spent != 50 {
+		t.Errorf("cap usage = %v, want 50", spent)
+	}
+
+	// Once applied, the top-up is finished: a card drained back to the same
+	// balance gets a new key and is funded again.
+	funding.cards["card-1"] = 0
+	if result, err := engine.Check(context.Background(), "card-1"); err != nil || funding.cards["card-1"] != 50 {
+		t.Fatalf("next top-up = %+v, %v, balance = %v", result, err, funding.cards["card-1"])
+	}
+	if len(funding.keys) != 3 || funding.keys[2] == funding.keys[0] {
+		t.Errorf("keys = %v", funding.keys)
+	}
+}
+
+func TestAutoTopUpWaitsForPendingOrders(t *testing.T) {
+	funding := &fakeFunding{cards: map[string]float64{"card-1": 0}, issuing: 1000, orderStatus: OrderStatusProcessing}
+	store := NewMemoryTopUpStore()
+	engine, err := NewAutoTopUp(newTopUpTestClient(t, funding), []TopUpRule{{CardID: "card-1", Threshold: 10, Target: 50}}, TopUpOptions{Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := engine.Check(context.Background(), "card-1"); err != nil || result.Amount != 50 {
+		t.Fatalf("first check = %+v, %v", result, err)
+	}
+	result, err := engine.Check(context.Background(), "card-1")
+	if err != nil || result.Reason != "recharge order pending" || len(funding.recharge) != 1 || funding.lookups != 1 {
+		t.Fatalf("pending check = %+v, %v, recharges = %v", result, err, funding.recharge)
+	}
+
+	// The failed order is released from the cap and the card is queued.
+	if err := engine.HandleEvent(&webhook.Event{EventType: webhook.EventTypeCardRechargeFailed, Data: json.RawMessage(`{"card_id":"card-1","card_order_id":"order-card-1","order_status":"FAILED"}`)}); err != nil {
+		t.Fatalf("HandleEvent = %v", err)
+	}
+	if cardID := <-engine.queue; cardID != "card-1" {
+		t.Fatalf("queued %q", cardID)
+	}
+	if spent, _, _ := store.Usage(context.Background(), engine.day(), "card-1"); spent != 0 {
+		t.Errorf("cap usage after failed order = %v", spent)
+	}
+	funding.orderStatus = ""
+	if result, err := engine.Check(context.Background(), "card-1"); err != nil || result.Amount != 50 || funding.cards["card-1"] != 50 {
+		t.Fatalf("check after failed order = %+v, %v, balance = %v", result, err, funding.cards["card-1"])
+	}
+	if len(funding.keys) != 2 || funding.keys[0] == funding.keys[1] {
+		t.Errorf("a failed order's key was reused: %v", funding.keys)
+	}
+}
+
+func TestAutoTopUpRejectsOtherFundingCurrencies(t *testing.T) {
+	funding := &fakeFunding{cards: map[string]float64{"card-1": 0}, issuing: 1000}
+	var alerts []TopUpAlert
+	engine, err := NewAutoTopUp(newTopUpTestClient(t, funding), []TopUpRule{{CardID: "card-1", Threshold: 10, Target: 50}}, TopUpOptions{
+		Currency: "SGD",
+		OnAlert:  func(a TopUpAlert) { alerts = append(alerts, a) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.Check(context.Background(), "card-1"); err == nil || len(funding.recharge) != 0 || len(alerts) != 1 || alerts[0].Kind != TopUpFailed {
+		t.Fatalf("err = %v, recharges = %v, alerts = %+v", err, funding.recharge, alerts)
+	}
+}
+
+func TestAutoTopUpDryRunAndInsufficientBalance(t *testing.T) {
+	funding := &fakeFunding{cards: map[string]float64{"card-1": 0, "card-2": 0}, status: map[string]string{"card-2": CardStatusFrozen}, issuing: 30}
+	client := newTopUpTestClient(t, funding)
+	var topUps []TopUpResult
+	var alerts []TopUpAlert
+	rules := []TopUpRule{{CardID: "card-1", Threshold: 10, Target: 25}, {CardID: "card-2", Threshold: 10, Target: 25}}
+	engine, err := NewAutoTopUp(client, rules, TopUpOptions{
+		DryRun:  true,
+		OnTopUp: func(r TopUpResult) { topUps = append(topUps, r) },
+		OnAlert: func(a TopUpAlert) { alerts = append(alerts, a) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := engine.CheckAll(context.Background())
+	if !results[0].DryRun || results[0].Amount != 25 || results[0].Order != nil || len(funding.recharge) != 0 {
+		t.Errorf("dry run result = %+v, recharges = %v", results[0], funding.recharge)
+	}
+	if results[1].Reason != "card is frozen" || len(topUps) != 1 {
+		t.Errorf("frozen result = %+v, topUps = %+v", results[1], topUps)
+	}
+
+	rules[0].Target = 50
+	engine, _ = NewAutoTopUp(client, rules[:1], TopUpOptions{OnAlert: func(a TopUpAlert) { alerts = append(alerts, a) }})
+	result, err := engine.Check(context.Background(), "card-1")
+	if err != nil || result.Amount != 0 || len(alerts) != 1 || alerts[0].Kind != TopUpInsufficientBalance || alerts[0].Available != 30 {
+		t.Errorf("result = %+v, err = %v, alerts = %+v", result, err, alerts)
+	}
+}
+
+func TestAutoTopUpRunChecksCardsFromEvents(t *testing.T) {
+	funding := &fakeFunding{cards: map[string]float64{"card-1": 50}, issuing: 1000}
+	topped := make(chan TopUpResult, 1)
+	engine, err := NewAutoTopUp(newTopUpTestClient(t, funding), []TopUpRule{{CardID: "card-1", Threshold: 10, Target: 50}}, TopUpOptions{
+		Interval: time.Hour,
+		OnTopUp:  func(r TopUpResult) { topped <- r },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- engine.Run(ctx) }()
+
+	funding.mu.Lock()
+	funding.cards["card-1"] = 4
+	funding.mu.Unlock()
+	if err := engine.HandleEvent(&webhook.Event{EventType: webhook.EventTypeIssuingAuthorizationCard, Data: json.RawMessage(`{"card_id":"card-1"}`)}); err != nil {
+		t.Fatalf("HandleEvent = %v", err)
+	}
+	if err := engine.HandleEvent(&webhook.Event{EventType: webhook.EventTypeIssuingAuthorizationCard, Data: json.RawMessage(`{"card_id":1}`)}); err == nil {
+		t.Fatal("undecodable authorization event was not reported")
+	}
+
+	select {
+	case result := <-topped:
+		if result.Amount != 46 {
+			t.Errorf("result = %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event did not trigger a top-up")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run = %v", err)
+	}
+}
+
+func TestNewAutoTopUpValidatesRules(t *testing.T) {
+	for _, rules := range [][]TopUpRule{
+		{{Threshold: 1, Target: 2}},
+		{{CardID: "c", Threshold: 10, Target: 10}},
+		{{CardID: "c", Threshold: 1, Target: 2}, {CardID: "c", Threshold: 1, Target: 2}},
+	} {
+		if _, err := NewAutoTopUp(NewClient(nil), rules, TopUpOptions{}); err == nil {
+			t.Errorf("expected an error for %+v", rules)
+		}
+	}
+}
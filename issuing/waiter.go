@@ -0,0 +1,354 @@
+package issuing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+// Card order statuses.
+const (
+	OrderStatusPending    = "PENDING"
+	OrderStatusProcessing = "PROCESSING"
+	OrderStatusSuccess    = "SUCCESS"
+	OrderStatusFailed     = "FAILED"
+)
+
+// Card statuses.
+const (
+	CardStatusPending   = "PENDING"
+	CardStatusActive    = "ACTIVE"
+	CardStatusFrozen    = "FROZEN"
+	CardStatusCancelled = "CANCELLED"
+	CardStatusClosed    = "CLOSED"
+)
+
+// OrderOutcome is the result of a card order that reached a terminal status.
+type OrderOutcome string
+
+const (
+	OrderSucceeded OrderOutcome = "succeeded"
+	OrderFailed    OrderOutcome = "failed"
+)
+
+// DefaultOrderStates is the terminal-state table used when
+// WaiterOptions.OrderStates is nil. Statuses not listed are still in progress.
+var DefaultOrderStates = map[string]OrderOutcome{
+	OrderStatusSuccess: OrderSucceeded,
+	OrderStatusFailed:  OrderFailed,
+}
+
+// DefaultTerminalCardStatuses are card statuses a card never leaves. Waiting
+// for any other status stops with a CardStatusError once one is reached.
+var DefaultTerminalCardStatuses = []string{CardStatusCancelled, CardStatusClosed}
+
+// Backoff is an exponential polling schedule.
+type Backoff struct {
+	// Initial is the delay before the second poll.
+	Initial time.Duration
+	// Max caps the delay between polls.
+	Max time.Duration
+	// Multiplier grows the delay after each poll. Values below 1 are treated
+	// as 1.
+	Multiplier float64
+	// Jitter randomises each delay by up to this fraction, e.g. 0.2 for ±20%.
+	Jitter float64
+}
+
+// DefaultBackoff polls after 500ms, doubling up to 10s, with 20% jitter.
+var DefaultBackoff = Backoff{Initial: 500 * time.Millisecond, Max: 10 * time.Second, Multiplier: 2, Jitter: 0.2}
+
+// delay returns the delay before poll attempt+1.
+func (b Backoff) delay(attempt int) time.Duration {
+	d := float64(b.Initial)
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	for i := 0; i < attempt && (b.Max <= 0 || d < float64(b.Max)); i++ {
+		d *= multiplier
+	}
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if b.Jitter > 0 {
+		d += d * b.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d)
+}
+
+// WaiterOptions configures a Waiter.
+type WaiterOptions struct {
+	// Backoff is the polling schedule. The zero value uses DefaultBackoff.
+	Backoff Backoff
+	// OrderStates maps order statuses to outcomes. Nil uses
+	// DefaultOrderStates.
+	OrderStates map[string]OrderOutcome
+	// TerminalCardStatuses are statuses that end WaitForCardStatus with a
+	// CardStatusError. Nil uses DefaultTerminalCardStatuses.
+	TerminalCardStatuses []string
+	// Webhooks declares that card.* webhook events are passed to
+	// HandleEvent. Waits then re-check only when an event for their card or
+	// order arrives, plus every WebhookFallback in case an event is lost.
+	Webhooks bool
+	// WebhookFallback is the polling interval used when Webhooks is set.
+	// Zero uses one minute.
+	WebhookFallback time.Duration
+	// RequestOptions is passed to every API call, e.g. OnBehalfOf.
+	RequestOptions *common.RequestOptions
+}
+
+// OrderFailedError is returned by WaitForOrder when an order ends in a failed
+// state.
+type OrderFailedError struct {
+	Order *CardOrder
+}
+
+func (e *OrderFailedError) Error() string {
+	return fmt.Sprintf("card order %s %s", e.Order.CardOrderID, strings.ToLower(e.Order.OrderStatus))
+}
+
+// CardStatusError is returned by WaitForCardStatus when the card reaches a
+// terminal status other than the one waited for.
+type CardStatusError struct {
+	CardID string
+	Want   string
+	Got    string
+}
+
+func (e *CardStatusError) Error() string {
+	return fmt.Sprintf("card %s reached terminal status %s while waiting for %s", e.CardID, e.Got, e.Want)
+}
+
+// Waiter waits for asynchronous card operations to finish, polling with
+// backoff or, when webhooks are wired in, re-checking as card.* events
+// arrive. It is safe for concurrent use.
+type Waiter struct {
+	cards *CardsClient
+	opts  WaiterOptions
+
+	mu       sync.Mutex
+	watchers map[string]map[*watcher]struct{}
+}
+
+type watcher struct {
+	wake chan struct{}
+}
+
+// NewWaiter creates a waiter that reads orders and cards through cards.
+func NewWaiter(cards *CardsClient, opts WaiterOptions) *Waiter {
+	if opts.Backoff == (Backoff{}) {
+		opts.Backoff = DefaultBackoff
+	}
+	if opts.OrderStates == nil {
+		opts.OrderStates = DefaultOrderStates
+	}
+	if opts.TerminalCardStatuses == nil {
+		opts.TerminalCardStatuses = DefaultTerminalCardStatuses
+	}
+	if opts.WebhookFallback <= 0 {
+		opts.WebhookFallback = time.Minute
+	}
+	return &Waiter{cards: cards, opts: opts, watchers: map[string]map[*watcher]struct{}{}}
+}
+
+// WaitForOrder waits until the card order reaches a terminal status and
+// returns it. A failed order returns the order and an *OrderFailedError.
+func (w *Waiter) WaitForOrder(ctx context.Context, orderID string) (*CardOrder, error) {
+	var order *CardOrder
+	err := w.wait(ctx, []string{"order:" + orderID}, func(watch func(string)) (bool, error) {
+		latest, err := w.cards.GetOrder(ctx, orderID, w.opts.RequestOptions)
+		if err != nil {
+			return false, err
+		}
+		order = latest
+		if order.CardID != "" {
+			watch("card:" + order.CardID)
+		}
+		switch w.opts.OrderStates[order.OrderStatus] {
+		case OrderSucceeded:
+			return true, nil
+		case OrderFailed:
+			return true, &OrderFailedError{Order: order}
+		}
+		return false, nil
+	})
+	if err != nil && order == nil {
+		return nil, fmt.Errorf("failed to wait for card order %s: %w", orderID, err)
+	}
+	if err != nil {
+		var failed *OrderFailedError
+		if !errors.As(err, &failed) {
+			err = fmt.Errorf("failed to wait for card order %s (last status %s): %w", orderID, order.OrderStatus, err)
+		}
+	}
+	return order, err
+}
+
+// WaitForCardStatus waits until the card has status and returns it. If the
+// card reaches a terminal status instead, it returns the card and a
+// *CardStatusError.
+func (w *Waiter) WaitForCardStatus(ctx context.Context, cardID, status string) (*RetrieveCardResponse, error) {
+	var card *RetrieveCardResponse
+	err := w.wait(ctx, []string{"card:" + cardID}, func(func(string)) (bool, error) {
+		latest, err := w.cards.Get(ctx, cardID, w.opts.RequestOptions)
+		if err != nil {
+			return false, err
+		}
+		card = latest
+		if card.CardStatus == status {
+			return true, nil
+		}
+		for _, terminal := range w.opts.TerminalCardStatuses {
+			if card.CardStatus == terminal {
+				return true, &CardStatusError{CardID: cardID, Want: status, Got: card.CardStatus}
+			}
+		}
+		return false, nil
+	})
+	if err != nil && card == nil {
+		return nil, fmt.Errorf("failed to wait for card %s: %w", cardID, err)
+	}
+	if err != nil {
+		var statusErr *CardStatusError
+		if !errors.As(err, &statusErr) {
+			err = fmt.Errorf("failed to wait for card %s to become %s (last status %s): %w", cardID, status, card.CardStatus, err)
+		}
+	}
+	return card, err
+}
+
+// HandleEvent wakes waits for the card or card order named by a card
+// webhook event so they re-check immediately. Other events are ignored. The
+// event only triggers a check; the API response decides the outcome. Events
+// whose data cannot be decoded return an error and are left to the fallback
+// poll.
+func (w *Waiter) HandleEvent(event *webhook.Event) error {
+	if event == nil {
+		return nil
+	}
+	payload, err := event.Payload()
+	if errors.Is(err, webhook.ErrUnregisteredEventType) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to decode webhook event %s: %w", event.EventID, err)
+	}
+	var cardID, orderID string
+	switch payload := payload.(type) {
+	case *webhook.CardData:
+		cardID, orderID = payload.CardID, payload.CardOrderID
+	case *webhook.CardRechargeData:
+		cardID = payload.CardID
+	case *webhook.CardStatusUpdateData:
+		cardID = payload.CardID
+	case *webhook.CardLifecycleData:
+		cardID = payload.CardID
+	case *webhook.CardActivationCodeData:
+		cardID = payload.CardID
+	default:
+		return nil
+	}
+	keys := []string{"card:" + event.SourceID, "order:" + event.SourceID}
+	if cardID != "" {
+		keys = append(keys, "card:"+cardID)
+	}
+	if orderID != "" {
+		keys = append(keys, "order:"+orderID)
+	}
+	w.wake(keys)
+	return nil
+}
+
+// wake signals every wait watching one of keys.
+func (w *Waiter) wake(keys []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, key := range keys {
+		for watcher := range w.watchers[key] {
+			select {
+			case watcher.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// wait calls check until it reports done or returns a permanent error. check
+// may call watch to subscribe to events for further keys.
+func (w *Waiter) wait(ctx context.Context, keys []string, check func(watch func(string)) (bool, error)) error {
+	self := &watcher{wake: make(chan struct{}, 1)}
+	var watched []string
+	watch := func(key string) {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.watchers[key] == nil {
+			w.watchers[key] = map[*watcher]struct{}{}
+		}
+		if _, ok := w.watchers[key][self]; !ok {
+			w.watchers[key][self] = struct{}{}
+			watched = append(watched, key)
+		}
+	}
+	defer func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for _, key := range watched {
+			delete(w.watchers[key], self)
+			if len(w.watchers[key]) == 0 {
+				delete(w.watchers, key)
+			}
+		}
+	}()
+	for _, key := range keys {
+		watch(key)
+	}
+
+	for attempt := 0; ; attempt++ {
+		done, err := check(watch)
+		if done {
+			return err
+		}
+		if err != nil && !retryable(err) {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		delay := w.opts.Backoff.delay(attempt)
+		if w.opts.Webhooks {
+			delay = w.opts.WebhookFallback
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-self.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether a polling error is transient: network failures,
+// rate limiting and server errors.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
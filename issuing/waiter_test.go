@@ -0,0 +1,167 @@
+package issuing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+// sequenceServer answers each path with its bodies in order, repeating the
+// last one.
+type sequenceServer struct {
+	mu     sync.Mutex
+	bodies map[string][]string
+	calls  map[string]int
+}
+
+func newWaiterTestClient(t *testing.T, bodies map[string][]string) (*CardsClient, *sequenceServer) {
+	t.Helper()
+	seq := &sequenceServer{bodies: bodies, calls: map[string]int{}}
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		seq.mu.Lock()
+		responses := seq.bodies[r.URL.Path]
+		n := seq.calls[r.URL.Path]
+		seq.calls[r.URL.Path]++
+		seq.mu.Unlock()
+		if len(responses) == 0 {
+			t.Errorf("unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		body := responses[len(responses)-1]
+		if n < len(responses) {
+			body = responses[n]
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if body == "503" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"type":"server_error","message":"unavailable"}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}).Cards, seq
+}
+
+func (s *sequenceServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[path]
+}
+
+var fastBackoff = Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2}
+
+func TestWaitForOrderPollsUntilTerminalStatus(t *testing.T) {
+	cards, seq := newWaiterTestClient(t, map[string][]string{
+		"/v1/issuing/cards/order-1/order": {
+			`{"card_order_id":"order-1","card_id":"card-1","order_status":"PENDING"}`,
+			"503",
+			`{"card_order_id":"order-1","card_id":"card-1","order_status":"PROCESSING"}`,
+			`{"card_order_id":"order-1","card_id":"card-1","order_status":"SUCCESS","amount":"10"}`,
+		},
+		"/v1/issuing/cards/order-2/order": {`{"card_order_id":"order-2","order_status":"FAILED"}`},
+	})
+	waiter := NewWaiter(cards, WaiterOptions{Backoff: fastBackoff})
+
+	order, err := waiter.WaitForOrder(context.Background(), "order-1")
+	if err != nil || order.OrderStatus != OrderStatusSuccess || order.Amount != 10 {
+		t.Fatalf("WaitForOrder = %+v, %v", order, err)
+	}
+	if calls := seq.count("/v1/issuing/cards/order-1/order"); calls != 4 {
+		t.Fatalf("polled %d times, want 4", calls)
+	}
+
+	order, err = waiter.WaitForOrder(context.Background(), "order-2")
+	var failed *OrderFailedError
+	if !errors.As(err, &failed) || failed.Order.CardOrderID != "order-2" || order == nil {
+		t.Fatalf("WaitForOrder(failed) = %+v, %v", order, err)
+	}
+}
+
+func TestWaitForCardStatusStopsAtTerminalStatus(t *testing.T) {
+	cards, _ := newWaiterTestClient(t, map[string][]string{
+		"/v1/issuing/cards/card-1": {`{"card_id":"card-1","card_status":"PENDING"}`, `{"card_id":"card-1","card_status":"ACTIVE"}`},
+		"/v1/issuing/cards/card-2": {`{"card_id":"card-2","card_status":"PENDING"}`, `{"card_id":"card-2","card_status":"CANCELLED"}`},
+	})
+	waiter := NewWaiter(cards, WaiterOptions{Backoff: fastBackoff})
+
+	card, err := waiter.WaitForCardStatus(context.Background(), "card-1", CardStatusActive)
+	if err != nil || card.CardStatus != CardStatusActive {
+		t.Fatalf("WaitForCardStatus = %+v, %v", card, err)
+	}
+	_, err = waiter.WaitForCardStatus(context.Background(), "card-2", CardStatusActive)
+	var statusErr *CardStatusError
+	if !errors.As(err, &statusErr) || statusErr.Got != CardStatusCancelled {
+		t.Fatalf("WaitForCardStatus(cancelled) error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = waiter.WaitForCardStatus(ctx, "card-1", CardStatusFrozen)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitForCardStatus past deadline error = %v", err)
+	}
+}
+
+func TestWaiterCompletesOnWebhookEvents(t *testing.T) {
+	cards, seq := newWaiterTestClient(t, map[string][]string{
+		"/v1/issuing/cards/order-1/order": {
+			`{"card_order_id":"order-1","card_id":"card-1","order_status":"PROCESSING"}`,
+			`{"card_order_id":"order-1","card_id":"card-1","order_status":"SUCCESS"}`,
+		},
+	})
+	waiter := NewWaiter(cards, WaiterOptions{Webhooks: true, WebhookFallback: time.Hour})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := waiter.WaitForOrder(context.Background(), "order-1")
+		done <- err
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for seq.count("/v1/issuing/cards/order-1/order") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("first poll never happened")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := waiter.HandleEvent(&webhook.Event{EventType: webhook.EventTypePayoutCompleted, SourceID: "card-1", Data: []byte(`{}`)}); err != nil {
+		t.Fatalf("HandleEvent(payout) = %v", err)
+	}
+	if err := waiter.HandleEvent(&webhook.Event{EventType: webhook.EventTypeCardRechargeSucceeded, Data: []byte(`{"card_id":1}`)}); err == nil {
+		t.Fatal("undecodable recharge event was not reported")
+	}
+	// The order learns its card ID from the first poll; the recharge event
+	// names only the card.
+	for {
+		if err := waiter.HandleEvent(&webhook.Event{EventType: webhook.EventTypeCardRechargeSucceeded, Data: []byte(`{"card_id":"card-1","order_status":"SUCCESS"}`)}); err != nil {
+			t.Fatalf("HandleEvent(recharge) = %v", err)
+		}
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("WaitForOrder: %v", err)
+			}
+			if calls := seq.count("/v1/issuing/cards/order-1/order"); calls != 2 {
+				t.Fatalf("polled %d times, want 2", calls)
+			}
+			return
+		case <-time.After(5 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("webhook event did not complete the wait")
+		}
+	}
+}
+
+func TestBackoffDelayGrowsToMax(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 3}
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 900 * time.Millisecond, time.Second, time.Second} {
+		if got := b.delay(attempt); got != want {
+			t.Errorf("delay(%d) = %s, want %s", attempt, got, want)
+		}
+	}
+}
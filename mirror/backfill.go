@@ -0,0 +1,104 @@
+package mirror
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/banking"
+	"github.com/uqpay/uqpay-sdk-go/v2/issuing"
+)
+
+// list pages through the List endpoint for resourceType and calls visit for
+// every resource returned.
+func (s *Syncer) list(ctx context.Context, resourceType ResourceType, visit func(*Record) error) error {
+	switch resourceType {
+	case ResourceCard:
+		if s.issuing == nil {
+			return fmt.Errorf("issuing client is required")
+		}
+	case ResourcePayout, ResourceConversion, ResourceDeposit, ResourceBeneficiary:
+		if s.banking == nil {
+			return fmt.Errorf("banking client is required")
+		}
+	default:
+		return fmt.Errorf("unsupported resource type %q", resourceType)
+	}
+
+	since := s.now().Add(-s.opts.Lookback)
+	for page := 1; ; page++ {
+		var records []*Record
+		var totalPages int
+		switch resourceType {
+		case ResourceCard:
+			resp, err := s.issuing.Cards.List(ctx, &issuing.ListCardsRequest{PageSize: s.opts.PageSize, PageNumber: page}, s.opts.RequestOptions)
+			if err != nil {
+				return err
+			}
+			totalPages = resp.TotalPages
+			for i := range resp.Data {
+				records = append(records, cardRecord(&resp.Data[i]))
+			}
+		case ResourcePayout:
+			req := &banking.ListPayoutsRequest{PageSize: s.opts.PageSize, PageNumber: page}
+			if s.opts.Lookback > 0 {
+				req.StartTime = since.UTC().Format(time.RFC3339)
+			}
+			resp, err := s.banking.Payouts.List(ctx, req, s.opts.RequestOptions)
+			if err != nil {
+				return err
+			}
+			totalPages = resp.TotalPages
+			for i := range resp.Data {
+				records = append(records, payoutRecord(&resp.Data[i]))
+			}
+		case ResourceConversion:
+			req := &banking.ListConversionsRequest{PageSize: s.opts.PageSize, PageNumber: page}
+			if s.opts.Lookback > 0 {
+				req.StartTime = since.UnixMilli()
+			}
+			resp, err := s.banking.Conversions.List(ctx, req, s.opts.RequestOptions)
+			if err != nil {
+				return err
+			}
+			totalPages = resp.TotalPages
+			for i := range resp.Data {
+				records = append(records, apiConversionRecord(&resp.Data[i]))
+			}
+		case ResourceDeposit:
+			req := &banking.ListDepositsRequest{PageSize: s.opts.PageSize, PageNumber: page}
+			if s.opts.Lookback > 0 {
+				req.StartTime = since.UTC().Format(time.RFC3339)
+			}
+			resp, err := s.banking.Deposits.List(ctx, req, s.opts.RequestOptions)
+			if err != nil {
+				return err
+			}
+			totalPages = resp.TotalPages
+			for i := range resp.Data {
+				records = append(records, apiDepositRecord(&resp.Data[i]))
+			}
+		case ResourceBeneficiary:
+			resp, err := s.banking.Beneficiaries.List(ctx, &banking.ListBeneficiariesRequest{PageSize: s.opts.PageSize, PageNumber: page}, s.opts.RequestOptions)
+			if err != nil {
+				return err
+			}
+			totalPages = resp.TotalPages
+			for i := range resp.Data {
+				records = append(records, beneficiaryRecord(&resp.Data[i]))
+			}
+		}
+
+		for _, record := range records {
+			if record.ID == "" {
+				continue
+			}
+			if err := visit(record); err != nil {
+				return err
+			}
+		}
+		if page >= totalPages || len(records) == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package mirror keeps a local copy of UQPAY resources up to date.
+//
+// A Syncer applies verified webhook events to a Store as they arrive and
+// periodically backfills from the List endpoints, which heals missed or
+// failed webhook deliveries. Whenever the API disagrees with state that was
+// last written by a webhook, the difference is reported as a Drift.
+//
+// Example usage:
+//
+//	syncer := mirror.NewSyncer(store, client.Issuing, client.Banking, mirror.Options{
+//	    OnDrift: func(d mirror.Drift) { log.Printf("drift: %+v", d) },
+//	})
+//	go syncer.Run(ctx)
+//
+//	// in the webhook handler, after verification:
+//	if _, err := syncer.HandleEvent(ctx, event); err != nil {
+//	    // retry later
+//	}
+package mirror
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ResourceType identifies the kind of mirrored resource.
+type ResourceType string
+
+const (
+	ResourceCard        ResourceType = "card"
+	ResourcePayout      ResourceType = "payout"
+	ResourceConversion  ResourceType = "conversion"
+	ResourceDeposit     ResourceType = "deposit"
+	ResourceBeneficiary ResourceType = "beneficiary"
+)
+
+// ResourceTypes lists every resource type a Syncer can mirror.
+var ResourceTypes = []ResourceType{
+	ResourceCard,
+	ResourcePayout,
+	ResourceConversion,
+	ResourceDeposit,
+	ResourceBeneficiary,
+}
+
+// Source records where the state of a Record last came from.
+type Source string
+
+const (
+	SourceWebhook Source = "webhook"
+	SourceAPI     Source = "api"
+)
+
+// Record is the mirrored state of one resource.
+type Record struct {
+	Type ResourceType
+	ID   string
+
+	// Status, Amount and Currency are the fields compared for drift. Amount
+	// and Currency are empty for resource types without a single amount.
+	Status   string
+	Amount   string
+	Currency string
+
+	Source Source
+	// EventID is the webhook event that last updated the record, if Source is
+	// SourceWebhook.
+	EventID string
+	// UpdatedAt is the resource's own update time, when the webhook payload or
+	// API response carries one. It orders writes: state older than the stored
+	// record is not applied. Zero means unknown.
+	UpdatedAt time.Time
+	SyncedAt  time.Time
+
+	// Data is the typed payload the record was built from, for example
+	// *webhook.PayoutData for a webhook or *banking.Payout from the API.
+	Data interface{}
+}
+
+// Store persists mirrored records. Get returns nil and no error when no record
+// exists. Implementations must be safe for concurrent use.
+type Store interface {
+	Get(ctx context.Context, resourceType ResourceType, id string) (*Record, error)
+	Upsert(ctx context.Context, record *Record) error
+}
+
+// MemoryStore is an in-memory Store, useful for tests and small services.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records map[ResourceType]map[string]*Record
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: map[ResourceType]map[string]*Record{}}
+}
+
+// Get returns a copy of the stored record.
+func (s *MemoryStore) Get(ctx context.Context, resourceType ResourceType, id string) (*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	record, ok := s.records[resourceType][id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// Upsert stores a copy of record.
+func (s *MemoryStore) Upsert(ctx context.Context, record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	byID := s.records[record.Type]
+	if byID == nil {
+		byID = map[string]*Record{}
+		s.records[record.Type] = byID
+	}
+	copied := *record
+	byID[record.ID] = &copied
+	return nil
+}
+
+// Len returns the number of records stored for resourceType.
+func (s *MemoryStore) Len(resourceType ResourceType) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records[resourceType])
+}
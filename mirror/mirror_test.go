@@ -0,0 +1,223 @@
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/banking"
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+	"github.com/uqpay/uqpay-sdk-go/v2/configuration"
+	"github.com/uqpay/uqpay-sdk-go/v2/issuing"
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+type staticTokenProvider struct{}
+
+func (*staticTokenProvider) GetToken() (string, error) { return "token_123", nil }
+
+func newTestSyncer(t *testing.T, store Store, opts Options) *Syncer {
+	t.Helper()
+	pages := map[string]string{
+		"/v1/issuing/cards": `{"total_pages":1,"total_items":1,"data":[{"card_id":"card-001","card_status":"ACTIVE","card_currency":"USD"}]}`,
+		"/v1/payouts":       `{"total_pages":1,"total_items":2,"data":[{"payout_id":"payout-001","payout_status":"COMPLETED","payout_amount":"100.00","payout_currency":"USD"},{"payout_id":"payout-002","payout_status":"PENDING","payout_amount":"5.00","payout_currency":"SGD"}]}`,
+		"/v1/conversion":    `{"total_pages":1,"total_items":0,"data":[]}`,
+		"/v1/deposit":       `{"total_pages":1,"total_items":0,"data":[]}`,
+		"/v1/beneficiaries": `{"total_pages":1,"total_items":0,"data":[]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	config := &configuration.Configuration{
+		Environment: &configuration.Environment{BaseURL: server.URL},
+		HTTPClient:  server.Client(),
+	}
+	apiClient := common.NewAPIClient(config, &staticTokenProvider{})
+	return NewSyncer(store, issuing.NewClient(apiClient), banking.NewClient(apiClient), opts)
+}
+
+func payoutEvent(t *testing.T, status, amount string) *webhook.Event {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{
+		"payout_id":       "payout-001",
+		"status":          status,
+		"payout_amount":   amount,
+		"payout_currency": "USD",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &webhook.Event{
+		Version:   "V1.6.0",
+		EventName: webhook.EventNamePayout,
+		EventType: webhook.EventTypePayoutReadySend,
+		EventID:   "evt-payout-" + status,
+		SourceID:  "payout-001",
+		Data:      data,
+	}
+}
+
+func TestHandleEventUpsertsTypedRecords(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	syncer := newTestSyncer(t, store, Options{})
+
+	record, err := syncer.HandleEvent(ctx, payoutEvent(t, "READY_TO_SEND", "100.00"))
+	if err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+	if record.Type != ResourcePayout || record.Status != "READY_TO_SEND" || record.Source != SourceWebhook {
+		t.Fatalf("record = %+v", record)
+	}
+	if _, ok := record.Data.(*webhook.PayoutData); !ok {
+		t.Fatalf("record data = %T, want *webhook.PayoutData", record.Data)
+	}
+
+	status := &webhook.Event{
+		Version:   "V1.6.0",
+		EventType: webhook.EventTypeCardStatusUpdateSucceeded,
+		EventID:   "evt-card",
+		Data:      json.RawMessage(`{"card_id":"card-001","card_status":"FROZEN"}`),
+	}
+	if _, err := syncer.HandleEvent(ctx, status); err != nil {
+		t.Fatalf("HandleEvent(card): %v", err)
+	}
+	card, _ := store.Get(ctx, ResourceCard, "card-001")
+	if card == nil || card.Status != "FROZEN" || card.EventID != "evt-card" {
+		t.Fatalf("card record = %+v", card)
+	}
+
+	unrelated := &webhook.Event{Version: "V1.6.0", EventType: "future.event.added", Data: json.RawMessage(`{}`)}
+	if record, err := syncer.HandleEvent(ctx, unrelated); record != nil || err != nil {
+		t.Fatalf("HandleEvent(unrelated) = %+v, %v; want nil, nil", record, err)
+	}
+}
+
+func TestBackfillHealsMissedWebhooksAndReportsDrift(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	var reported []Drift
+	syncer := newTestSyncer(t, store, Options{OnDrift: func(d Drift) { reported = append(reported, d) }})
+
+	if _, err := syncer.HandleEvent(ctx, payoutEvent(t, "READY_TO_SEND", "100.00")); err != nil {
+		t.Fatalf("HandleEvent: %v", err)
+	}
+
+	report, err := syncer.Backfill(ctx)
+	if err != nil {
+		t.Fatalf("Backfill: %v", err)
+	}
+	if report.Fetched[ResourcePayout] != 2 || report.Fetched[ResourceCard] != 1 {
+		t.Fatalf("fetched = %v", report.Fetched)
+	}
+	if len(reported) != len(report.Drifts) {
+		t.Fatalf("OnDrift saw %d drifts, report has %d", len(reported), len(report.Drifts))
+	}
+
+	drifts := map[string]Drift{}
+	for _, drift := range report.Drifts {
+		drifts[string(drift.Type)+"/"+drift.ID+"/"+string(drift.Kind)] = drift
+	}
+	mismatch, ok := drifts["payout/payout-001/mismatch"]
+	if !ok || mismatch.Field != "status" || mismatch.Local != "READY_TO_SEND" || mismatch.Remote != "COMPLETED" || mismatch.EventID != "evt-payout-READY_TO_SEND" {
+		t.Fatalf("payout-001 drift = %+v (all: %+v)", mismatch, report.Drifts)
+	}
+	if _, ok := drifts["payout/payout-002/missing"]; !ok {
+		t.Errorf("missed payout-002 webhook was not reported: %+v", report.Drifts)
+	}
+	if _, ok := drifts["card/card-001/missing"]; !ok {
+		t.Errorf("missed card-001 webhook was not reported: %+v", report.Drifts)
+	}
+
+	healed, _ := store.Get(ctx, ResourcePayout, "payout-002")
+	if healed == nil || healed.Source != SourceAPI || healed.Status != "PENDING" {
+		t.Fatalf("payout-002 = %+v", healed)
+	}
+	if _, ok := healed.Data.(*banking.Payout); !ok {
+		t.Fatalf("backfilled data = %T, want *banking.Payout", healed.Data)
+	}
+
+	second, err := syncer.Backfill(ctx)
+	if err != nil {
+		t.Fatalf("second Backfill: %v", err)
+	}
+	if len(second.Drifts) != 0 {
+		t.Fatalf("second backfill drifts = %+v, want none", second.Drifts)
+	}
+}
+
+func TestHandleEventSkipsRedeliveredAndStaleEvents(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	syncer := newTestSyncer(t, store, Options{})
+
+	cardEvent := func(id, status, updated string) *webhook.Event {
+		return &webhook.Event{
+			Version:   "V1.6.0",
+			EventType: webhook.EventTypeCardStatusUpdateSucceeded,
+			EventID:   id,
+			Data:      json.RawMessage(`{"card_id":"card-001","card_status":"` + status + `","update_time":"` + updated + `"}`),
+		}
+	}
+	if _, err := syncer.HandleEvent(ctx, cardEvent("evt-2", "FROZEN", "2026-01-26T15:30:00+08:00")); err != nil {
+		t.Fatal(err)
+	}
+	record, err := syncer.HandleEvent(ctx, cardEvent("evt-1", "ACTIVE", "2026-01-26T15:29:00+08:00"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != "FROZEN" || record.EventID != "evt-2" {
+		t.Fatalf("stale event was applied: %+v", record)
+	}
+	stored, _ := store.Get(ctx, ResourceCard, "card-001")
+	syncedAt := stored.SyncedAt
+	if _, err := syncer.HandleEvent(ctx, cardEvent("evt-2", "FROZEN", "2026-01-26T15:30:00+08:00")); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ = store.Get(ctx, ResourceCard, "card-001"); stored.Status != "FROZEN" || !stored.SyncedAt.Equal(syncedAt) {
+		t.Fatalf("redelivery rewrote the record: %+v", stored)
+	}
+}
+
+func TestBackfillKeepsWebhookWrittenDuringBackfill(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	syncer := newTestSyncer(t, store, Options{Resources: []ResourceType{ResourcePayout}})
+	started := time.Date(2026, 1, 26, 12, 0, 0, 0, time.UTC)
+	syncer.now = func() time.Time { return started }
+
+	_ = store.Upsert(ctx, &Record{Type: ResourcePayout, ID: "payout-001", Status: "FAILED", Amount: "100.00", Currency: "USD",
+		Source: SourceWebhook, EventID: "evt-late", SyncedAt: started.Add(time.Second)})
+	report, err := syncer.Backfill(ctx)
+	if err != nil {
+		t.Fatalf("Backfill: %v", err)
+	}
+	if len(report.Drifts) != 1 || report.Drifts[0].ID != "payout-002" {
+		t.Fatalf("drifts = %+v", report.Drifts)
+	}
+	if record, _ := store.Get(ctx, ResourcePayout, "payout-001"); record.Status != "FAILED" || record.Source != SourceWebhook {
+		t.Fatalf("backfill overwrote a newer webhook: %+v", record)
+	}
+}
+
+func TestCompareTreatsAmountsAsDecimals(t *testing.T) {
+	local := &Record{Type: ResourcePayout, ID: "payout-001", Amount: "5000.00", Source: SourceWebhook}
+	if drifts := compare(local, &Record{Type: ResourcePayout, ID: "payout-001", Amount: "5000"}); len(drifts) != 0 {
+		t.Fatalf("drifts = %+v, want none", drifts)
+	}
+	if drifts := compare(local, &Record{Type: ResourcePayout, ID: "payout-001", Amount: "5000.01"}); len(drifts) != 1 {
+		t.Fatalf("drifts = %+v, want one", drifts)
+	}
+}
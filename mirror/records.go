@@ -0,0 +1,91 @@
+package mirror
+
+import (
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/banking"
+	"github.com/uqpay/uqpay-sdk-go/v2/issuing"
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+// recordFromPayload builds a record from a decoded webhook payload. It returns
+// nil for payloads of resources that are not mirrored.
+func recordFromPayload(payload interface{}) *Record {
+	switch data := payload.(type) {
+	case *webhook.CardData:
+		return &Record{Type: ResourceCard, ID: data.CardID, Status: data.CardStatus, Currency: data.CardCurrency, Data: data}
+	case *webhook.CardLifecycleData:
+		return &Record{Type: ResourceCard, ID: data.CardID, Status: data.CardStatus, UpdatedAt: parseTime(data.UpdateTime), Data: data}
+	case *webhook.CardStatusUpdateData:
+		return &Record{Type: ResourceCard, ID: data.CardID, Status: data.CardStatus, UpdatedAt: parseTime(data.UpdateTime), Data: data}
+	case *webhook.PayoutData:
+		return &Record{Type: ResourcePayout, ID: data.PayoutID, Status: data.Status, Amount: data.PayoutAmount, Currency: data.PayoutCurrency, Data: data}
+	case *webhook.ConversionData:
+		return conversionRecord(data, data)
+	case *webhook.ConversionFundsAwaitingData:
+		return conversionRecord(&data.ConversionData, data)
+	case *webhook.ConversionFundsArrivedData:
+		return conversionRecord(&data.ConversionData, data)
+	case *webhook.DepositPendingData:
+		return depositRecord(&data.DepositData, data)
+	case *webhook.DepositComplianceRejectedData:
+		return depositRecord(&data.DepositData, data)
+	case *webhook.DepositCompletedData:
+		return depositRecord(&data.DepositData, data)
+	case *webhook.BeneficiaryData:
+		return &Record{Type: ResourceBeneficiary, ID: data.BeneficiaryID, Status: data.BeneficiaryStatus, Currency: data.AccountCurrencyCode, Data: data}
+	}
+	return nil
+}
+
+func conversionRecord(data *webhook.ConversionData, payload interface{}) *Record {
+	return &Record{Type: ResourceConversion, ID: data.ConversionID, Status: data.ConversionStatus, Amount: data.SellAmount, Currency: data.SellCurrency, Data: payload}
+}
+
+func depositRecord(data *webhook.DepositData, payload interface{}) *Record {
+	return &Record{Type: ResourceDeposit, ID: data.DepositID, Status: data.DepositStatus, Amount: data.DepositAmount, Currency: data.DepositCurrency, UpdatedAt: parseTime(data.UpdateTime), Data: payload}
+}
+
+func cardRecord(card *issuing.RetrieveCardResponse) *Record {
+	return &Record{Type: ResourceCard, ID: card.CardID, Status: card.CardStatus, Currency: card.CardCurrency, Data: card}
+}
+
+func payoutRecord(payout *banking.Payout) *Record {
+	return &Record{Type: ResourcePayout, ID: payout.PayoutID, Status: payout.PayoutStatus, Amount: payout.PayoutAmount, Currency: payout.PayoutCurrency, UpdatedAt: parseTime(payout.UpdateTime), Data: payout}
+}
+
+func apiConversionRecord(conversion *banking.Conversion) *Record {
+	return &Record{Type: ResourceConversion, ID: conversion.ConversionID, Status: conversion.ConversionStatus, Amount: conversion.SellAmount, Currency: conversion.SellCurrency, Data: conversion}
+}
+
+func apiDepositRecord(deposit *banking.Deposit) *Record {
+	return &Record{Type: ResourceDeposit, ID: deposit.DepositID, Status: deposit.DepositStatus, Amount: deposit.Amount, Currency: deposit.Currency, Data: deposit}
+}
+
+// Beneficiary webhooks report the outcome of creation (SUCCESSFUL, FAILED,
+// PENDING) while the API reports the lifecycle state (active, inactive,
+// deleted), so the status is stored but not compared for drift.
+func beneficiaryRecord(beneficiary *banking.Beneficiary) *Record {
+	record := &Record{Type: ResourceBeneficiary, ID: beneficiary.BeneficiaryID, Status: beneficiary.Status, UpdatedAt: parseTime(beneficiary.UpdateTime), Data: beneficiary}
+	if beneficiary.BankDetails != nil {
+		record.Currency = beneficiary.BankDetails.AccountCurrencyCode
+	}
+	return record
+}
+
+// olderThan reports whether r is known to predate other. Records without an
+// update time are never considered older.
+func (r *Record) olderThan(other *Record) bool {
+	return !r.UpdatedAt.IsZero() && !other.UpdatedAt.IsZero() && r.UpdatedAt.Before(other.UpdatedAt)
+}
+
+// parseTime parses the timestamp formats used by webhook payloads and the
+// API. Unparsable or empty values return the zero time.
+func parseTime(value string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
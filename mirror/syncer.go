@@ -0,0 +1,277 @@
+package mirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/banking"
+	"github.com/uqpay/uqpay-sdk-go/v2/common"
+	"github.com/uqpay/uqpay-sdk-go/v2/issuing"
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+const (
+	// DefaultBackfillInterval is how often Run backfills from the API.
+	DefaultBackfillInterval = 15 * time.Minute
+	defaultPageSize         = 100
+)
+
+// DriftKind classifies a Drift.
+type DriftKind string
+
+const (
+	// DriftMissing means the API returned a resource the mirror did not hold,
+	// usually because its webhook was never delivered.
+	DriftMissing DriftKind = "missing"
+	// DriftMismatch means a field written by a webhook differs from the API.
+	DriftMismatch DriftKind = "mismatch"
+)
+
+// Drift is a difference between the mirrored state and the API found during a
+// backfill. The mirror is updated to the API state after it is reported.
+type Drift struct {
+	Kind  DriftKind
+	Type  ResourceType
+	ID    string
+	Field string
+	// Local is the mirrored value and Remote the API value. Both are empty
+	// for DriftMissing.
+	Local  string
+	Remote string
+	// EventID is the webhook event that produced the mirrored value.
+	EventID string
+}
+
+// BackfillReport summarises one Backfill run.
+type BackfillReport struct {
+	StartedAt time.Time
+	// Fetched counts the resources returned by the API per resource type.
+	Fetched map[ResourceType]int
+	Drifts  []Drift
+}
+
+// Options configures a Syncer.
+type Options struct {
+	// Resources limits the mirrored resource types. Empty mirrors all of
+	// ResourceTypes.
+	Resources []ResourceType
+	// BackfillInterval is the period between backfills in Run.
+	// Zero uses DefaultBackfillInterval.
+	BackfillInterval time.Duration
+	// Lookback limits backfills of payouts, deposits and conversions to
+	// resources created within this window. Zero lists everything. Cards and
+	// beneficiaries cannot be filtered by time and are always fully listed.
+	Lookback time.Duration
+	// PageSize is the List page size, between 10 and 100. Zero uses 100.
+	PageSize int
+	// RequestOptions is passed to every API call, e.g. to mirror a connected
+	// account with OnBehalfOf.
+	RequestOptions *common.RequestOptions
+	// OnDrift is called for every difference found during a backfill.
+	OnDrift func(Drift)
+	// OnError is called when a backfill in Run fails.
+	OnError func(error)
+}
+
+// Syncer mirrors resources into a Store from webhook events and periodic
+// backfills.
+type Syncer struct {
+	store   Store
+	issuing *issuing.Client
+	banking *banking.Client
+	opts    Options
+	now     func() time.Time
+}
+
+// NewSyncer creates a syncer writing to store. Either client may be nil if the
+// corresponding resources are not mirrored.
+func NewSyncer(store Store, issuingClient *issuing.Client, bankingClient *banking.Client, opts Options) *Syncer {
+	if len(opts.Resources) == 0 {
+		opts.Resources = ResourceTypes
+	}
+	if opts.BackfillInterval <= 0 {
+		opts.BackfillInterval = DefaultBackfillInterval
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = defaultPageSize
+	}
+	return &Syncer{
+		store:   store,
+		issuing: issuingClient,
+		banking: bankingClient,
+		opts:    opts,
+		now:     time.Now,
+	}
+}
+
+// HandleEvent applies a verified webhook event to the store and returns the
+// updated record. Events for resources that are not mirrored return nil and
+// no error. Fields the event does not carry keep their mirrored values.
+//
+// A redelivery of the event that last wrote the record, or an event whose
+// update time is older than the stored record's, is not applied; the stored
+// record is returned unchanged.
+func (s *Syncer) HandleEvent(ctx context.Context, event *webhook.Event) (*Record, error) {
+	payload, err := event.Payload()
+	if errors.Is(err, webhook.ErrUnregisteredEventType) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode webhook event %s: %w", event.EventID, err)
+	}
+	record := recordFromPayload(payload)
+	if record == nil || record.ID == "" || !s.mirrors(record.Type) {
+		return nil, nil
+	}
+
+	existing, err := s.store.Get(ctx, record.Type, record.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s: %w", record.Type, record.ID, err)
+	}
+	if existing != nil {
+		if (existing.Source == SourceWebhook && existing.EventID == event.EventID) || record.olderThan(existing) {
+			return existing, nil
+		}
+		record.Status = firstNonEmpty(record.Status, existing.Status)
+		record.Amount = firstNonEmpty(record.Amount, existing.Amount)
+		record.Currency = firstNonEmpty(record.Currency, existing.Currency)
+	}
+	record.Source = SourceWebhook
+	record.EventID = event.EventID
+	record.SyncedAt = s.now()
+	if err := s.store.Upsert(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to upsert %s %s: %w", record.Type, record.ID, err)
+	}
+	return record, nil
+}
+
+// Backfill lists every mirrored resource type from the API, reports drift
+// against the store and upserts the API state.
+func (s *Syncer) Backfill(ctx context.Context) (*BackfillReport, error) {
+	report := &BackfillReport{StartedAt: s.now(), Fetched: map[ResourceType]int{}}
+	for _, resourceType := range s.opts.Resources {
+		err := s.list(ctx, resourceType, func(record *Record) error {
+			report.Fetched[resourceType]++
+			return s.reconcile(ctx, record, report)
+		})
+		if err != nil {
+			return report, fmt.Errorf("failed to backfill %ss: %w", resourceType, err)
+		}
+	}
+	return report, nil
+}
+
+// Run backfills immediately and then every BackfillInterval until ctx is done.
+// Backfill errors are passed to OnError and do not stop the loop.
+func (s *Syncer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.opts.BackfillInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.Backfill(ctx); err != nil && s.opts.OnError != nil && ctx.Err() == nil {
+			s.opts.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Syncer) reconcile(ctx context.Context, record *Record, report *BackfillReport) error {
+	existing, err := s.store.Get(ctx, record.Type, record.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get %s %s: %w", record.Type, record.ID, err)
+	}
+	if existing != nil && (record.olderThan(existing) || (existing.Source == SourceWebhook && existing.SyncedAt.After(report.StartedAt))) {
+		// A webhook written since the backfill started is newer than the
+		// listed state; the next backfill compares against it.
+		return nil
+	}
+	for _, drift := range compare(existing, record) {
+		report.Drifts = append(report.Drifts, drift)
+		if s.opts.OnDrift != nil {
+			s.opts.OnDrift(drift)
+		}
+	}
+	record.Source = SourceAPI
+	record.SyncedAt = s.now()
+	if err := s.store.Upsert(ctx, record); err != nil {
+		return fmt.Errorf("failed to upsert %s %s: %w", record.Type, record.ID, err)
+	}
+	return nil
+}
+
+// compare returns the drift between the mirrored record and the API record.
+// Only state written by a webhook is compared, since earlier API state is
+// expected to change between backfills.
+func compare(local, remote *Record) []Drift {
+	if local == nil {
+		return []Drift{{Kind: DriftMissing, Type: remote.Type, ID: remote.ID}}
+	}
+	if local.Source != SourceWebhook {
+		return nil
+	}
+	fields := []struct {
+		name          string
+		local, remote string
+	}{
+		{"status", local.Status, remote.Status},
+		{"amount", local.Amount, remote.Amount},
+		{"currency", local.Currency, remote.Currency},
+	}
+	var drifts []Drift
+	for _, field := range fields {
+		if field.name == "status" && remote.Type == ResourceBeneficiary {
+			continue
+		}
+		if field.local == "" || field.remote == "" || field.local == field.remote {
+			continue
+		}
+		if field.name == "amount" && amountsEqual(field.local, field.remote) {
+			continue
+		}
+		drifts = append(drifts, Drift{
+			Kind:    DriftMismatch,
+			Type:    remote.Type,
+			ID:      remote.ID,
+			Field:   field.name,
+			Local:   field.local,
+			Remote:  field.remote,
+			EventID: local.EventID,
+		})
+	}
+	return drifts
+}
+
+// amountsEqual compares decimal amounts, so "5000.00" equals "5000".
+func amountsEqual(a, b string) bool {
+	x, ok := new(big.Rat).SetString(strings.TrimSpace(a))
+	if !ok {
+		return false
+	}
+	y, ok := new(big.Rat).SetString(strings.TrimSpace(b))
+	return ok && x.Cmp(y) == 0
+}
+
+func (s *Syncer) mirrors(resourceType ResourceType) bool {
+	for _, mirrored := range s.opts.Resources {
+		if mirrored == resourceType {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
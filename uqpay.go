@@ -1,6 +1,7 @@
 package uqpay
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/uqpay/uqpay-sdk-go/v2/auth"
@@ -26,17 +27,37 @@ type Client struct {
 
 // NewClient creates a new UQPAY client
 func NewClient(clientID, apiKey string, env *configuration.Environment) (*Client, error) {
-	config := &configuration.Configuration{
+	return NewClientWithConfig(&configuration.Configuration{
 		ClientID:    clientID,
 		APIKey:      apiKey,
 		Environment: env,
 		HTTPClient:  &http.Client{},
+	})
+}
+
+// NewClientWithConfig creates a new UQPAY client from a full configuration.
+// Set APIKeySource to load the API key from an environment variable, file or
+// secret manager instead of passing it inline.
+func NewClientWithConfig(config *configuration.Configuration) (*Client, error) {
+	if config == nil || config.Environment == nil {
+		return nil, errors.New("configuration with an environment is required")
+	}
+	// Work on a shallow copy so the caller's configuration is left untouched.
+	cfg := *config
+	config = &cfg
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{}
+	}
+	apiKey := config.APIKeySource
+	if apiKey == nil {
+		apiKey = configuration.StaticKey(config.APIKey)
 	}
+	env := config.Environment
 
 	// Create token provider
-	tokenProvider := auth.NewTokenProvider(
+	tokenProvider := auth.NewTokenProviderFromSource(
 		env.BaseURL,
-		clientID,
+		config.ClientID,
 		apiKey,
 		config.HTTPClient,
 	)
@@ -46,14 +67,15 @@ func NewClient(clientID, apiKey string, env *configuration.Environment) (*Client
 
 	// Create separate configuration for Files API (different base URL)
 	filesConfig := &configuration.Configuration{
-		ClientID:    clientID,
-		APIKey:      apiKey,
-		Environment: &configuration.Environment{BaseURL: env.FilesBaseURL},
-		HTTPClient:  &http.Client{},
+		ClientID:     config.ClientID,
+		APIKey:       config.APIKey,
+		Environment:  &configuration.Environment{BaseURL: env.FilesBaseURL},
+		HTTPClient:   &http.Client{},
+		APIKeySource: config.APIKeySource,
 	}
-	filesTokenProvider := auth.NewTokenProvider(
+	filesTokenProvider := auth.NewTokenProviderFromSource(
 		env.FilesBaseURL,
-		clientID,
+		config.ClientID,
 		apiKey,
 		filesConfig.HTTPClient,
 	)
@@ -49,6 +49,20 @@ type ConversionData struct {
 	ShortReferenceID string `json:"short_reference_id"`
 }
 
+// ConversionFundsAwaitingData represents a conversion that is waiting for the
+// sell-side funds to arrive.
+// This is returned in the data field for conversion.funds.awaiting events.
+type ConversionFundsAwaitingData struct {
+	ConversionData
+}
+
+// ConversionFundsArrivedData represents a conversion whose sell-side funds have
+// arrived and which is ready to settle.
+// This is returned in the data field for conversion.funds.arrived events.
+type ConversionFundsArrivedData struct {
+	ConversionData
+}
+
 // Conversion status constants
 const (
 	// ConversionStatusTradeSettled indicates the trade has been settled
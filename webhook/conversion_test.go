@@ -374,3 +374,28 @@ func TestParseConversionData_WrongEventType(t *testing.T) {
 		t.Error("ParseConversionData should fail for non-conversion event type")
 	}
 }
+
+func TestParseConversionFundsEventsAreDistinct(t *testing.T) {
+	var awaiting Event
+	if err := json.Unmarshal([]byte(conversionFundsAwaitingWebhookJSON), &awaiting); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	data, err := awaiting.ParseConversionFundsAwaitingData()
+	if err != nil {
+		t.Fatalf("ParseConversionFundsAwaitingData: %v", err)
+	}
+	if data.ConversionStatus != ConversionStatusAwaitingFunds || data.ConversionID != "CONV-2024-002" {
+		t.Errorf("awaiting data = %+v", data)
+	}
+	if _, err := awaiting.ParseConversionFundsArrivedData(); err == nil {
+		t.Error("expected ParseConversionFundsArrivedData to reject conversion.funds.awaiting")
+	}
+
+	arrived := Event{EventName: EventNameConversion, EventType: EventTypeConversionFundsArrived, Data: awaiting.Data}
+	if !arrived.IsConversionFundsArrivedEvent() || arrived.IsConversionFundsAwaitingEvent() {
+		t.Error("funds arrived helpers mismatch")
+	}
+	if _, err := arrived.ParseConversionFundsArrivedData(); err != nil {
+		t.Errorf("ParseConversionFundsArrivedData: %v", err)
+	}
+}
@@ -16,3 +16,32 @@ type DepositData struct {
 	DepositStatus    string `json:"deposit_status"`
 	DepositReference string `json:"deposit_reference"`
 }
+
+// DepositPendingData represents a deposit that has been received and is
+// awaiting processing or compliance review.
+// This is returned in the data field for deposit.pending events.
+type DepositPendingData struct {
+	DepositData
+}
+
+// DepositComplianceRejectedData represents a deposit rejected by compliance review.
+// This is returned in the data field for deposit.compliance.rejected events.
+type DepositComplianceRejectedData struct {
+	DepositData
+
+	// FailureReason explains why the deposit was rejected
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// DepositCompletedData represents a deposit credited to the account balance.
+// This is returned in the data field for deposit.completed events.
+type DepositCompletedData struct {
+	DepositData
+}
+
+// Deposit status constants
+const (
+	DepositStatusPending   = "PENDING"
+	DepositStatusRejected  = "REJECTED"
+	DepositStatusCompleted = "COMPLETED"
+)
@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseDepositEventsUseDedicatedTypes(t *testing.T) {
+	payload := json.RawMessage(`{
+		"direct_id": "0",
+		"account_id": "acc-123456",
+		"deposit_id": "dep-001",
+		"deposit_currency": "USD",
+		"deposit_amount": "5000.00",
+		"deposit_status": "REJECTED",
+		"failure_reason": "Sender name does not match account holder"
+	}`)
+
+	rejected := Event{EventName: EventNameDeposit, EventType: EventTypeDepositComplianceRejected, Data: payload}
+	data, err := rejected.ParseDepositComplianceRejectedData()
+	if err != nil {
+		t.Fatalf("ParseDepositComplianceRejectedData: %v", err)
+	}
+	if data.DepositID != "dep-001" || data.DepositStatus != DepositStatusRejected {
+		t.Errorf("rejected data = %+v", data)
+	}
+	if data.FailureReason != "Sender name does not match account holder" {
+		t.Errorf("failure reason = %q", data.FailureReason)
+	}
+	if _, err := rejected.ParseDepositPendingData(); err == nil {
+		t.Error("expected ParseDepositPendingData to reject deposit.compliance.rejected")
+	}
+
+	pending := Event{EventName: EventNameDeposit, EventType: EventTypeDepositPending, Data: payload}
+	if !pending.IsDepositPendingEvent() || pending.IsDepositComplianceRejectedEvent() {
+		t.Error("deposit pending helpers mismatch")
+	}
+	if _, err := pending.ParseDepositPendingData(); err != nil {
+		t.Errorf("ParseDepositPendingData: %v", err)
+	}
+	completed := Event{EventName: EventNameDeposit, EventType: EventTypeDepositCompleted, Data: payload}
+	if data := completed.MustParseDepositCompletedData(); data.DepositAmount != "5000.00" {
+		t.Errorf("completed amount = %q", data.DepositAmount)
+	}
+	if _, err := completed.ParseDepositData(); err != nil {
+		t.Errorf("ParseDepositData remains available: %v", err)
+	}
+}
@@ -193,6 +193,9 @@ type CardRechargeData struct {
 	// OrderStatus is the status of the recharge order (e.g., "SUCCESS", "FAILED")
 	OrderStatus string `json:"order_status"`
 
+	// CardOrderID is the unique identifier for the recharge order
+	CardOrderID string `json:"card_order_id,omitempty"`
+
 	// CompleteTime is the timestamp when the recharge was completed
 	CompleteTime string `json:"complete_time,omitempty"`
 
@@ -201,7 +204,8 @@ type CardRechargeData struct {
 }
 
 // CardTransactionData represents card transaction information in issuing webhook events.
-// This is returned in the data field for issuing.fee.card and other transaction events.
+// This is returned in the data field for issuing.fee.card events, and holds the
+// fields shared by every issuing.*.card transaction event.
 type CardTransactionData struct {
 	// CardID is the unique identifier for the card
 	CardID string `json:"card_id"`
@@ -249,6 +253,119 @@ type CardTransactionData struct {
 	Remark string `json:"remark,omitempty"`
 }
 
+// CardLifecycleData represents the card information in card lifecycle webhook events.
+// This is returned in the data field for card.activated, card.suspended and card.closed events.
+type CardLifecycleData struct {
+	// CardID is the unique identifier for the card
+	CardID string `json:"card_id"`
+
+	// CardNumber is the masked card number (e.g., "49372418****4306")
+	CardNumber string `json:"card_number"`
+
+	// CardholderID is the unique identifier for the cardholder
+	CardholderID string `json:"cardholder_id,omitempty"`
+
+	// CardStatus is the status of the card after the lifecycle change
+	CardStatus string `json:"card_status"`
+
+	// UpdateReason is the reason for the lifecycle change
+	UpdateReason string `json:"update_reason,omitempty"`
+
+	// UpdateTime is the timestamp when the lifecycle change took effect
+	UpdateTime string `json:"update_time,omitempty"`
+}
+
+// CardMerchantData represents the merchant details attached to card transaction events
+type CardMerchantData struct {
+	// CategoryCode is the merchant category code (MCC)
+	CategoryCode string `json:"category_code,omitempty"`
+
+	// City is the merchant city
+	City string `json:"city,omitempty"`
+
+	// Country is the merchant country code
+	Country string `json:"country,omitempty"`
+
+	// Name is the merchant name
+	Name string `json:"name,omitempty"`
+}
+
+// CardAuthorizationData represents an authorization in issuing webhook events.
+// This is returned in the data field for issuing.authorization.card events,
+// including declined authorizations.
+type CardAuthorizationData struct {
+	CardTransactionData
+
+	// AuthorizationCode is the approval code returned to the acquirer
+	AuthorizationCode string `json:"authorization_code,omitempty"`
+
+	// MerchantData contains the merchant details
+	MerchantData *CardMerchantData `json:"merchant_data,omitempty"`
+
+	// WalletType is the token wallet used, if any (e.g., "APPLE_PAY")
+	WalletType string `json:"wallet_type,omitempty"`
+
+	// TransactionFee is the fee charged for the authorization
+	TransactionFee string `json:"transaction_fee,omitempty"`
+
+	// TransactionFeeCurrency is the ISO 4217 currency code for the fee
+	TransactionFeeCurrency string `json:"transaction_fee_currency,omitempty"`
+
+	// FailureReason explains why a declined authorization was declined
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// CardReversalData represents an authorization reversal in issuing webhook events.
+// This is returned in the data field for issuing.reversal.card events.
+type CardReversalData struct {
+	CardTransactionData
+
+	// OriginalTransactionID is the ID of the authorization being reversed
+	OriginalTransactionID string `json:"original_transaction_id"`
+
+	// AuthorizationCode is the approval code of the reversed authorization
+	AuthorizationCode string `json:"authorization_code,omitempty"`
+
+	// MerchantData contains the merchant details
+	MerchantData *CardMerchantData `json:"merchant_data,omitempty"`
+}
+
+// CardClearingData represents a cleared (settled) card transaction in issuing webhook events.
+// This is returned in the data field for issuing.clearing.card events.
+type CardClearingData struct {
+	CardTransactionData
+
+	// OriginalTransactionID is the ID of the authorization being cleared
+	OriginalTransactionID string `json:"original_transaction_id,omitempty"`
+
+	// AuthorizationCode is the approval code of the cleared authorization
+	AuthorizationCode string `json:"authorization_code,omitempty"`
+
+	// MerchantData contains the merchant details
+	MerchantData *CardMerchantData `json:"merchant_data,omitempty"`
+
+	// TransactionFee is the fee charged at clearing
+	TransactionFee string `json:"transaction_fee,omitempty"`
+
+	// TransactionFeeCurrency is the ISO 4217 currency code for the fee
+	TransactionFeeCurrency string `json:"transaction_fee_currency,omitempty"`
+
+	// FeePassThrough indicates if the fee is passed through to the cardholder ("Y" or "N")
+	FeePassThrough string `json:"fee_pass_through,omitempty"`
+}
+
+// CardRefundData represents a merchant refund to a card in issuing webhook events.
+// This is returned in the data field for issuing.refund.card events.
+type CardRefundData struct {
+	CardTransactionData
+
+	// OriginalTransactionID is the ID of the purchase being refunded, if known
+	OriginalTransactionID string `json:"original_transaction_id,omitempty"`
+
+	// MerchantData contains the merchant details
+	MerchantData *CardMerchantData `json:"merchant_data,omitempty"`
+}
+
 // Card status constants
 const (
 	// CardStatusActive indicates the card is active and can be used
@@ -370,4 +487,13 @@ const (
 
 	// TransactionTypeTopup indicates a card top-up transaction
 	TransactionTypeTopup = "TOPUP"
+
+	// TransactionTypeAuthorization indicates an authorization transaction
+	TransactionTypeAuthorization = "AUTHORIZATION"
+
+	// TransactionTypeReversal indicates an authorization reversal transaction
+	TransactionTypeReversal = "REVERSAL"
+
+	// TransactionTypeClearing indicates a clearing (settlement) transaction
+	TransactionTypeClearing = "CLEARING"
 )
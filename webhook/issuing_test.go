@@ -635,3 +635,89 @@ func TestParseCardDataError(t *testing.T) {
 		t.Error("Expected error when parsing non-card event as card data")
 	}
 }
+
+func TestParseCardLifecycleEvents(t *testing.T) {
+	for eventType, status := range map[string]string{
+		EventTypeCardActivated: CardStatusActive,
+		EventTypeCardSuspended: CardStatusSuspended,
+		EventTypeCardClosed:    CardStatusClosed,
+	} {
+		event := Event{
+			EventName: EventNameIssuing,
+			EventType: eventType,
+			Data:      json.RawMessage(`{"card_id":"card-001","card_number":"49372418****4306","cardholder_id":"holder-001","card_status":"` + status + `","update_reason":"cardholder request"}`),
+		}
+		if !event.IsCardEvent() || !event.IsCardLifecycleEvent() {
+			t.Fatalf("%s: expected card lifecycle event", eventType)
+		}
+		data, err := event.ParseCardLifecycleData()
+		if err != nil {
+			t.Fatalf("%s: ParseCardLifecycleData: %v", eventType, err)
+		}
+		if data.CardID != "card-001" || data.CardStatus != status || data.CardholderID != "holder-001" {
+			t.Errorf("%s: data = %+v", eventType, data)
+		}
+	}
+
+	event := Event{EventType: EventTypeCardCreateSucceeded, Data: json.RawMessage(`{}`)}
+	if _, err := event.ParseCardLifecycleData(); err == nil {
+		t.Error("expected ParseCardLifecycleData to reject card.create.succeeded")
+	}
+}
+
+func TestParseCardTransactionLifecycleEvents(t *testing.T) {
+	payload := json.RawMessage(`{
+		"card_id": "card-001",
+		"card_number": "49372418****4306",
+		"cardholder_id": "holder-001",
+		"transaction_amount": "25.00",
+		"transaction_currency": "SGD",
+		"billing_amount": "18.60",
+		"billing_currency": "USD",
+		"transaction_status": "APPROVED",
+		"transaction_type": "AUTHORIZATION",
+		"authorization_code": "A1B2C3",
+		"original_transaction_id": "txn-auth-001",
+		"transaction_fee": "0.20",
+		"fee_pass_through": "N",
+		"wallet_type": "APPLE_PAY",
+		"merchant_data": {"category_code": "5812", "city": "SINGAPORE", "country": "SG", "name": "EXAMPLE CAFE"}
+	}`)
+
+	authorization := Event{EventName: EventNameIssuing, EventType: EventTypeIssuingAuthorizationCard, Data: payload}
+	authData, err := authorization.ParseCardAuthorizationData()
+	if err != nil {
+		t.Fatalf("ParseCardAuthorizationData: %v", err)
+	}
+	if authData.AuthorizationCode != "A1B2C3" || authData.WalletType != "APPLE_PAY" || authData.BillingAmount != "18.60" {
+		t.Errorf("authorization data = %+v", authData)
+	}
+	if authData.MerchantData == nil || authData.MerchantData.CategoryCode != "5812" {
+		t.Errorf("merchant data = %+v", authData.MerchantData)
+	}
+
+	reversal := Event{EventName: EventNameIssuing, EventType: EventTypeIssuingReversalCard, Data: payload}
+	if data := reversal.MustParseCardReversalData(); data.OriginalTransactionID != "txn-auth-001" {
+		t.Errorf("reversal original transaction = %q", data.OriginalTransactionID)
+	}
+	clearing := Event{EventName: EventNameIssuing, EventType: EventTypeIssuingClearingCard, Data: payload}
+	if data := clearing.MustParseCardClearingData(); data.TransactionFee != "0.20" || data.FeePassThrough != "N" {
+		t.Errorf("clearing fee = %q/%q", data.TransactionFee, data.FeePassThrough)
+	}
+	refund := Event{EventName: EventNameIssuing, EventType: EventTypeIssuingRefundCard, Data: payload}
+	if data := refund.MustParseCardRefundData(); data.CardID != "card-001" {
+		t.Errorf("refund card = %q", data.CardID)
+	}
+
+	for _, event := range []Event{authorization, reversal, clearing, refund} {
+		if !event.IsCardTransactionEvent() {
+			t.Errorf("%s: expected IsCardTransactionEvent", event.EventType)
+		}
+		if _, err := event.ParseCardTransactionData(); err != nil {
+			t.Errorf("%s: ParseCardTransactionData: %v", event.EventType, err)
+		}
+	}
+	if _, err := authorization.ParseCardClearingData(); err == nil {
+		t.Error("expected ParseCardClearingData to reject an authorization event")
+	}
+}
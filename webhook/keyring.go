@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultSecretLabel is the label NewVerifier gives its single secret.
+const DefaultSecretLabel = "default"
+
+// Secret is one webhook signing secret held in a Keyring. A zero NotBefore or
+// NotAfter leaves that side of the validity window open.
+type Secret struct {
+	// Label identifies the secret in Verification results, e.g. "2024-06".
+	Label     string
+	Value     string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// activeAt reports whether the secret may be used to verify a delivery at t.
+func (s Secret) activeAt(t time.Time) bool {
+	if !s.NotBefore.IsZero() && t.Before(s.NotBefore) {
+		return false
+	}
+	if !s.NotAfter.IsZero() && t.After(s.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// Keyring holds the webhook secrets a Verifier accepts. During a rotation add
+// the new secret next to the old one, then remove the old secret once
+// Verification.SecretLabel shows it no longer matches deliveries.
+//
+// Platforms that register a separate webhook endpoint per connected account
+// can store that account's secrets with SetAccountSecrets. A Keyring is safe
+// for concurrent use.
+type Keyring struct {
+	mu       sync.RWMutex
+	secrets  []Secret
+	accounts map[string][]Secret
+}
+
+// NewKeyring creates a keyring holding secrets.
+func NewKeyring(secrets ...Secret) *Keyring {
+	k := &Keyring{accounts: map[string][]Secret{}}
+	for _, secret := range secrets {
+		k.Add(secret)
+	}
+	return k
+}
+
+// Add adds secret to the platform-wide secrets, replacing any secret with the
+// same label.
+func (k *Keyring) Add(secret Secret) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.secrets = upsertSecret(k.secrets, secret)
+}
+
+// Remove removes the platform-wide secret with label.
+func (k *Keyring) Remove(label string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.secrets = removeSecret(k.secrets, label)
+}
+
+// SetAccountSecrets replaces the secrets used for deliveries to the connected
+// account accountID. Passing no secrets removes the account, so its
+// deliveries are verified with the platform-wide secrets again.
+func (k *Keyring) SetAccountSecrets(accountID string, secrets ...Secret) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(secrets) == 0 {
+		delete(k.accounts, accountID)
+		return
+	}
+	var stored []Secret
+	for _, secret := range secrets {
+		stored = upsertSecret(stored, secret)
+	}
+	k.accounts[accountID] = stored
+}
+
+// Labels returns the labels of the secrets that apply to accountID, in the
+// order they were added. An empty accountID returns the platform-wide labels.
+func (k *Keyring) Labels(accountID string) []string {
+	secrets := k.secretsFor(accountID)
+	labels := make([]string, len(secrets))
+	for i, secret := range secrets {
+		labels[i] = secret.Label
+	}
+	return labels
+}
+
+// secretsFor returns a copy of the account's secrets, or of the platform-wide
+// secrets when the account has none of its own.
+func (k *Keyring) secretsFor(accountID string) []Secret {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	secrets := k.secrets
+	if accountSecrets, ok := k.accounts[accountID]; ok && accountID != "" {
+		secrets = accountSecrets
+	}
+	return append([]Secret(nil), secrets...)
+}
+
+// match compares received against the MAC of every secret that is active at
+// now. All active secrets are checked, whatever the outcome, so timing does not
+// reveal which secret matched.
+func (k *Keyring) match(accountID string, payload []byte, timestampHeader string, received []byte, now time.Time) (string, error) {
+	secrets := k.secretsFor(accountID)
+	matched := -1
+	active := 0
+	for i, secret := range secrets {
+		if !secret.activeAt(now) {
+			continue
+		}
+		active++
+		if equal := hmac.Equal(signatureMAC([]byte(secret.Value), payload, timestampHeader), received); equal && matched < 0 {
+			matched = i
+		}
+	}
+	if active == 0 {
+		return "", fmt.Errorf("webhook: no active signing secret")
+	}
+	if matched < 0 {
+		return "", fmt.Errorf("webhook signature verification failed")
+	}
+	return secrets[matched].Label, nil
+}
+
+func upsertSecret(secrets []Secret, secret Secret) []Secret {
+	for i := range secrets {
+		if secrets[i].Label == secret.Label {
+			secrets[i] = secret
+			return secrets
+		}
+	}
+	return append(secrets, secret)
+}
+
+func removeSecret(secrets []Secret, label string) []Secret {
+	kept := secrets[:0]
+	for _, secret := range secrets {
+		if secret.Label != label {
+			kept = append(kept, secret)
+		}
+	}
+	return kept
+}
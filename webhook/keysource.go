@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/configuration"
+)
+
+var errEmptySecret = errors.New("webhook: fetched signing secret is empty")
+
+// AddFromSource fetches a signing secret from source once and adds it under
+// label. An empty label uses DefaultSecretLabel.
+func (k *Keyring) AddFromSource(ctx context.Context, label string, source configuration.KeySource) error {
+	value, err := source.FetchKey(ctx)
+	if err != nil {
+		return fmt.Errorf("webhook: fetch signing secret: %w", err)
+	}
+	if value == "" {
+		return errEmptySecret
+	}
+	k.Add(Secret{Label: secretLabel(label), Value: value})
+	return nil
+}
+
+// WatchSecret adds the secret fetched from source under label and re-fetches
+// it every interval, replacing the stored value whenever it changes. Zero
+// interval uses configuration.DefaultReloadInterval. Reload errors are passed
+// to onError and the previous value stays in the keyring; an error from the
+// initial fetch is returned. WatchSecret blocks until ctx is done.
+//
+// Replacing the value rejects deliveries signed with the old one straight
+// away. For a gradual rotation add the new secret under a new label instead.
+func (k *Keyring) WatchSecret(ctx context.Context, label string, source configuration.KeySource, interval time.Duration, onError func(error)) error {
+	label = secretLabel(label)
+	err := configuration.WatchKeys(ctx, interval, func(values []string) error {
+		if values[0] == "" {
+			return errEmptySecret
+		}
+		k.Add(Secret{Label: label, Value: values[0]})
+		return nil
+	}, onError, source)
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("webhook: watch signing secret: %w", err)
+	}
+	return err
+}
+
+func secretLabel(label string) string {
+	if label == "" {
+		return DefaultSecretLabel
+	}
+	return label
+}
@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/configuration"
+)
+
+func TestKeyringWatchSecretReplacesRotatedValue(t *testing.T) {
+	var mu sync.Mutex
+	value := "whsec_old"
+	source := configuration.KeySourceFunc(func(context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return value, nil
+	})
+
+	keyring := NewKeyring()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- keyring.WatchSecret(ctx, "", source, 5*time.Millisecond, nil) }()
+
+	waitForSecret(t, keyring, "whsec_old")
+	mu.Lock()
+	value = "whsec_new"
+	mu.Unlock()
+	waitForSecret(t, keyring, "whsec_new")
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("WatchSecret returned %v, want context.Canceled", err)
+	}
+	if labels := keyring.Labels(""); len(labels) != 1 || labels[0] != DefaultSecretLabel {
+		t.Fatalf("labels = %v, want [%s]", labels, DefaultSecretLabel)
+	}
+}
+
+func TestKeyringAddFromSource(t *testing.T) {
+	t.Setenv("UQPAY_TEST_WEBHOOK_SECRET", "whsec_env")
+	keyring := NewKeyring()
+	if err := keyring.AddFromSource(context.Background(), "2026-01", configuration.EnvKey("UQPAY_TEST_WEBHOOK_SECRET")); err != nil {
+		t.Fatalf("AddFromSource: %v", err)
+	}
+	if got := keyring.secretsFor(""); len(got) != 1 || got[0].Label != "2026-01" || got[0].Value != "whsec_env" {
+		t.Fatalf("secrets = %+v", got)
+	}
+	if err := keyring.AddFromSource(context.Background(), "", configuration.StaticKey("")); err == nil {
+		t.Fatal("AddFromSource accepted an empty secret")
+	}
+}
+
+func waitForSecret(t *testing.T, keyring *Keyring, want string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		secrets := keyring.secretsFor("")
+		if len(secrets) == 1 && secrets[0].Value == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("secret never became %q: %+v", want, secrets)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -56,6 +56,59 @@ type AccountData struct {
 	Source string `json:"source,omitempty"`
 }
 
+// SubAccountData represents the connected (sub-)account information in
+// sub-account onboarding webhook events.
+// This is returned in the data field for onboarding.sub_account.* events.
+type SubAccountData struct {
+	// AccountID is the unique identifier for the sub-account
+	AccountID string `json:"account_id"`
+
+	// DirectID is the main account that owns the sub-account
+	DirectID string `json:"direct_id"`
+
+	// ShortReferenceID is a short reference identifier for the sub-account
+	ShortReferenceID string `json:"short_reference_id,omitempty"`
+
+	// AccountName is the display name of the sub-account
+	AccountName string `json:"account_name"`
+
+	// EntityType is the type of entity (e.g., "COMPANY", "INDIVIDUAL")
+	EntityType string `json:"entity_type"`
+
+	// Country is the ISO 3166-1 alpha-2 country code
+	Country string `json:"country,omitempty"`
+
+	// Status is the current sub-account status (e.g., "PROCESSING", "ACTIVE")
+	Status string `json:"status"`
+
+	// VerificationStatus is the overall verification status
+	VerificationStatus string `json:"verification_status,omitempty"`
+
+	// ReviewReason provides the reason for the current review status
+	ReviewReason string `json:"review_reason,omitempty"`
+
+	// Requirements lists the information still needed to activate the sub-account
+	Requirements *SubAccountRequirements `json:"requirements,omitempty"`
+
+	// CreateTime is the creation timestamp
+	CreateTime string `json:"create_time,omitempty"`
+
+	// UpdateTime is the last update timestamp
+	UpdateTime string `json:"update_time,omitempty"`
+}
+
+// SubAccountRequirements lists outstanding onboarding requirements for a sub-account
+type SubAccountRequirements struct {
+	// CurrentlyDue lists fields that must be provided now
+	CurrentlyDue []string `json:"currently_due,omitempty"`
+
+	// EventuallyDue lists fields that will be required later
+	EventuallyDue []string `json:"eventually_due,omitempty"`
+
+	// PastDue lists fields whose deadline has passed
+	PastDue []string `json:"past_due,omitempty"`
+}
+
 // ContactDetails represents contact information for an account
 type ContactDetails struct {
 	// Email is the contact email address
@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ErrUnregisteredEventType is returned when no payload type is registered for
+// an event's type and version. Callers can fall back to the raw Event.Data.
+var ErrUnregisteredEventType = errors.New("webhook: no payload type registered for event")
+
+// EventValidator is implemented by payload types that must be checked against
+// the event envelope after decoding, for example to require that SourceID
+// matches an ID in the payload.
+type EventValidator interface {
+	ValidateEvent(e *Event) error
+}
+
+// Registry maps event types to the Go types of their data payloads.
+// Mappings can be limited to specific webhook versions; a version-specific
+// mapping takes precedence over one registered for all versions.
+// A Registry is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+}
+
+type registryEntry struct {
+	any       reflect.Type
+	byVersion map[string]reflect.Type
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{entries: map[string]*registryEntry{}}
+}
+
+// DefaultRegistry contains every payload type shipped with the SDK. It is used
+// by Decode and Event.Payload. Register additional types on it to decode new
+// server events before the SDK adds them.
+var DefaultRegistry = newDefaultRegistry()
+
+// Register maps eventTypes to payload type T for every webhook version.
+func Register[T any](r *Registry, eventTypes ...string) {
+	r.register(payloadType[T](), nil, eventTypes)
+}
+
+// RegisterVersions maps eventTypes to payload type T only for the listed
+// webhook versions (e.g. "V1.6.0").
+func RegisterVersions[T any](r *Registry, versions []string, eventTypes ...string) {
+	r.register(payloadType[T](), versions, eventTypes)
+}
+
+func (r *Registry) register(typ reflect.Type, versions []string, eventTypes []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, eventType := range eventTypes {
+		entry := r.entries[eventType]
+		if entry == nil {
+			entry = &registryEntry{byVersion: map[string]reflect.Type{}}
+			r.entries[eventType] = entry
+		}
+		if len(versions) == 0 {
+			entry.any = typ
+			continue
+		}
+		for _, version := range versions {
+			entry.byVersion[version] = typ
+		}
+	}
+}
+
+// Lookup returns the payload type registered for eventType at version.
+func (r *Registry) Lookup(eventType, version string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry := r.entries[eventType]
+	if entry == nil {
+		return nil, false
+	}
+	if typ, ok := entry.byVersion[version]; ok {
+		return typ, true
+	}
+	return entry.any, entry.any != nil
+}
+
+// Payload decodes the event data into a new value of its registered type and
+// returns it as a pointer, e.g. *CardData for card.create.succeeded.
+func (r *Registry) Payload(e *Event) (interface{}, error) {
+	typ, err := r.payloadTypeFor(e)
+	if err != nil {
+		return nil, err
+	}
+	data := reflect.New(typ).Interface()
+	if err := decodeInto(e, data, typ); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (r *Registry) payloadTypeFor(e *Event) (reflect.Type, error) {
+	typ, ok := r.Lookup(e.EventType, e.Version)
+	if !ok {
+		return nil, fmt.Errorf("%w: type %s, version %s", ErrUnregisteredEventType, e.EventType, e.Version)
+	}
+	return typ, nil
+}
+
+// Payload decodes the event data using DefaultRegistry. Use a type switch on
+// the result to handle events:
+//
+//	payload, err := event.Payload()
+//	switch data := payload.(type) {
+//	case *webhook.CardAuthorizationData:
+//	    // ...
+//	}
+func (e *Event) Payload() (interface{}, error) {
+	return DefaultRegistry.Payload(e)
+}
+
+// Decode decodes the event data as T using DefaultRegistry. It returns an
+// error if T is not the payload type registered for the event's type and
+// version.
+func Decode[T any](e *Event) (*T, error) {
+	return DecodeWith[T](DefaultRegistry, e)
+}
+
+// DecodeWith is like Decode but uses registry r.
+func DecodeWith[T any](r *Registry, e *Event) (*T, error) {
+	typ, err := r.payloadTypeFor(e)
+	if err != nil {
+		return nil, err
+	}
+	if want := payloadType[T](); typ != want {
+		return nil, fmt.Errorf("event type %s carries %s data, not %s", e.EventType, typ.Name(), want.Name())
+	}
+	var data T
+	if err := decodeInto(e, &data, typ); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+func decodeInto(e *Event, data interface{}, typ reflect.Type) error {
+	if err := json.Unmarshal(e.Data, data); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", typ.Name(), err)
+	}
+	if validator, ok := data.(EventValidator); ok {
+		if err := validator.ValidateEvent(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func payloadType[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// parseData backs the type-specific Parse* methods. Most take their event
+// types from the same groups the default registry is built from; a few accept
+// a wider set (e.g. a whole event name) for compatibility.
+func parseData[T any](e *Event, ok bool, what, desc string) (*T, error) {
+	if !ok {
+		return nil, fmt.Errorf("event type %s is not %s", e.EventType, what)
+	}
+	var data T
+	if err := json.Unmarshal(e.Data, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", desc, err)
+	}
+	if validator, ok := interface{}(&data).(EventValidator); ok {
+		if err := validator.ValidateEvent(e); err != nil {
+			return nil, err
+		}
+	}
+	return &data, nil
+}
+
+func mustParse[T any](data *T, err error) *T {
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// Event type groups shared by the default registry and the Is*Event methods,
+// so a new event type only has to be added in one place.
+var (
+	accountEventTypes                   = []string{EventTypeAccountCreate, EventTypeAccountUpdate}
+	subAccountEventTypes                = []string{EventTypeSubAccountCreate, EventTypeSubAccountUpdate, EventTypeSubAccountActivated, EventTypeSubAccountRejected}
+	rfiEventTypes                       = []string{EventTypeRFIActionRequired, EventTypeRFISubmitted, EventTypeRFIApproved, EventTypeRFIRejected}
+	paymentIntentEventTypes             = []string{EventTypePaymentIntentCreated, EventTypePaymentIntentSucceeded, EventTypePaymentIntentFailed, EventTypePaymentIntentCanceled}
+	paymentAttemptEventTypes            = []string{EventTypePaymentAttemptCreated, EventTypePaymentAttemptCaptureRequested, EventTypePaymentAttemptSucceeded, EventTypePaymentAttemptFailed, EventTypePaymentAttemptCanceled}
+	refundEventTypes                    = []string{EventTypeRefundCreated, EventTypeRefundSucceeded, EventTypeRefundFailed}
+	cardCreateOrUpdateEventTypes        = []string{EventTypeCardCreateSucceeded, EventTypeCardCreateFailed, EventTypeCardUpdateSucceeded, EventTypeCardUpdateFailed}
+	cardRechargeEventTypes              = []string{EventTypeCardRechargeSucceeded, EventTypeCardRechargeFailed}
+	cardLifecycleEventTypes             = []string{EventTypeCardActivated, EventTypeCardSuspended, EventTypeCardClosed}
+	cardStatusUpdateEventTypes          = []string{EventTypeCardStatusUpdateSucceeded, EventTypeCardStatusUpdateFailed}
+	payoutEventTypes                    = []string{EventTypePayoutReadySend, EventTypePayoutComplianceRejected, EventTypePayoutCompleted, EventTypePayoutFailed}
+	beneficiaryEventTypes               = []string{EventTypeBeneficiarySuccessful, EventTypeBeneficiaryFailed, EventTypeBeneficiaryPending}
+	virtualAccountApplicationEventTypes = []string{EventTypeVirtualAccountCreate, EventTypeVirtualAccountUpdate, EventTypeVirtualAccountClosed}
+)
+
+func hasString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	Register[AccountData](r, accountEventTypes...)
+	Register[SubAccountData](r, subAccountEventTypes...)
+	Register[RFIData](r, rfiEventTypes...)
+
+	Register[PaymentIntentData](r, paymentIntentEventTypes...)
+	Register[PaymentAttemptData](r, paymentAttemptEventTypes...)
+	Register[RefundData](r, refundEventTypes...)
+
+	Register[ConversionData](r, EventTypeConversionTradeSettled)
+	Register[ConversionFundsAwaitingData](r, EventTypeConversionFundsAwaiting)
+	Register[ConversionFundsArrivedData](r, EventTypeConversionFundsArrived)
+
+	Register[CardData](r, cardCreateOrUpdateEventTypes...)
+	Register[CardRechargeData](r, cardRechargeEventTypes...)
+	Register[CardActivationCodeData](r, EventTypeCardActivationCode)
+	Register[CardLifecycleData](r, cardLifecycleEventTypes...)
+	Register[CardStatusUpdateData](r, cardStatusUpdateEventTypes...)
+	Register[CardAuthorizationData](r, EventTypeIssuingAuthorizationCard)
+	Register[CardReversalData](r, EventTypeIssuingReversalCard)
+	Register[CardClearingData](r, EventTypeIssuingClearingCard)
+	Register[CardRefundData](r, EventTypeIssuingRefundCard)
+	Register[CardTransactionData](r, EventTypeIssuingFeeCard)
+
+	Register[PayoutData](r, payoutEventTypes...)
+
+	Register[DepositPendingData](r, EventTypeDepositPending)
+	Register[DepositComplianceRejectedData](r, EventTypeDepositComplianceRejected)
+	Register[DepositCompletedData](r, EventTypeDepositCompleted)
+
+	Register[BeneficiaryData](r, beneficiaryEventTypes...)
+
+	RegisterVersions[VirtualAccountApplicationData](r, virtualAccountApplicationVersions, virtualAccountApplicationEventTypes...)
+
+	return r
+}
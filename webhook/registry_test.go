@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDecodeUsesRegisteredPayloadType(t *testing.T) {
+	event := Event{
+		Version:   "V1.6.0",
+		EventName: EventNameIssuing,
+		EventType: EventTypeIssuingAuthorizationCard,
+		Data:      json.RawMessage(`{"card_id":"card-001","authorization_code":"A1B2C3","billing_amount":"10.00"}`),
+	}
+
+	data, err := Decode[CardAuthorizationData](&event)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if data.CardID != "card-001" || data.AuthorizationCode != "A1B2C3" {
+		t.Errorf("data = %+v", data)
+	}
+	if _, err := Decode[CardData](&event); err == nil {
+		t.Error("Decode accepted a payload type that is not registered for the event")
+	}
+
+	payload, err := event.Payload()
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+	switch typed := payload.(type) {
+	case *CardAuthorizationData:
+		if typed.BillingAmount != "10.00" {
+			t.Errorf("billing amount = %q", typed.BillingAmount)
+		}
+	default:
+		t.Fatalf("payload type = %T, want *CardAuthorizationData", payload)
+	}
+}
+
+func TestPayloadReportsUnregisteredEventTypes(t *testing.T) {
+	event := Event{Version: "V1.6.0", EventType: "future.event.added", Data: json.RawMessage(`{}`)}
+	if _, err := event.Payload(); !errors.Is(err, ErrUnregisteredEventType) {
+		t.Fatalf("Payload error = %v, want ErrUnregisteredEventType", err)
+	}
+}
+
+type futureEventData struct {
+	WidgetID string `json:"widget_id"`
+}
+
+func TestRegisterCustomAndVersionedPayloadTypes(t *testing.T) {
+	registry := NewRegistry()
+	Register[futureEventData](registry, "future.widget.created")
+	RegisterVersions[CardData](registry, []string{"V2.0.0"}, "future.widget.created")
+
+	event := Event{Version: "V1.6.0", EventType: "future.widget.created", Data: json.RawMessage(`{"widget_id":"w-1","card_id":"card-001"}`)}
+	data, err := DecodeWith[futureEventData](registry, &event)
+	if err != nil {
+		t.Fatalf("DecodeWith: %v", err)
+	}
+	if data.WidgetID != "w-1" {
+		t.Errorf("widget id = %q", data.WidgetID)
+	}
+
+	event.Version = "V2.0.0"
+	payload, err := registry.Payload(&event)
+	if err != nil {
+		t.Fatalf("Payload: %v", err)
+	}
+	if card, ok := payload.(*CardData); !ok || card.CardID != "card-001" {
+		t.Fatalf("versioned payload = %#v, want *CardData", payload)
+	}
+}
+
+func TestDefaultRegistryAppliesVersionsAndValidation(t *testing.T) {
+	valid := `{"account_id":"account-id","direct_id":"0","application_id":"app-id","public_version":1,"country":"SG","currency":"USD","status":"SUBMITTED","results":[]}`
+	event := Event{Version: "V1.6.0", EventType: EventTypeVirtualAccountCreate, SourceID: "app-id", Data: json.RawMessage(valid)}
+	if _, err := Decode[VirtualAccountApplicationData](&event); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	event.SourceID = "other-id"
+	if _, err := event.Payload(); err == nil {
+		t.Error("Payload skipped ValidateEvent for a source_id mismatch")
+	}
+
+	event.SourceID = "app-id"
+	event.Version = "V1.5.0"
+	if _, err := event.Payload(); !errors.Is(err, ErrUnregisteredEventType) {
+		t.Errorf("Payload error for an unsupported version = %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package webhook
+
+import "github.com/uqpay/uqpay-sdk-go/v2/connect"
+
+// RFIData represents a request for information (RFI) in RFI webhook events.
+// It has the same shape as connect.RFI so payloads can be handed directly to
+// RFIsClient.Answer workflows.
+type RFIData struct {
+	connect.RFI
+}
+
+// IsActionRequired returns true if the RFI is waiting for an answer.
+func (r *RFIData) IsActionRequired() bool {
+	return r.Status == connect.RFIStatusActionRequired
+}
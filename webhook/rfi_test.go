@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/connect"
+)
+
+func TestParseRFIAndSubAccountEvents(t *testing.T) {
+	rfi := Event{
+		EventName: EventNameRFI,
+		EventType: EventTypeRFIActionRequired,
+		Data: json.RawMessage(`{
+			"account_id": "acc-123456",
+			"rfi_id": "rfi-001",
+			"status": "ACTION_REQUIRED",
+			"request": [{"question": {"key": "proof_of_address", "type": "ATTACHMENT"}}]
+		}`),
+	}
+	if !rfi.IsRFIEvent() {
+		t.Fatal("expected IsRFIEvent to return true")
+	}
+	rfiData, err := rfi.ParseRFIData()
+	if err != nil {
+		t.Fatalf("ParseRFIData: %v", err)
+	}
+	if rfiData.RFIID != "rfi-001" || rfiData.Status != connect.RFIStatusActionRequired || !rfiData.IsActionRequired() {
+		t.Errorf("rfi data = %+v", rfiData)
+	}
+	if len(rfiData.Request) != 1 || rfiData.Request[0].Question.Key != "proof_of_address" {
+		t.Errorf("rfi request = %+v", rfiData.Request)
+	}
+
+	subAccount := Event{
+		EventName: EventNameOnboarding,
+		EventType: EventTypeSubAccountActivated,
+		Data: json.RawMessage(`{
+			"account_id": "sub-001",
+			"direct_id": "acc-123456",
+			"account_name": "Example Subsidiary Ltd.",
+			"entity_type": "COMPANY",
+			"status": "ACTIVE",
+			"requirements": {"currently_due": ["business_details.website_url"]}
+		}`),
+	}
+	if !subAccount.IsOnboardingEvent() || !subAccount.IsSubAccountEvent() || subAccount.IsAccountCreateEvent() {
+		t.Fatal("sub-account helper mismatch")
+	}
+	subData, err := subAccount.ParseSubAccountData()
+	if err != nil {
+		t.Fatalf("ParseSubAccountData: %v", err)
+	}
+	if subData.AccountID != "sub-001" || subData.DirectID != "acc-123456" || subData.Status != AccountStatusActive {
+		t.Errorf("sub-account data = %+v", subData)
+	}
+	if subData.Requirements == nil || len(subData.Requirements.CurrentlyDue) != 1 {
+		t.Errorf("requirements = %+v", subData.Requirements)
+	}
+	if _, err := subAccount.ParseRFIData(); err == nil {
+		t.Error("expected ParseRFIData to reject a sub-account event")
+	}
+}
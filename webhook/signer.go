@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Webhook delivery headers set by UQPAY.
+const (
+	SignatureHeader = "x-wk-signature"
+	TimestampHeader = "x-wk-timestamp"
+)
+
+// Signer produces UQPAY webhook signatures. It mirrors what UQPAY does when
+// delivering an event so handlers can be exercised end to end in tests and
+// local development without hand-rolling the MAC.
+type Signer struct {
+	secret []byte
+	// Milliseconds signs with Webhook Hub's Unix-millisecond timestamps instead
+	// of Unix seconds.
+	Milliseconds bool
+	now          func() time.Time
+}
+
+// NewSigner creates a webhook signer for secret.
+func NewSigner(secret string) *Signer {
+	return &Signer{
+		secret: []byte(secret),
+		now:    time.Now,
+	}
+}
+
+// Sign returns the hex-encoded HMAC-SHA512(secret, payload + timestampHeader).
+// The timestamp is signed exactly as it will be sent in x-wk-timestamp.
+func (s *Signer) Sign(payload []byte, timestampHeader string) string {
+	return hex.EncodeToString(signatureMAC(s.secret, payload, timestampHeader))
+}
+
+// SignAt formats at as a timestamp header and signs payload with it.
+func (s *Signer) SignAt(payload []byte, at time.Time) (signature, timestampHeader string) {
+	timestampHeader = s.FormatTimestamp(at)
+	return s.Sign(payload, timestampHeader), timestampHeader
+}
+
+// FormatTimestamp formats at as Unix seconds, or Unix milliseconds when
+// Milliseconds is set.
+func (s *Signer) FormatTimestamp(at time.Time) string {
+	if s.Milliseconds {
+		return strconv.FormatInt(at.UnixMilli(), 10)
+	}
+	return strconv.FormatInt(at.Unix(), 10)
+}
+
+// Headers signs payload with the current time and returns the delivery
+// headers UQPAY would send alongside it.
+func (s *Signer) Headers(payload []byte) http.Header {
+	signature, timestampHeader := s.SignAt(payload, s.now())
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set(SignatureHeader, signature)
+	header.Set(TimestampHeader, timestampHeader)
+	return header
+}
+
+func signatureMAC(secret, payload []byte, timestampHeader string) []byte {
+	mac := hmac.New(sha512.New, secret)
+	_, _ = mac.Write(payload)
+	_, _ = mac.Write([]byte(timestampHeader))
+	return mac.Sum(nil)
+}
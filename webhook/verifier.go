@@ -1,8 +1,6 @@
 package webhook
 
 import (
-	"crypto/hmac"
-	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -16,20 +14,43 @@ const DefaultSignatureTolerance = 5 * time.Minute
 // envelope. EventType remains a string so newly introduced server events stay
 // forward-compatible even before a typed payload helper is added.
 type Verifier struct {
-	secret    []byte
+	keyring   *Keyring
 	tolerance time.Duration
 	now       func() time.Time
 }
 
+// Verification is the result of verifying a delivery with a keyring.
+type Verification struct {
+	Event *Event
+	// SecretLabel is the label of the secret whose signature matched.
+	SecretLabel string
+	// AccountID is the connected account passed to VerifyForAccount, if any.
+	AccountID string
+}
+
 // NewVerifier creates a webhook verifier. Pass a positive tolerance to
 // override the default five-minute replay window.
 func NewVerifier(secret string, tolerance ...time.Duration) *Verifier {
+	return newVerifier(NewKeyring(Secret{Label: DefaultSecretLabel, Value: secret}), tolerance)
+}
+
+// NewKeyringVerifier creates a webhook verifier that accepts a signature made
+// with any active secret in keyring. The keyring may be changed while the
+// verifier is in use. It returns an error if keyring is nil.
+func NewKeyringVerifier(keyring *Keyring, tolerance ...time.Duration) (*Verifier, error) {
+	if keyring == nil {
+		return nil, fmt.Errorf("webhook: keyring is required")
+	}
+	return newVerifier(keyring, tolerance), nil
+}
+
+func newVerifier(keyring *Keyring, tolerance []time.Duration) *Verifier {
 	resolvedTolerance := DefaultSignatureTolerance
 	if len(tolerance) > 0 && tolerance[0] > 0 {
 		resolvedTolerance = tolerance[0]
 	}
 	return &Verifier{
-		secret:    []byte(secret),
+		keyring:   keyring,
 		tolerance: resolvedTolerance,
 		now:       time.Now,
 	}
@@ -38,22 +59,39 @@ func NewVerifier(secret string, tolerance ...time.Duration) *Verifier {
 // ConstructEvent verifies HMAC-SHA512(secret, rawPayload + timestamp) and
 // returns the parsed event envelope.
 func (v *Verifier) ConstructEvent(payload []byte, signatureHeader, timestampHeader string) (*Event, error) {
+	verification, err := v.Verify(payload, signatureHeader, timestampHeader)
+	if err != nil {
+		return nil, err
+	}
+	return verification.Event, nil
+}
+
+// Verify is like ConstructEvent but also reports which secret matched.
+func (v *Verifier) Verify(payload []byte, signatureHeader, timestampHeader string) (*Verification, error) {
+	return v.VerifyForAccount("", payload, signatureHeader, timestampHeader)
+}
+
+// VerifyForAccount verifies a delivery made to the endpoint of connected
+// account accountID, using that account's secrets when the keyring has any
+// and the platform-wide secrets otherwise.
+func (v *Verifier) VerifyForAccount(accountID string, payload []byte, signatureHeader, timestampHeader string) (*Verification, error) {
 	if signatureHeader == "" {
-		return nil, fmt.Errorf("webhook header missing: x-wk-signature")
+		return nil, fmt.Errorf("webhook header missing: %s", SignatureHeader)
 	}
 	if timestampHeader == "" {
-		return nil, fmt.Errorf("webhook header missing: x-wk-timestamp")
+		return nil, fmt.Errorf("webhook header missing: %s", TimestampHeader)
 	}
 
 	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("invalid x-wk-timestamp %q: %w", timestampHeader, err)
+		return nil, fmt.Errorf("invalid %s %q: %w", TimestampHeader, timestampHeader, err)
 	}
 	timestampTime := time.Unix(timestamp, 0)
 	if timestamp >= 1_000_000_000_000 {
 		timestampTime = time.UnixMilli(timestamp)
 	}
-	if delta := v.now().Sub(timestampTime); delta > v.tolerance || delta < -v.tolerance {
+	now := v.now()
+	if delta := now.Sub(timestampTime); delta > v.tolerance || delta < -v.tolerance {
 		return nil, fmt.Errorf("webhook timestamp is outside the allowed tolerance of %s", v.tolerance)
 	}
 
@@ -61,16 +99,14 @@ func (v *Verifier) ConstructEvent(payload []byte, signatureHeader, timestampHead
 	if err != nil {
 		return nil, fmt.Errorf("webhook signature verification failed")
 	}
-	mac := hmac.New(sha512.New, v.secret)
-	_, _ = mac.Write(payload)
-	_, _ = mac.Write([]byte(timestampHeader))
-	if !hmac.Equal(mac.Sum(nil), received) {
-		return nil, fmt.Errorf("webhook signature verification failed")
+	label, err := v.keyring.match(accountID, payload, timestampHeader, received, now)
+	if err != nil {
+		return nil, err
 	}
 
 	var event Event
 	if err := json.Unmarshal(payload, &event); err != nil {
 		return nil, fmt.Errorf("webhook body is not valid JSON: %w", err)
 	}
-	return &event, nil
+	return &Verification{Event: &event, SecretLabel: label, AccountID: accountID}, nil
 }
@@ -1,9 +1,6 @@
 package webhook
 
 import (
-	"crypto/hmac"
-	"crypto/sha512"
-	"encoding/hex"
 	"strconv"
 	"testing"
 	"time"
@@ -59,13 +56,107 @@ func TestVerifierRejectsInvalidSignatureAndStaleTimestamp(t *testing.T) {
 	}
 }
 
+func TestSignerHeadersRoundTripThroughVerifier(t *testing.T) {
+	const secret = "whsec_test_secret"
+	payload := []byte(`{"event_type":"payout.completed","event_id":"evt_signed"}`)
+	for _, milliseconds := range []bool{false, true} {
+		signer := NewSigner(secret)
+		signer.Milliseconds = milliseconds
+		header := signer.Headers(payload)
+
+		if milliseconds && len(header.Get(TimestampHeader)) != 13 {
+			t.Fatalf("timestamp = %q, want Unix milliseconds", header.Get(TimestampHeader))
+		}
+		event, err := NewVerifier(secret).ConstructEvent(payload, header.Get(SignatureHeader), header.Get(TimestampHeader))
+		if err != nil {
+			t.Fatalf("milliseconds=%t: ConstructEvent returned an error: %v", milliseconds, err)
+		}
+		if event.EventID != "evt_signed" {
+			t.Fatalf("event id = %q, want evt_signed", event.EventID)
+		}
+		if _, err := NewVerifier("whsec_other").ConstructEvent(payload, header.Get(SignatureHeader), header.Get(TimestampHeader)); err == nil {
+			t.Fatal("ConstructEvent accepted a signature made with a different secret")
+		}
+	}
+}
+
 func signWebhook(secret string, payload []byte, timestamp string) string {
-	mac := hmac.New(sha512.New, []byte(secret))
-	_, _ = mac.Write(payload)
-	_, _ = mac.Write([]byte(timestamp))
-	return hex.EncodeToString(mac.Sum(nil))
+	return NewSigner(secret).Sign(payload, timestamp)
 }
 
 func stringInt(value int64) string {
 	return strconv.FormatInt(value, 10)
 }
+
+func TestKeyringVerifierAcceptsRotatedSecretsAndReportsMatch(t *testing.T) {
+	now := time.Now()
+	keyring := NewKeyring(
+		Secret{Label: "old", Value: "whsec_old", NotAfter: now.Add(time.Hour)},
+		Secret{Label: "new", Value: "whsec_new", NotBefore: now.Add(-time.Hour)},
+		Secret{Label: "future", Value: "whsec_future", NotBefore: now.Add(24 * time.Hour)},
+	)
+	verifier, err := NewKeyringVerifier(keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier.now = func() time.Time { return now }
+
+	payload := []byte(`{"event_type":"card.create.succeeded","event_id":"evt_rotated"}`)
+	timestampHeader := stringInt(now.Unix())
+	for _, tc := range []struct{ secret, label string }{{"whsec_old", "old"}, {"whsec_new", "new"}} {
+		verification, err := verifier.Verify(payload, signWebhook(tc.secret, payload, timestampHeader), timestampHeader)
+		if err != nil {
+			t.Fatalf("%s: Verify returned an error: %v", tc.label, err)
+		}
+		if verification.SecretLabel != tc.label || verification.Event.EventID != "evt_rotated" {
+			t.Fatalf("verification = %+v, want label %q", verification, tc.label)
+		}
+	}
+	if _, err := verifier.Verify(payload, signWebhook("whsec_future", payload, timestampHeader), timestampHeader); err == nil {
+		t.Fatal("Verify accepted a secret before its NotBefore")
+	}
+
+	keyring.Remove("old")
+	if _, err := verifier.Verify(payload, signWebhook("whsec_old", payload, timestampHeader), timestampHeader); err == nil {
+		t.Fatal("Verify accepted a removed secret")
+	}
+	if labels := keyring.Labels(""); len(labels) != 2 || labels[0] != "new" {
+		t.Fatalf("labels = %v, want [new future]", labels)
+	}
+}
+
+func TestKeyringVerifierUsesPerAccountSecrets(t *testing.T) {
+	keyring := NewKeyring(Secret{Label: "platform", Value: "whsec_platform"})
+	keyring.SetAccountSecrets("account_sub_123", Secret{Label: "sub-123", Value: "whsec_sub"})
+	verifier, err := NewKeyringVerifier(keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`{"event_type":"deposit.completed","event_id":"evt_sub"}`)
+	timestampHeader := stringInt(time.Now().Unix())
+	subSignature := signWebhook("whsec_sub", payload, timestampHeader)
+
+	verification, err := verifier.VerifyForAccount("account_sub_123", payload, subSignature, timestampHeader)
+	if err != nil {
+		t.Fatalf("VerifyForAccount returned an error: %v", err)
+	}
+	if verification.SecretLabel != "sub-123" || verification.AccountID != "account_sub_123" {
+		t.Fatalf("verification = %+v", verification)
+	}
+	if _, err := verifier.VerifyForAccount("account_sub_123", payload, signWebhook("whsec_platform", payload, timestampHeader), timestampHeader); err == nil {
+		t.Fatal("VerifyForAccount accepted the platform secret for an account with its own secrets")
+	}
+	if _, err := verifier.Verify(payload, subSignature, timestampHeader); err == nil {
+		t.Fatal("Verify accepted an account secret for a platform delivery")
+	}
+	if _, err := verifier.VerifyForAccount("account_other", payload, signWebhook("whsec_platform", payload, timestampHeader), timestampHeader); err != nil {
+		t.Fatalf("account without secrets did not fall back to the platform secrets: %v", err)
+	}
+}
+
+func TestNewKeyringVerifierRejectsNilKeyring(t *testing.T) {
+	if verifier, err := NewKeyringVerifier(nil); err == nil || verifier != nil {
+		t.Fatalf("NewKeyringVerifier(nil) = %v, %v; want an error", verifier, err)
+	}
+}
@@ -1,7 +1,6 @@
 package webhook
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/uqpay/uqpay-sdk-go/v2/banking"
@@ -14,11 +13,7 @@ const (
 	EventTypeVirtualAccountClosed = "virtual.account.closed"
 )
 
-var virtualAccountApplicationVersions = map[string]struct{}{
-	"V1.5.1": {},
-	"V1.5.2": {},
-	"V1.6.0": {},
-}
+var virtualAccountApplicationVersions = []string{"V1.5.1", "V1.5.2", "V1.6.0"}
 
 // VirtualAccountApplicationData is the application shape delivered by VA
 // application webhook events. It inherits the same required AccountID and
@@ -33,21 +28,23 @@ type VirtualAccountApplicationData struct {
 // versions V1.5.1, V1.5.2, and V1.6.0. SourceID equals ApplicationID; callers
 // should order changes by ApplicationID and PublicVersion.
 func (e *Event) ParseVirtualAccountApplicationData() (*VirtualAccountApplicationData, error) {
-	if e.EventType != EventTypeVirtualAccountCreate && e.EventType != EventTypeVirtualAccountUpdate && e.EventType != EventTypeVirtualAccountClosed {
+	if !hasString(virtualAccountApplicationEventTypes, e.EventType) {
 		return nil, fmt.Errorf("event type %s is not a virtual account application event", e.EventType)
 	}
-	if _, ok := virtualAccountApplicationVersions[e.Version]; !ok {
+	if !hasString(virtualAccountApplicationVersions, e.Version) {
 		return nil, fmt.Errorf("webhook version %s does not use the virtual account application contract", e.Version)
 	}
-	var data VirtualAccountApplicationData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse virtual account application data: %w", err)
-	}
-	if data.AccountID == "" || data.DirectID == "" {
-		return nil, fmt.Errorf("virtual account application data requires account_id and direct_id")
+	return parseData[VirtualAccountApplicationData](e, true, "a virtual account application event", "virtual account application data")
+}
+
+// ValidateEvent requires the account context and that the event's SourceID
+// identifies this application.
+func (d *VirtualAccountApplicationData) ValidateEvent(e *Event) error {
+	if d.AccountID == "" || d.DirectID == "" {
+		return fmt.Errorf("virtual account application data requires account_id and direct_id")
 	}
-	if data.ApplicationID == "" || e.SourceID != data.ApplicationID {
-		return nil, fmt.Errorf("virtual account application source_id must equal application_id")
+	if d.ApplicationID == "" || e.SourceID != d.ApplicationID {
+		return fmt.Errorf("virtual account application source_id must equal application_id")
 	}
-	return &data, nil
+	return nil
 }
@@ -99,3 +99,14 @@ func TestRESTApplicationPublicTypesIncludeAccountContext(t *testing.T) {
 		}
 	}
 }
+
+func TestParseVirtualAccountApplicationDataIgnoresRegistryChanges(t *testing.T) {
+	defer func() { DefaultRegistry = newDefaultRegistry() }()
+	RegisterVersions[VirtualAccountApplicationData](DefaultRegistry, []string{"V1.4.0"}, EventTypeVirtualAccountCreate)
+
+	e := &Event{EventType: EventTypeVirtualAccountCreate, Version: "V1.4.0", SourceID: "app-1",
+		Data: json.RawMessage(`{"application_id":"app-1","account_id":"acct-1","direct_id":"0"}`)}
+	if _, err := e.ParseVirtualAccountApplicationData(); err == nil {
+		t.Fatal("expected an unsupported version to be rejected after registering it")
+	}
+}
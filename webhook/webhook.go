@@ -23,12 +23,19 @@
 //	        // Handle account creation
 //	    }
 //	}
+//
+// Every event type shipped with the SDK is also mapped to its payload type in
+// DefaultRegistry, so handlers can decode without per-type Parse methods:
+//
+//	account, err := webhook.Decode[webhook.AccountData](&event)
+//
+//	payload, err := event.Payload() // e.g. *webhook.CardAuthorizationData
+//
+// Register additional payload types for server events that the SDK does not
+// know yet with Register or RegisterVersions.
 package webhook
 
-import (
-	"encoding/json"
-	"fmt"
-)
+import "encoding/json"
 
 // Event names
 const (
@@ -39,6 +46,7 @@ const (
 	EventNameBeneficiary = "BENEFICIARY"
 	EventNamePayout      = "PAYOUT"
 	EventNameDeposit     = "DEPOSIT"
+	EventNameRFI         = "RFI"
 )
 
 // Event types for onboarding
@@ -47,6 +55,22 @@ const (
 	EventTypeAccountUpdate = "onboarding.account.update"
 )
 
+// Event types for onboarding (connected sub-accounts)
+const (
+	EventTypeSubAccountCreate    = "onboarding.sub_account.create"
+	EventTypeSubAccountUpdate    = "onboarding.sub_account.update"
+	EventTypeSubAccountActivated = "onboarding.sub_account.activated"
+	EventTypeSubAccountRejected  = "onboarding.sub_account.rejected"
+)
+
+// Event types for requests for information (RFI)
+const (
+	EventTypeRFIActionRequired = "rfi.action_required"
+	EventTypeRFISubmitted      = "rfi.submitted"
+	EventTypeRFIApproved       = "rfi.approved"
+	EventTypeRFIRejected       = "rfi.rejected"
+)
+
 // Event types for acquiring (payment intents)
 const (
 	EventTypePaymentIntentCreated   = "acquiring.payment_intent.created"
@@ -96,7 +120,11 @@ const (
 
 // Event types for issuing (card transaction events)
 const (
-	EventTypeIssuingFeeCard = "issuing.fee.card"
+	EventTypeIssuingAuthorizationCard = "issuing.authorization.card"
+	EventTypeIssuingReversalCard      = "issuing.reversal.card"
+	EventTypeIssuingClearingCard      = "issuing.clearing.card"
+	EventTypeIssuingRefundCard        = "issuing.refund.card"
+	EventTypeIssuingFeeCard           = "issuing.fee.card"
 )
 
 // Event types for payout
@@ -162,25 +190,30 @@ func (e *Event) IsAccountUpdateEvent() bool {
 // ParseAccountData parses the event data as an AccountData struct.
 // Returns an error if the event type is not an account event or if parsing fails.
 func (e *Event) ParseAccountData() (*AccountData, error) {
-	if e.EventType != EventTypeAccountCreate && e.EventType != EventTypeAccountUpdate {
-		return nil, fmt.Errorf("event type %s is not an account event", e.EventType)
-	}
-
-	var data AccountData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse account data: %w", err)
-	}
-	return &data, nil
+	return parseData[AccountData](e, hasString(accountEventTypes, e.EventType), "an account event", "account data")
 }
 
 // MustParseAccountData is like ParseAccountData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseAccountData() *AccountData {
-	data, err := e.ParseAccountData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseAccountData())
+}
+
+// IsSubAccountEvent returns true if this is a connected sub-account onboarding event
+func (e *Event) IsSubAccountEvent() bool {
+	return hasString(subAccountEventTypes, e.EventType)
+}
+
+// ParseSubAccountData parses the event data as a SubAccountData struct.
+// Returns an error if the event type is not a sub-account event or if parsing fails.
+func (e *Event) ParseSubAccountData() (*SubAccountData, error) {
+	return parseData[SubAccountData](e, e.IsSubAccountEvent(), "a sub-account event", "sub-account data")
+}
+
+// MustParseSubAccountData is like ParseSubAccountData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseSubAccountData() *SubAccountData {
+	return mustParse(e.ParseSubAccountData())
 }
 
 // IsAcquiringEvent returns true if this is an acquiring-related event
@@ -190,110 +223,53 @@ func (e *Event) IsAcquiringEvent() bool {
 
 // IsPaymentIntentEvent returns true if this is a payment intent event
 func (e *Event) IsPaymentIntentEvent() bool {
-	switch e.EventType {
-	case EventTypePaymentIntentCreated,
-		EventTypePaymentIntentSucceeded,
-		EventTypePaymentIntentFailed,
-		EventTypePaymentIntentCanceled:
-		return true
-	}
-	return false
+	return hasString(paymentIntentEventTypes, e.EventType)
 }
 
 // ParsePaymentIntentData parses the event data as a PaymentIntentData struct.
 // Returns an error if the event type is not a payment intent event or if parsing fails.
 func (e *Event) ParsePaymentIntentData() (*PaymentIntentData, error) {
-	if !e.IsPaymentIntentEvent() {
-		return nil, fmt.Errorf("event type %s is not a payment intent event", e.EventType)
-	}
-
-	var data PaymentIntentData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse payment intent data: %w", err)
-	}
-	return &data, nil
+	return parseData[PaymentIntentData](e, e.IsPaymentIntentEvent(), "a payment intent event", "payment intent data")
 }
 
 // MustParsePaymentIntentData is like ParsePaymentIntentData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParsePaymentIntentData() *PaymentIntentData {
-	data, err := e.ParsePaymentIntentData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParsePaymentIntentData())
 }
 
 // IsPaymentAttemptEvent returns true if this is a payment attempt event
 func (e *Event) IsPaymentAttemptEvent() bool {
-	switch e.EventType {
-	case EventTypePaymentAttemptCreated,
-		EventTypePaymentAttemptCaptureRequested,
-		EventTypePaymentAttemptSucceeded,
-		EventTypePaymentAttemptFailed,
-		EventTypePaymentAttemptCanceled:
-		return true
-	}
-	return false
+	return hasString(paymentAttemptEventTypes, e.EventType)
 }
 
 // ParsePaymentAttemptData parses the event data as a PaymentAttemptData struct.
 // Returns an error if the event type is not a payment attempt event or if parsing fails.
 func (e *Event) ParsePaymentAttemptData() (*PaymentAttemptData, error) {
-	if !e.IsPaymentAttemptEvent() {
-		return nil, fmt.Errorf("event type %s is not a payment attempt event", e.EventType)
-	}
-
-	var data PaymentAttemptData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse payment attempt data: %w", err)
-	}
-	return &data, nil
+	return parseData[PaymentAttemptData](e, e.IsPaymentAttemptEvent(), "a payment attempt event", "payment attempt data")
 }
 
 // MustParsePaymentAttemptData is like ParsePaymentAttemptData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParsePaymentAttemptData() *PaymentAttemptData {
-	data, err := e.ParsePaymentAttemptData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParsePaymentAttemptData())
 }
 
 // IsRefundEvent returns true if this is a refund event
 func (e *Event) IsRefundEvent() bool {
-	switch e.EventType {
-	case EventTypeRefundCreated,
-		EventTypeRefundSucceeded,
-		EventTypeRefundFailed:
-		return true
-	}
-	return false
+	return hasString(refundEventTypes, e.EventType)
 }
 
 // ParseRefundData parses the event data as a RefundData struct.
 // Returns an error if the event type is not a refund event or if parsing fails.
 func (e *Event) ParseRefundData() (*RefundData, error) {
-	if !e.IsRefundEvent() {
-		return nil, fmt.Errorf("event type %s is not a refund event", e.EventType)
-	}
-
-	var data RefundData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse refund data: %w", err)
-	}
-	return &data, nil
+	return parseData[RefundData](e, e.IsRefundEvent(), "a refund event", "refund data")
 }
 
 // MustParseRefundData is like ParseRefundData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseRefundData() *RefundData {
-	data, err := e.ParseRefundData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseRefundData())
 }
 
 // IsConversionEvent returns true if this is a conversion-related event
@@ -309,25 +285,47 @@ func (e *Event) IsConversionTradeSettledEvent() bool {
 // ParseConversionData parses the event data as a ConversionData struct.
 // Returns an error if the event type is not a conversion event or if parsing fails.
 func (e *Event) ParseConversionData() (*ConversionData, error) {
-	if !e.IsConversionEvent() {
-		return nil, fmt.Errorf("event type %s is not a conversion event", e.EventType)
-	}
-
-	var data ConversionData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse conversion data: %w", err)
-	}
-	return &data, nil
+	return parseData[ConversionData](e, e.IsConversionEvent(), "a conversion event", "conversion data")
 }
 
 // MustParseConversionData is like ParseConversionData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseConversionData() *ConversionData {
-	data, err := e.ParseConversionData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseConversionData())
+}
+
+// IsConversionFundsAwaitingEvent returns true if this is a conversion funds awaiting event
+func (e *Event) IsConversionFundsAwaitingEvent() bool {
+	return e.EventType == EventTypeConversionFundsAwaiting
+}
+
+// ParseConversionFundsAwaitingData parses the event data as a ConversionFundsAwaitingData struct.
+// Returns an error if the event type is not a conversion funds awaiting event or if parsing fails.
+func (e *Event) ParseConversionFundsAwaitingData() (*ConversionFundsAwaitingData, error) {
+	return parseData[ConversionFundsAwaitingData](e, e.IsConversionFundsAwaitingEvent(), "a conversion funds awaiting event", "conversion funds awaiting data")
+}
+
+// MustParseConversionFundsAwaitingData is like ParseConversionFundsAwaitingData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseConversionFundsAwaitingData() *ConversionFundsAwaitingData {
+	return mustParse(e.ParseConversionFundsAwaitingData())
+}
+
+// IsConversionFundsArrivedEvent returns true if this is a conversion funds arrived event
+func (e *Event) IsConversionFundsArrivedEvent() bool {
+	return e.EventType == EventTypeConversionFundsArrived
+}
+
+// ParseConversionFundsArrivedData parses the event data as a ConversionFundsArrivedData struct.
+// Returns an error if the event type is not a conversion funds arrived event or if parsing fails.
+func (e *Event) ParseConversionFundsArrivedData() (*ConversionFundsArrivedData, error) {
+	return parseData[ConversionFundsArrivedData](e, e.IsConversionFundsArrivedEvent(), "a conversion funds arrived event", "conversion funds arrived data")
+}
+
+// MustParseConversionFundsArrivedData is like ParseConversionFundsArrivedData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseConversionFundsArrivedData() *ConversionFundsArrivedData {
+	return mustParse(e.ParseConversionFundsArrivedData())
 }
 
 // IsIssuingEvent returns true if this is an issuing-related event
@@ -357,58 +355,29 @@ func (e *Event) IsCardEvent() bool {
 
 // IsCardStatusUpdateEvent returns true if this is a card status update event
 func (e *Event) IsCardStatusUpdateEvent() bool {
-	switch e.EventType {
-	case EventTypeCardStatusUpdateSucceeded,
-		EventTypeCardStatusUpdateFailed:
-		return true
-	}
-	return false
+	return hasString(cardStatusUpdateEventTypes, e.EventType)
 }
 
 // IsCardCreateOrUpdateEvent returns true if this is a card create or update event
 func (e *Event) IsCardCreateOrUpdateEvent() bool {
-	switch e.EventType {
-	case EventTypeCardCreateSucceeded,
-		EventTypeCardCreateFailed,
-		EventTypeCardUpdateSucceeded,
-		EventTypeCardUpdateFailed:
-		return true
-	}
-	return false
+	return hasString(cardCreateOrUpdateEventTypes, e.EventType)
 }
 
 // ParseCardData parses the event data as a CardData struct.
 // Returns an error if the event type is not a card create/update event or if parsing fails.
 func (e *Event) ParseCardData() (*CardData, error) {
-	if !e.IsCardCreateOrUpdateEvent() {
-		return nil, fmt.Errorf("event type %s is not a card create/update event", e.EventType)
-	}
-
-	var data CardData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse card data: %w", err)
-	}
-	return &data, nil
+	return parseData[CardData](e, e.IsCardCreateOrUpdateEvent(), "a card create/update event", "card data")
 }
 
 // MustParseCardData is like ParseCardData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseCardData() *CardData {
-	data, err := e.ParseCardData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseCardData())
 }
 
 // IsCardRechargeEvent returns true if this is a card recharge event
 func (e *Event) IsCardRechargeEvent() bool {
-	switch e.EventType {
-	case EventTypeCardRechargeSucceeded,
-		EventTypeCardRechargeFailed:
-		return true
-	}
-	return false
+	return hasString(cardRechargeEventTypes, e.EventType)
 }
 
 // IsCardActivationCodeEvent returns true if this is a card activation code event
@@ -419,79 +388,47 @@ func (e *Event) IsCardActivationCodeEvent() bool {
 // ParseCardActivationCodeData parses the event data as a CardActivationCodeData struct.
 // Returns an error if the event type is not a card activation code event or if parsing fails.
 func (e *Event) ParseCardActivationCodeData() (*CardActivationCodeData, error) {
-	if !e.IsCardActivationCodeEvent() {
-		return nil, fmt.Errorf("event type %s is not a card activation code event", e.EventType)
-	}
-
-	var data CardActivationCodeData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse card activation code data: %w", err)
-	}
-	return &data, nil
+	return parseData[CardActivationCodeData](e, e.IsCardActivationCodeEvent(), "a card activation code event", "card activation code data")
 }
 
 // MustParseCardActivationCodeData is like ParseCardActivationCodeData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseCardActivationCodeData() *CardActivationCodeData {
-	data, err := e.ParseCardActivationCodeData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseCardActivationCodeData())
 }
 
 // ParseCardRechargeData parses the event data as a CardRechargeData struct.
 // Returns an error if the event type is not a card recharge event or if parsing fails.
 func (e *Event) ParseCardRechargeData() (*CardRechargeData, error) {
-	if !e.IsCardRechargeEvent() {
-		return nil, fmt.Errorf("event type %s is not a card recharge event", e.EventType)
-	}
-
-	var data CardRechargeData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse card recharge data: %w", err)
-	}
-	return &data, nil
+	return parseData[CardRechargeData](e, e.IsCardRechargeEvent(), "a card recharge event", "card recharge data")
 }
 
 // MustParseCardRechargeData is like ParseCardRechargeData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseCardRechargeData() *CardRechargeData {
-	data, err := e.ParseCardRechargeData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseCardRechargeData())
 }
 
 // ParseCardStatusUpdateData parses the event data as a CardStatusUpdateData struct.
 // Returns an error if the event type is not a card status update event or if parsing fails.
 func (e *Event) ParseCardStatusUpdateData() (*CardStatusUpdateData, error) {
-	if !e.IsCardStatusUpdateEvent() {
-		return nil, fmt.Errorf("event type %s is not a card status update event", e.EventType)
-	}
-
-	var data CardStatusUpdateData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse card status update data: %w", err)
-	}
-	return &data, nil
+	return parseData[CardStatusUpdateData](e, e.IsCardStatusUpdateEvent(), "a card status update event", "card status update data")
 }
 
 // MustParseCardStatusUpdateData is like ParseCardStatusUpdateData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseCardStatusUpdateData() *CardStatusUpdateData {
-	data, err := e.ParseCardStatusUpdateData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseCardStatusUpdateData())
 }
 
 // IsCardTransactionEvent returns true if this is a card transaction event
 func (e *Event) IsCardTransactionEvent() bool {
 	switch e.EventType {
-	case EventTypeIssuingFeeCard:
+	case EventTypeIssuingAuthorizationCard,
+		EventTypeIssuingReversalCard,
+		EventTypeIssuingClearingCard,
+		EventTypeIssuingRefundCard,
+		EventTypeIssuingFeeCard:
 		return true
 	}
 	return false
@@ -500,25 +437,98 @@ func (e *Event) IsCardTransactionEvent() bool {
 // ParseCardTransactionData parses the event data as a CardTransactionData struct.
 // Returns an error if the event type is not a card transaction event or if parsing fails.
 func (e *Event) ParseCardTransactionData() (*CardTransactionData, error) {
-	if !e.IsCardTransactionEvent() {
-		return nil, fmt.Errorf("event type %s is not a card transaction event", e.EventType)
-	}
-
-	var data CardTransactionData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse card transaction data: %w", err)
-	}
-	return &data, nil
+	return parseData[CardTransactionData](e, e.IsCardTransactionEvent(), "a card transaction event", "card transaction data")
 }
 
 // MustParseCardTransactionData is like ParseCardTransactionData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseCardTransactionData() *CardTransactionData {
-	data, err := e.ParseCardTransactionData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseCardTransactionData())
+}
+
+// IsCardLifecycleEvent returns true if this is a card activated, suspended or closed event
+func (e *Event) IsCardLifecycleEvent() bool {
+	return hasString(cardLifecycleEventTypes, e.EventType)
+}
+
+// ParseCardLifecycleData parses the event data as a CardLifecycleData struct.
+// Returns an error if the event type is not a card lifecycle event or if parsing fails.
+func (e *Event) ParseCardLifecycleData() (*CardLifecycleData, error) {
+	return parseData[CardLifecycleData](e, e.IsCardLifecycleEvent(), "a card lifecycle event", "card lifecycle data")
+}
+
+// MustParseCardLifecycleData is like ParseCardLifecycleData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseCardLifecycleData() *CardLifecycleData {
+	return mustParse(e.ParseCardLifecycleData())
+}
+
+// IsCardAuthorizationEvent returns true if this is a card authorization event
+func (e *Event) IsCardAuthorizationEvent() bool {
+	return e.EventType == EventTypeIssuingAuthorizationCard
+}
+
+// ParseCardAuthorizationData parses the event data as a CardAuthorizationData struct.
+// Returns an error if the event type is not a card authorization event or if parsing fails.
+func (e *Event) ParseCardAuthorizationData() (*CardAuthorizationData, error) {
+	return parseData[CardAuthorizationData](e, e.IsCardAuthorizationEvent(), "a card authorization event", "card authorization data")
+}
+
+// MustParseCardAuthorizationData is like ParseCardAuthorizationData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseCardAuthorizationData() *CardAuthorizationData {
+	return mustParse(e.ParseCardAuthorizationData())
+}
+
+// IsCardReversalEvent returns true if this is a card authorization reversal event
+func (e *Event) IsCardReversalEvent() bool {
+	return e.EventType == EventTypeIssuingReversalCard
+}
+
+// ParseCardReversalData parses the event data as a CardReversalData struct.
+// Returns an error if the event type is not a card reversal event or if parsing fails.
+func (e *Event) ParseCardReversalData() (*CardReversalData, error) {
+	return parseData[CardReversalData](e, e.IsCardReversalEvent(), "a card reversal event", "card reversal data")
+}
+
+// MustParseCardReversalData is like ParseCardReversalData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseCardReversalData() *CardReversalData {
+	return mustParse(e.ParseCardReversalData())
+}
+
+// IsCardClearingEvent returns true if this is a card clearing event
+func (e *Event) IsCardClearingEvent() bool {
+	return e.EventType == EventTypeIssuingClearingCard
+}
+
+// ParseCardClearingData parses the event data as a CardClearingData struct.
+// Returns an error if the event type is not a card clearing event or if parsing fails.
+func (e *Event) ParseCardClearingData() (*CardClearingData, error) {
+	return parseData[CardClearingData](e, e.IsCardClearingEvent(), "a card clearing event", "card clearing data")
+}
+
+// MustParseCardClearingData is like ParseCardClearingData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseCardClearingData() *CardClearingData {
+	return mustParse(e.ParseCardClearingData())
+}
+
+// IsCardRefundEvent returns true if this is a card refund event
+func (e *Event) IsCardRefundEvent() bool {
+	return e.EventType == EventTypeIssuingRefundCard
+}
+
+// ParseCardRefundData parses the event data as a CardRefundData struct.
+// Returns an error if the event type is not a card refund event or if parsing fails.
+func (e *Event) ParseCardRefundData() (*CardRefundData, error) {
+	return parseData[CardRefundData](e, e.IsCardRefundEvent(), "a card refund event", "card refund data")
+}
+
+// MustParseCardRefundData is like ParseCardRefundData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseCardRefundData() *CardRefundData {
+	return mustParse(e.ParseCardRefundData())
 }
 
 // IsPayoutEvent returns true if this is a payout-related event
@@ -529,24 +539,13 @@ func (e *Event) IsPayoutEvent() bool {
 // ParsePayoutData parses the event data as a PayoutData struct.
 // Returns an error if the event type is not a payout event or if parsing fails.
 func (e *Event) ParsePayoutData() (*PayoutData, error) {
-	if !e.IsPayoutEvent() {
-		return nil, fmt.Errorf("event type %s is not a payout event", e.EventType)
-	}
-	var data PayoutData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse payout data: %w", err)
-	}
-	return &data, nil
+	return parseData[PayoutData](e, e.IsPayoutEvent(), "a payout event", "payout data")
 }
 
 // MustParsePayoutData is like ParsePayoutData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParsePayoutData() *PayoutData {
-	data, err := e.ParsePayoutData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParsePayoutData())
 }
 
 // IsBeneficiaryEvent returns true if this is a beneficiary-related event
@@ -567,25 +566,13 @@ func (e *Event) IsBeneficiaryFailedEvent() bool {
 // ParseBeneficiaryData parses the event data as a BeneficiaryData struct.
 // Returns an error if the event type is not a beneficiary event or if parsing fails.
 func (e *Event) ParseBeneficiaryData() (*BeneficiaryData, error) {
-	if !e.IsBeneficiaryEvent() {
-		return nil, fmt.Errorf("event type %s is not a beneficiary event", e.EventType)
-	}
-
-	var data BeneficiaryData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse beneficiary data: %w", err)
-	}
-	return &data, nil
+	return parseData[BeneficiaryData](e, e.IsBeneficiaryEvent(), "a beneficiary event", "beneficiary data")
 }
 
 // MustParseBeneficiaryData is like ParseBeneficiaryData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseBeneficiaryData() *BeneficiaryData {
-	data, err := e.ParseBeneficiaryData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseBeneficiaryData())
 }
 
 // IsDepositEvent returns true if this is a deposit-related event
@@ -596,22 +583,79 @@ func (e *Event) IsDepositEvent() bool {
 // ParseDepositData parses the event data as a DepositData struct.
 // Returns an error if the event type is not a deposit event or if parsing fails.
 func (e *Event) ParseDepositData() (*DepositData, error) {
-	if !e.IsDepositEvent() {
-		return nil, fmt.Errorf("event type %s is not a deposit event", e.EventType)
-	}
-	var data DepositData
-	if err := json.Unmarshal(e.Data, &data); err != nil {
-		return nil, fmt.Errorf("failed to parse deposit data: %w", err)
-	}
-	return &data, nil
+	return parseData[DepositData](e, e.IsDepositEvent(), "a deposit event", "deposit data")
 }
 
 // MustParseDepositData is like ParseDepositData but panics on error.
 // Use this only when you are certain the event type is correct.
 func (e *Event) MustParseDepositData() *DepositData {
-	data, err := e.ParseDepositData()
-	if err != nil {
-		panic(err)
-	}
-	return data
+	return mustParse(e.ParseDepositData())
+}
+
+// IsDepositPendingEvent returns true if this is a deposit pending event
+func (e *Event) IsDepositPendingEvent() bool {
+	return e.EventType == EventTypeDepositPending
+}
+
+// ParseDepositPendingData parses the event data as a DepositPendingData struct.
+// Returns an error if the event type is not a deposit pending event or if parsing fails.
+func (e *Event) ParseDepositPendingData() (*DepositPendingData, error) {
+	return parseData[DepositPendingData](e, e.IsDepositPendingEvent(), "a deposit pending event", "deposit pending data")
+}
+
+// MustParseDepositPendingData is like ParseDepositPendingData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseDepositPendingData() *DepositPendingData {
+	return mustParse(e.ParseDepositPendingData())
+}
+
+// IsDepositComplianceRejectedEvent returns true if this is a deposit compliance rejected event
+func (e *Event) IsDepositComplianceRejectedEvent() bool {
+	return e.EventType == EventTypeDepositComplianceRejected
+}
+
+// ParseDepositComplianceRejectedData parses the event data as a DepositComplianceRejectedData struct.
+// Returns an error if the event type is not a deposit compliance rejected event or if parsing fails.
+func (e *Event) ParseDepositComplianceRejectedData() (*DepositComplianceRejectedData, error) {
+	return parseData[DepositComplianceRejectedData](e, e.IsDepositComplianceRejectedEvent(), "a deposit compliance rejected event", "deposit compliance rejected data")
+}
+
+// MustParseDepositComplianceRejectedData is like ParseDepositComplianceRejectedData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseDepositComplianceRejectedData() *DepositComplianceRejectedData {
+	return mustParse(e.ParseDepositComplianceRejectedData())
+}
+
+// IsDepositCompletedEvent returns true if this is a deposit completed event
+func (e *Event) IsDepositCompletedEvent() bool {
+	return e.EventType == EventTypeDepositCompleted
+}
+
+// ParseDepositCompletedData parses the event data as a DepositCompletedData struct.
+// Returns an error if the event type is not a deposit completed event or if parsing fails.
+func (e *Event) ParseDepositCompletedData() (*DepositCompletedData, error) {
+	return parseData[DepositCompletedData](e, e.IsDepositCompletedEvent(), "a deposit completed event", "deposit completed data")
+}
+
+// MustParseDepositCompletedData is like ParseDepositCompletedData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseDepositCompletedData() *DepositCompletedData {
+	return mustParse(e.ParseDepositCompletedData())
+}
+
+// IsRFIEvent returns true if this is a request for information (RFI) event
+func (e *Event) IsRFIEvent() bool {
+	return hasString(rfiEventTypes, e.EventType)
+}
+
+// ParseRFIData parses the event data as a RFIData struct.
+// Returns an error if the event type is not an RFI event or if parsing fails.
+func (e *Event) ParseRFIData() (*RFIData, error) {
+	return parseData[RFIData](e, e.IsRFIEvent(), "an RFI event", "RFI data")
+}
+
+// MustParseRFIData is like ParseRFIData but panics on error.
+// Use this only when you are certain the event type is correct.
+func (e *Event) MustParseRFIData() *RFIData {
+	return mustParse(e.ParseRFIData())
 }
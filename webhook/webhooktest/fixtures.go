@@ -0,0 +1,467 @@
+package webhooktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+// DefaultVersion is the webhook contract version stamped on fixture events.
+const DefaultVersion = "V1.6.0"
+
+// Fixture identifiers shared by every generated event so related deliveries
+// can be correlated in handler tests.
+const (
+	FixtureAccountID     = "f5bb6498-552e-40a5-b14b-616aa04ac1c1"
+	FixtureDirectID      = "0"
+	FixtureCardID        = "a738d29b-3dd7-4fe4-9119-3a3024100f30"
+	FixtureCardholderID  = "a88465b4-f9f2-45f6-bc28-ecadaad1062f"
+	FixtureCardOrderID   = "e3c76528-8c3a-42c8-b1be-40bb16c6d9c0"
+	FixtureConversionID  = "1c8a3e0f-4f0b-4c1e-9d8a-2f4f6a2b9c11"
+	FixturePayoutID      = "7a1b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d"
+	FixtureDepositID     = "3f2e1d0c-9b8a-4776-8554-433221100fed"
+	FixtureBeneficiaryID = "9d8c7b6a-5f4e-4d3c-8b2a-190f8e7d6c5b"
+	FixtureIntentID      = "PI1995398442515173376"
+	FixtureAttemptID     = "PA1995398442515173377"
+	FixtureRefundID      = "RF1995398442515173378"
+	FixtureApplicationID = "6b5a4f3e-2d1c-4b0a-9f8e-7d6c5b4a3f2e"
+	FixtureTransactionID = "5c4b3a29-1807-4f6e-8d5c-4b3a29180706"
+	FixtureSubAccountID  = "2e3f4a5b-6c7d-4e8f-9a0b-1c2d3e4f5a6b"
+	FixtureRFIID         = "8f7e6d5c-4b3a-4291-8f7e-6d5c4b3a2918"
+)
+
+type fixture struct {
+	eventName string
+	sourceID  string
+	data      string
+}
+
+var fixtures = map[string]fixture{
+	webhook.EventTypeAccountCreate: accountFixture("PROCESSING"),
+	webhook.EventTypeAccountUpdate: accountFixture("ACTIVE"),
+
+	webhook.EventTypeSubAccountCreate:    subAccountFixture("PROCESSING", "PENDING"),
+	webhook.EventTypeSubAccountUpdate:    subAccountFixture("PROCESSING", "PENDING"),
+	webhook.EventTypeSubAccountActivated: subAccountFixture("ACTIVE", "APPROVED"),
+	webhook.EventTypeSubAccountRejected:  subAccountFixture("CLOSED", "REJECTED"),
+
+	webhook.EventTypeRFIActionRequired: rfiFixture("ACTION_REQUIRED"),
+	webhook.EventTypeRFISubmitted:      rfiFixture("SUBMITTED_PENDING"),
+	webhook.EventTypeRFIApproved:       rfiFixture("APPROVED"),
+	webhook.EventTypeRFIRejected:       rfiFixture("REJECTED"),
+
+	webhook.EventTypePaymentIntentCreated:   paymentIntentFixture(webhook.IntentStatusRequiresPaymentMethod),
+	webhook.EventTypePaymentIntentSucceeded: paymentIntentFixture(webhook.IntentStatusSucceeded),
+	webhook.EventTypePaymentIntentFailed:    paymentIntentFixture(webhook.IntentStatusFailed),
+	webhook.EventTypePaymentIntentCanceled:  paymentIntentFixture(webhook.IntentStatusCanceled),
+
+	webhook.EventTypePaymentAttemptCreated:          paymentAttemptFixture(webhook.AttemptStatusInitiated),
+	webhook.EventTypePaymentAttemptCaptureRequested: paymentAttemptFixture(webhook.AttemptStatusCaptureRequested),
+	webhook.EventTypePaymentAttemptSucceeded:        paymentAttemptFixture(webhook.AttemptStatusSucceeded),
+	webhook.EventTypePaymentAttemptFailed:           paymentAttemptFixture(webhook.AttemptStatusFailed),
+	webhook.EventTypePaymentAttemptCanceled:         paymentAttemptFixture(webhook.AttemptStatusCanceled),
+
+	webhook.EventTypeRefundCreated:   refundFixture(webhook.RefundStatusInitiated),
+	webhook.EventTypeRefundSucceeded: refundFixture(webhook.RefundStatusSucceeded),
+	webhook.EventTypeRefundFailed:    refundFixture(webhook.RefundStatusFailed),
+
+	webhook.EventTypeConversionTradeSettled:  conversionFixture(webhook.ConversionStatusTradeSettled),
+	webhook.EventTypeConversionFundsAwaiting: conversionFixture(webhook.ConversionStatusAwaitingFunds),
+	webhook.EventTypeConversionFundsArrived:  conversionFixture(webhook.ConversionStatusFundsArrived),
+
+	webhook.EventTypeCardCreateSucceeded:       cardFixture("success"),
+	webhook.EventTypeCardCreateFailed:          cardFixture("failed"),
+	webhook.EventTypeCardUpdateSucceeded:       cardFixture("success"),
+	webhook.EventTypeCardUpdateFailed:          cardFixture("failed"),
+	webhook.EventTypeCardRechargeSucceeded:     cardRechargeFixture("SUCCESS"),
+	webhook.EventTypeCardRechargeFailed:        cardRechargeFixture("FAILED"),
+	webhook.EventTypeCardActivationCode:        cardActivationCodeFixture(),
+	webhook.EventTypeCardActivated:             cardStatusFixture(webhook.CardStatusActive),
+	webhook.EventTypeCardSuspended:             cardStatusFixture(webhook.CardStatusSuspended),
+	webhook.EventTypeCardClosed:                cardStatusFixture(webhook.CardStatusClosed),
+	webhook.EventTypeCardStatusUpdateSucceeded: cardStatusFixture(webhook.CardStatusFrozen),
+	webhook.EventTypeCardStatusUpdateFailed:    cardStatusFixture(webhook.CardStatusActive),
+
+	webhook.EventTypeIssuingAuthorizationCard: cardTransactionFixture(webhook.TransactionTypeAuthorization, webhook.TransactionStatusApproved),
+	webhook.EventTypeIssuingReversalCard:      cardTransactionFixture(webhook.TransactionTypeReversal, webhook.TransactionStatusApproved),
+	webhook.EventTypeIssuingClearingCard:      cardTransactionFixture(webhook.TransactionTypeClearing, webhook.TransactionStatusApproved),
+	webhook.EventTypeIssuingRefundCard:        cardTransactionFixture(webhook.TransactionTypeRefund, webhook.TransactionStatusApproved),
+	webhook.EventTypeIssuingFeeCard:           cardTransactionFixture(webhook.TransactionTypeFee, webhook.TransactionStatusApproved),
+
+	webhook.EventTypePayoutReadySend:          payoutFixture("READY_TO_SEND", ""),
+	webhook.EventTypePayoutComplianceRejected: payoutFixture("REJECTED", "Compliance review rejected the payout"),
+	webhook.EventTypePayoutCompleted:          payoutFixture("COMPLETED", ""),
+	webhook.EventTypePayoutFailed:             payoutFixture("FAILED", "Beneficiary bank rejected the payment"),
+
+	webhook.EventTypeDepositPending:            depositFixture("PENDING"),
+	webhook.EventTypeDepositComplianceRejected: depositFixture("REJECTED"),
+	webhook.EventTypeDepositCompleted:          depositFixture("COMPLETED"),
+
+	webhook.EventTypeBeneficiarySuccessful: beneficiaryFixture(webhook.BeneficiaryStatusActive),
+	webhook.EventTypeBeneficiaryFailed:     beneficiaryFixture(webhook.BeneficiaryStatusRejected),
+	webhook.EventTypeBeneficiaryPending:    beneficiaryFixture(webhook.BeneficiaryStatusPending),
+
+	webhook.EventTypeVirtualAccountCreate: virtualAccountApplicationFixture(1, "SUBMITTED"),
+	webhook.EventTypeVirtualAccountUpdate: virtualAccountApplicationFixture(2, "COMPLETED"),
+	webhook.EventTypeVirtualAccountClosed: virtualAccountApplicationFixture(3, "CLOSED"),
+}
+
+// EventTypes returns every event type that has a fixture, sorted.
+func EventTypes() []string {
+	eventTypes := make([]string, 0, len(fixtures))
+	for eventType := range fixtures {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+	return eventTypes
+}
+
+// NewEvent builds a realistic event envelope for eventType. Each call gets a
+// fresh EventID; the data payload and SourceID come from the fixture table.
+func NewEvent(eventType string) (*webhook.Event, error) {
+	f, ok := fixtures[eventType]
+	if !ok {
+		return nil, fmt.Errorf("webhooktest: no fixture for event type %s", eventType)
+	}
+	return &webhook.Event{
+		Version:   DefaultVersion,
+		EventName: f.eventName,
+		EventType: eventType,
+		EventID:   uuid.New().String(),
+		SourceID:  f.sourceID,
+		Data:      json.RawMessage(f.data),
+	}, nil
+}
+
+// MustNewEvent is like NewEvent but panics on error.
+func MustNewEvent(eventType string) *webhook.Event {
+	event, err := NewEvent(eventType)
+	if err != nil {
+		panic(err)
+	}
+	return event
+}
+
+func accountFixture(status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameOnboarding,
+		sourceID:  FixtureAccountID,
+		data: fmt.Sprintf(`{
+			"account_id": %q,
+			"short_reference_id": "P250101-ABCD1234",
+			"email": "finance@example.com",
+			"account_name": "Example Trading Pte. Ltd.",
+			"country": "SG",
+			"status": %q,
+			"verification_status": "PENDING",
+			"entity_type": "COMPANY",
+			"contact_details": {"email": "finance@example.com", "phone": "+6561234567"},
+			"source": "api"
+		}`, FixtureAccountID, status),
+	}
+}
+
+func paymentIntentFixture(status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameAcquiring,
+		sourceID:  FixtureIntentID,
+		data: fmt.Sprintf(`{
+			"payment_intent_id": %q,
+			"amount": "101",
+			"currency": "USD",
+			"description": "Order #1001",
+			"intent_status": %q,
+			"merchant_order_id": "order-1001",
+			"metadata": {"order": "1001"},
+			"create_time": "2026-01-21T10:30:00+08:00"
+		}`, FixtureIntentID, status),
+	}
+}
+
+func paymentAttemptFixture(status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameAcquiring,
+		sourceID:  FixtureAttemptID,
+		data: fmt.Sprintf(`{
+			"payment_attempt_id": %q,
+			"payment_intent_id": %q,
+			"amount": "101",
+			"currency": "USD",
+			"attempt_status": %q,
+			"merchant_order_id": "order-1001",
+			"payment_method": {"type": "card", "card": {"brand": "visa", "last4": "4242", "exp_month": 12, "exp_year": 2030}},
+			"create_time": "2026-01-21T10:30:05+08:00"
+		}`, FixtureAttemptID, FixtureIntentID, status),
+	}
+}
+
+func refundFixture(status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameAcquiring,
+		sourceID:  FixtureRefundID,
+		data: fmt.Sprintf(`{
+			"payment_refund_id": %q,
+			"payment_intent_id": %q,
+			"payment_attempt_id": %q,
+			"amount": "10",
+			"currency": "USD",
+			"refund_status": %q,
+			"reason": "requested_by_customer",
+			"create_time": "2026-01-22T09:00:00+08:00"
+		}`, FixtureRefundID, FixtureIntentID, FixtureAttemptID, status),
+	}
+}
+
+func conversionFixture(status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameConversion,
+		sourceID:  FixtureConversionID,
+		data: fmt.Sprintf(`{
+			"account_id": %q,
+			"account_name": "Example Trading Pte. Ltd.",
+			"buy_amount": "134.56",
+			"buy_currency": "SGD",
+			"client_rate": "1.3456",
+			"conversion_id": %q,
+			"conversion_status": %q,
+			"conversion_way": "API",
+			"create_time": "2026-01-21T10:30:00+08:00",
+			"creator": "api_user",
+			"direct_id": %q,
+			"sell_amount": "100",
+			"sell_currency": "USD",
+			"short_reference_id": "CV260121-ABC123"
+		}`, FixtureAccountID, FixtureConversionID, status, FixtureDirectID),
+	}
+}
+
+func cardFixture(orderStatus string) fixture {
+	return fixture{
+		eventName: webhook.EventNameIssuing,
+		sourceID:  FixtureCardID,
+		data: fmt.Sprintf(`{
+			"card_id": %q,
+			"card_product_id": "5a9239b7-1618-41a5-989b-4a41fc2d4856",
+			"card_order_id": %q,
+			"card_number": "49372418****4306",
+			"card_bin": "49372418",
+			"card_scheme": "VISA",
+			"card_status": "ACTIVE",
+			"card_currency": "USD",
+			"card_limit": "10000",
+			"card_available_balance": "10000",
+			"form_factor": "VIRTUAL",
+			"mode_type": "SINGLE",
+			"order_status": %q,
+			"cardholder": {
+				"cardholder_id": %q,
+				"cardholder_status": "SUCCESS",
+				"first_name": "Ada",
+				"last_name": "Lovelace",
+				"email": "ada@example.com",
+				"create_time": "2026-01-21T09:45:41+08:00"
+			},
+			"spending_limits": [{"amount": "2500", "interval": "PER_TRANSACTION"}],
+			"risk_control": {"allow_3ds_transactions": "Y"},
+			"metadata": {"department": "engineering"}
+		}`, FixtureCardID, FixtureCardOrderID, orderStatus, FixtureCardholderID),
+	}
+}
+
+func cardRechargeFixture(orderStatus string) fixture {
+	return fixture{
+		eventName: webhook.EventNameIssuing,
+		sourceID:  FixtureCardID,
+		data: fmt.Sprintf(`{
+			"card_id": %q,
+			"amount": "100",
+			"card_currency": "USD",
+			"card_available_balance": "10100",
+			"card_status": "ACTIVE",
+			"order_status": %q,
+			"complete_time": "2026-01-21T10:31:00+08:00",
+			"update_time": "2026-01-21T10:31:00+08:00"
+		}`, FixtureCardID, orderStatus),
+	}
+}
+
+func cardActivationCodeFixture() fixture {
+	return fixture{
+		eventName: webhook.EventNameIssuing,
+		sourceID:  FixtureCardID,
+		data: fmt.Sprintf(`{
+			"card_id": %q,
+			"card_number": "49372418****4306",
+			"activation_code": "482915"
+		}`, FixtureCardID),
+	}
+}
+
+func cardStatusFixture(status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameIssuing,
+		sourceID:  FixtureCardID,
+		data: fmt.Sprintf(`{
+			"card_id": %q,
+			"card_number": "49372418****4306",
+			"card_status": %q,
+			"update_reason": "Requested by cardholder",
+			"update_time": "2026-01-21T10:32:00+08:00"
+		}`, FixtureCardID, status),
+	}
+}
+
+func cardTransactionFixture(transactionType, status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameIssuing,
+		sourceID:  FixtureTransactionID,
+		data: fmt.Sprintf(`{
+			"card_id": %q,
+			"card_number": "49372418****4306",
+			"cardholder_id": %q,
+			"card_available_balance": "9898.50",
+			"transaction_amount": "1.50",
+			"transaction_currency": "USD",
+			"billing_amount": "1.50",
+			"billing_currency": "USD",
+			"transaction_status": %q,
+			"transaction_type": %q,
+			"transaction_time": "2026-01-21T10:33:00+08:00",
+			"reference_id": %q,
+			"short_reference_id": "IT260121-TXN001",
+			"authorization_code": "A1B2C3",
+			"original_transaction_id": "0a1b2c3d-4e5f-4a6b-8c7d-8e9f0a1b2c3d",
+			"merchant_data": {"category_code": "5812", "city": "SINGAPORE", "country": "SG", "name": "EXAMPLE CAFE"}
+		}`, FixtureCardID, FixtureCardholderID, status, transactionType, FixtureTransactionID),
+	}
+}
+
+func payoutFixture(status, failureReason string) fixture {
+	return fixture{
+		eventName: webhook.EventNamePayout,
+		sourceID:  FixturePayoutID,
+		data: fmt.Sprintf(`{
+			"account_id": %q,
+			"amount": "250.00",
+			"beneficiary_id": %q,
+			"currency": "USD",
+			"direct_id": %q,
+			"failure_reason": %q,
+			"fee_amount": "5.00",
+			"fee_currency": "USD",
+			"fee_paid_by": "OURS",
+			"payment_date": "2026-01-22",
+			"payment_type": "LOCAL",
+			"payout_amount": "250.00",
+			"payout_currency": "USD",
+			"payout_id": %q,
+			"reason": "Invoice 2026-001",
+			"reference": "INV-2026-001",
+			"short_reference_id": "PO260121-XYZ789",
+			"status": %q,
+			"unique_request_id": "payout-req-0001"
+		}`, FixtureAccountID, FixtureBeneficiaryID, FixtureDirectID, failureReason, FixturePayoutID, status),
+	}
+}
+
+func depositFixture(status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameDeposit,
+		sourceID:  FixtureDepositID,
+		data: fmt.Sprintf(`{
+			"direct_id": %q,
+			"account_id": %q,
+			"account_name": "Example Trading Pte. Ltd.",
+			"deposit_id": %q,
+			"short_reference_id": "DP260121-QRS456",
+			"deposit_currency": "USD",
+			"deposit_amount": "5000.00",
+			"deposit_fee": "0",
+			"create_time": "2026-01-21T08:00:00+08:00",
+			"complete_time": "2026-01-21T08:05:00+08:00",
+			"update_time": "2026-01-21T08:05:00+08:00",
+			"deposit_status": %q,
+			"deposit_reference": "Wire from Example Holdings"
+		}`, FixtureDirectID, FixtureAccountID, FixtureDepositID, status),
+	}
+}
+
+func beneficiaryFixture(status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameBeneficiary,
+		sourceID:  FixtureBeneficiaryID,
+		data: fmt.Sprintf(`{
+			"account_id": %q,
+			"account_number": "1234567890",
+			"account_currency_code": "USD",
+			"beneficiary_id": %q,
+			"beneficiary_company_name": "Acme Supplies Inc.",
+			"beneficiary_email": "ap@acme.example",
+			"beneficiary_entity_type": "COMPANY",
+			"beneficiary_status": %q,
+			"beneficiary_address": "{\"country_code\":\"US\",\"city\":\"New York\",\"street_address\":\"1 Main St\",\"postal_code\":\"10001\"}",
+			"beneficiary_bank_details": "{\"bank_name\":\"Example Bank\",\"bank_country_code\":\"US\",\"account_holder\":\"Acme Supplies Inc.\",\"account_number\":\"1234567890\",\"swift_code\":\"EXAMUS33\"}",
+			"bank_country_code": "US",
+			"payment_type": "LOCAL",
+			"short_reference_id": "BN260121-LMN012",
+			"direct_id": %q
+		}`, FixtureAccountID, FixtureBeneficiaryID, status, FixtureDirectID),
+	}
+}
+
+func virtualAccountApplicationFixture(publicVersion int, status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameVirtual,
+		sourceID:  FixtureApplicationID,
+		data: fmt.Sprintf(`{
+			"account_id": %q,
+			"direct_id": %q,
+			"application_id": %q,
+			"public_version": %d,
+			"country": "SG",
+			"currency": "USD",
+			"status": %q,
+			"results": []
+		}`, FixtureAccountID, FixtureDirectID, FixtureApplicationID, publicVersion, status),
+	}
+}
+
+func subAccountFixture(status, verificationStatus string) fixture {
+	return fixture{
+		eventName: webhook.EventNameOnboarding,
+		sourceID:  FixtureSubAccountID,
+		data: fmt.Sprintf(`{
+			"account_id": %q,
+			"direct_id": %q,
+			"short_reference_id": "P260121-SUB00001",
+			"account_name": "Example Subsidiary Ltd.",
+			"entity_type": "COMPANY",
+			"country": "SG",
+			"status": %q,
+			"verification_status": %q,
+			"requirements": {"currently_due": []},
+			"create_time": "2026-01-21T09:00:00+08:00",
+			"update_time": "2026-01-21T09:30:00+08:00"
+		}`, FixtureSubAccountID, FixtureAccountID, status, verificationStatus),
+	}
+}
+
+func rfiFixture(status string) fixture {
+	return fixture{
+		eventName: webhook.EventNameRFI,
+		sourceID:  FixtureRFIID,
+		data: fmt.Sprintf(`{
+			"account_id": %q,
+			"rfi_id": %q,
+			"status": %q,
+			"create_time": "2026-01-21T09:00:00+08:00",
+			"update_time": "2026-01-21T09:30:00+08:00",
+			"request": [
+				{"question": {"key": "proof_of_address", "comment": "Utility bill dated within 3 months", "type": "ATTACHMENT"}}
+			]
+		}`, FixtureAccountID, FixtureRFIID, status),
+	}
+}
@@ -0,0 +1,173 @@
+// Package webhooktest signs and delivers UQPAY webhook events to a local
+// endpoint so webhook handlers can be tested end to end.
+//
+// Example usage:
+//
+//	sender := webhooktest.NewSender("http://localhost:8080/webhooks", "whsec_local")
+//	event := webhooktest.MustNewEvent(webhook.EventTypeCardCreateSucceeded)
+//	resp, err := sender.Send(ctx, event)
+//
+// SendTampered, SendExpired and SendDuplicate produce the deliveries a
+// handler must reject or deduplicate.
+package webhooktest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+// Delivery is one signed webhook HTTP request.
+type Delivery struct {
+	Payload   []byte
+	Signature string
+	Timestamp string
+}
+
+// Response is the endpoint's reply to a delivery.
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Sender delivers signed webhook events to URL.
+type Sender struct {
+	URL        string
+	Signer     *webhook.Signer
+	HTTPClient *http.Client
+	// Tolerance is the replay window of the receiving verifier. SendExpired
+	// signs deliveries just outside it. Zero uses webhook.DefaultSignatureTolerance.
+	Tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewSender creates a sender that signs with secret and posts to url.
+func NewSender(url, secret string) *Sender {
+	return &Sender{
+		URL:        url,
+		Signer:     webhook.NewSigner(secret),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}
+}
+
+// NewDelivery encodes event and signs it with the current time.
+func (s *Sender) NewDelivery(event *webhook.Event) (*Delivery, error) {
+	return s.newDeliveryAt(event, s.now())
+}
+
+func (s *Sender) newDeliveryAt(event *webhook.Event, at time.Time) (*Delivery, error) {
+	if event == nil {
+		return nil, fmt.Errorf("webhooktest: event is required")
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("webhooktest: encode event: %w", err)
+	}
+	signature, timestamp := s.Signer.SignAt(payload, at)
+	return &Delivery{Payload: payload, Signature: signature, Timestamp: timestamp}, nil
+}
+
+// NewRequest builds the HTTP request for d. It can be served directly to a
+// handler with httptest.NewRecorder instead of going over the network.
+func (d *Delivery) NewRequest(ctx context.Context, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return nil, fmt.Errorf("webhooktest: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.SignatureHeader, d.Signature)
+	req.Header.Set(webhook.TimestampHeader, d.Timestamp)
+	return req, nil
+}
+
+// Deliver posts d to the sender's URL.
+func (s *Sender) Deliver(ctx context.Context, d *Delivery) (*Response, error) {
+	req, err := d.NewRequest(ctx, s.URL)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("webhooktest: deliver: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("webhooktest: read response: %w", err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Body: body}, nil
+}
+
+// Send signs event with the current time and delivers it.
+func (s *Sender) Send(ctx context.Context, event *webhook.Event) (*Response, error) {
+	d, err := s.NewDelivery(event)
+	if err != nil {
+		return nil, err
+	}
+	return s.Deliver(ctx, d)
+}
+
+// SendTampered signs event, then alters the payload before delivering it so
+// the signature no longer matches the body.
+func (s *Sender) SendTampered(ctx context.Context, event *webhook.Event) (*Response, error) {
+	d, err := s.NewDelivery(event)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = Tamper(d.Payload)
+	return s.Deliver(ctx, d)
+}
+
+// SendExpired delivers event with a correct signature over a timestamp just
+// outside the receiver's replay window.
+func (s *Sender) SendExpired(ctx context.Context, event *webhook.Event) (*Response, error) {
+	tolerance := s.Tolerance
+	if tolerance <= 0 {
+		tolerance = webhook.DefaultSignatureTolerance
+	}
+	d, err := s.newDeliveryAt(event, s.now().Add(-tolerance-time.Minute))
+	if err != nil {
+		return nil, err
+	}
+	return s.Deliver(ctx, d)
+}
+
+// SendDuplicate delivers the same signed request count times, as UQPAY does
+// when it retries a delivery. Handlers should deduplicate on EventID.
+func (s *Sender) SendDuplicate(ctx context.Context, event *webhook.Event, count int) ([]*Response, error) {
+	if count < 2 {
+		return nil, fmt.Errorf("webhooktest: duplicate count must be at least 2")
+	}
+	d, err := s.NewDelivery(event)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]*Response, 0, count)
+	for i := 0; i < count; i++ {
+		resp, err := s.Deliver(ctx, d)
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
+
+// Tamper returns a copy of a JSON object payload with an extra field added to
+// the envelope. The result is still valid JSON but no longer matches the
+// original signature.
+func Tamper(payload []byte) []byte {
+	trimmed := bytes.TrimRight(payload, " \t\r\n")
+	if len(trimmed) == 0 || trimmed[len(trimmed)-1] != '}' {
+		return append(append([]byte(nil), payload...), ' ')
+	}
+	tampered := append([]byte(nil), trimmed[:len(trimmed)-1]...)
+	return append(tampered, []byte(`,"tampered":true}`)...)
+}
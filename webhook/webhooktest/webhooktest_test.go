@@ -0,0 +1,112 @@
+package webhooktest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/uqpay/uqpay-sdk-go/v2/webhook"
+)
+
+const testSecret = "whsec_test_secret"
+
+func TestEveryFixtureSignsAndVerifies(t *testing.T) {
+	sender := NewSender("", testSecret)
+	verifier := webhook.NewVerifier(testSecret)
+	for _, eventType := range EventTypes() {
+		event := MustNewEvent(eventType)
+		delivery, err := sender.NewDelivery(event)
+		if err != nil {
+			t.Fatalf("%s: NewDelivery: %v", eventType, err)
+		}
+		verified, err := verifier.ConstructEvent(delivery.Payload, delivery.Signature, delivery.Timestamp)
+		if err != nil {
+			t.Fatalf("%s: ConstructEvent: %v", eventType, err)
+		}
+		if verified.EventType != eventType || verified.EventName == "" || verified.SourceID == "" {
+			t.Fatalf("%s: envelope = %+v", eventType, verified)
+		}
+		if _, err := verified.Payload(); err != nil {
+			t.Fatalf("%s: Payload: %v", eventType, err)
+		}
+	}
+}
+
+func TestFixturesCoverPublishedEventTypes(t *testing.T) {
+	for _, eventType := range []string{
+		webhook.EventTypeAccountCreate,
+		webhook.EventTypePaymentAttemptCaptureRequested,
+		webhook.EventTypeConversionFundsArrived,
+		webhook.EventTypeCardClosed,
+		webhook.EventTypeIssuingFeeCard,
+		webhook.EventTypePayoutFailed,
+		webhook.EventTypeDepositComplianceRejected,
+		webhook.EventTypeBeneficiaryPending,
+		webhook.EventTypeVirtualAccountClosed,
+	} {
+		if _, err := NewEvent(eventType); err != nil {
+			t.Errorf("NewEvent(%s): %v", eventType, err)
+		}
+	}
+	if _, err := NewEvent("future.event.added"); err == nil {
+		t.Error("NewEvent accepted an event type without a fixture")
+	}
+
+	event := MustNewEvent(webhook.EventTypeVirtualAccountUpdate)
+	if _, err := event.ParseVirtualAccountApplicationData(); err != nil {
+		t.Fatalf("virtual account fixture does not satisfy the typed contract: %v", err)
+	}
+}
+
+func TestSenderDeliveryModes(t *testing.T) {
+	verifier := webhook.NewVerifier(testSecret)
+	var mu sync.Mutex
+	seen := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		event, err := verifier.ConstructEvent(payload, r.Header.Get(webhook.SignatureHeader), r.Header.Get(webhook.TimestampHeader))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		seen[event.EventID]++
+		duplicate := seen[event.EventID] > 1
+		mu.Unlock()
+		if duplicate {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	sender := NewSender(server.URL, testSecret)
+	sender.HTTPClient = server.Client()
+
+	resp, err := sender.Send(ctx, MustNewEvent(webhook.EventTypePayoutCompleted))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Send = %+v, %v; want 200", resp, err)
+	}
+	resp, err = sender.SendTampered(ctx, MustNewEvent(webhook.EventTypePayoutCompleted))
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("SendTampered = %+v, %v; want 400", resp, err)
+	}
+	resp, err = sender.SendExpired(ctx, MustNewEvent(webhook.EventTypePayoutCompleted))
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("SendExpired = %+v, %v; want 400", resp, err)
+	}
+
+	sender.Signer.Milliseconds = true
+	responses, err := sender.SendDuplicate(ctx, MustNewEvent(webhook.EventTypeDepositCompleted), 2)
+	if err != nil {
+		t.Fatalf("SendDuplicate: %v", err)
+	}
+	if responses[0].StatusCode != http.StatusOK || responses[1].StatusCode != http.StatusConflict {
+		t.Fatalf("duplicate statuses = %d/%d, want 200/409", responses[0].StatusCode, responses[1].StatusCode)
+	}
+}